@@ -0,0 +1,1281 @@
+// Package gg_debug provides a gg.Backend that validates calls before they
+// reach another backend.
+//
+// The debug Backend tracks the objects that have been created and the state
+// that has been bound, and checks each call against it: drawing with no
+// program in use, setting a uniform of a program other than the current one,
+// pointing an attribute at no buffer, drawing past the end of a buffer, and
+// passing an enum that is not valid for the call. Drivers report most of
+// these as an anonymous GL error, or not at all.
+//
+// A call that fails validation is reported as an *Error carrying the stack
+// of the caller and is not passed on, the same way OpenGL ignores a call that
+// raises an error.
+package gg_debug
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"runtime"
+	"strings"
+
+	"github.com/dmac/gg"
+)
+
+// Error describes a call that failed validation.
+type Error struct {
+	Call  string // name of the gg.Backend method
+	Msg   string
+	Stack string // stack of the caller, without frames inside gg
+}
+
+func (e *Error) Error() string {
+	return "gg: " + e.Call + ": " + e.Msg
+}
+
+type buffer struct {
+	target gg.Enum // first target the buffer was bound to
+	size   int
+
+	// indices holds a copy of the data of buffers bound to
+	// ELEMENT_ARRAY_BUFFER, so that DrawElements can check the largest
+	// index against the attribute buffers.
+	indices []byte
+}
+
+type program struct {
+	shaders []*gg.Shader
+	linked  bool
+	links   int // incremented by every LinkProgram, invalidating locations
+	// deleted is set when the program is deleted while in use. It is
+	// forgotten once another program is installed.
+	deleted bool
+
+	attribs  map[int]bool // locations returned by GetAttribLocation
+	uniforms []gg.ID      // locations returned by GetUniformLocation
+}
+
+type shader struct {
+	typ gg.Enum
+	// A shader deleted while attached to programs is forgotten once it is
+	// detached from all of them, since it can be detached until then.
+	programs int
+	deleted  bool
+}
+
+type uniform struct {
+	p     *gg.Program
+	links int
+}
+
+type attrib struct {
+	enabled bool
+	set     bool // VertexAttribPointer has been called
+	buf     *gg.Buffer
+	size    int
+	typ     gg.Enum
+	stride  int
+	offset  int
+}
+
+type texBinding struct {
+	unit   int
+	target gg.Enum
+}
+
+// Backend is a gg.Backend that validates calls before passing them on to
+// another backend.
+type Backend struct {
+	// Report is called with each validation error. If Report is nil, errors
+	// are written to the standard logger along with their stacks.
+	Report func(*Error)
+
+	b   gg.Backend
+	err *Error
+
+	buffers       map[*gg.Buffer]*buffer
+	shaders       map[*gg.Shader]*shader
+	programs      map[*gg.Program]*program
+	uniforms      map[gg.ID]uniform // GetUniformLocation returns a new handle each time
+	attribs       map[int]*attrib
+	textures      map[*gg.Texture]gg.Enum // target, 0 until first bound
+	framebuffers  map[*gg.Framebuffer]bool
+	renderbuffers map[*gg.Renderbuffer]bool // whether storage is allocated
+
+	bound        map[gg.Enum]*gg.Buffer
+	current      *gg.Program
+	unit         int
+	boundTex     map[texBinding]*gg.Texture
+	framebuffer  *gg.Framebuffer
+	renderbuffer *gg.Renderbuffer
+}
+
+var _ gg.Backend = (*Backend)(nil)
+
+// New returns a Backend that validates calls and passes the valid ones on
+// to b.
+func New(b gg.Backend) *Backend {
+	return &Backend{
+		b:             b,
+		buffers:       make(map[*gg.Buffer]*buffer),
+		shaders:       make(map[*gg.Shader]*shader),
+		programs:      make(map[*gg.Program]*program),
+		uniforms:      make(map[gg.ID]uniform),
+		attribs:       make(map[int]*attrib),
+		textures:      make(map[*gg.Texture]gg.Enum),
+		framebuffers:  make(map[*gg.Framebuffer]bool),
+		renderbuffers: make(map[*gg.Renderbuffer]bool),
+		bound:         make(map[gg.Enum]*gg.Buffer),
+		boundTex:      make(map[texBinding]*gg.Texture),
+	}
+}
+
+// Err returns the first validation error, or nil if every call so far was
+// valid.
+func (b *Backend) Err() error {
+	if b.err == nil {
+		return nil
+	}
+	return b.err
+}
+
+func (b *Backend) errorf(call, format string, args ...interface{}) *Error {
+	e := &Error{
+		Call:  call,
+		Msg:   fmt.Sprintf(format, args...),
+		Stack: callers(),
+	}
+	if b.err == nil {
+		b.err = e
+	}
+	if b.Report != nil {
+		b.Report(e)
+	} else {
+		log.Printf("%v\n%s", e, e.Stack)
+	}
+	return e
+}
+
+// callers formats the stack of the goroutine, leaving out the frames of gg
+// and of this package so that the first frame is the offending call site.
+func callers() string {
+	pc := make([]uintptr, 64)
+	n := runtime.Callers(1, pc)
+	frames := runtime.CallersFrames(pc[:n])
+	var buf bytes.Buffer
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, "github.com/dmac/gg.") &&
+			!strings.HasPrefix(f.Function, "github.com/dmac/gg/debug.") {
+			fmt.Fprintf(&buf, "%s\n\t%s:%d\n", f.Function, f.File, f.Line)
+		}
+		if !more {
+			break
+		}
+	}
+	return buf.String()
+}
+
+func hex(e gg.Enum) string {
+	return fmt.Sprintf("0x%x", uint32(e))
+}
+
+func oneOf(e gg.Enum, valid ...gg.Enum) bool {
+	for _, v := range valid {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+// handle checks a handle of the given kind. known reports whether it was
+// created through b.
+func (b *Backend) handle(call, kind string, isNil, known, deleted bool) *Error {
+	switch {
+	case isNil:
+		return b.errorf(call, "nil %s", kind)
+	case deleted:
+		return b.errorf(call, "%s has been deleted", kind)
+	case !known:
+		return b.errorf(call, "%s was not created by this backend", kind)
+	}
+	return nil
+}
+
+func (b *Backend) checkBuffer(call string, buf *gg.Buffer) *Error {
+	_, ok := b.buffers[buf]
+	return b.handle(call, "Buffer", buf == nil, ok, buf != nil && buf.Deleted())
+}
+
+func (b *Backend) checkShader(call string, s *gg.Shader) *Error {
+	info, ok := b.shaders[s]
+	return b.handle(call, "Shader", s == nil, ok, s != nil && (s.Deleted() || ok && info.deleted))
+}
+
+func (b *Backend) checkProgram(call string, p *gg.Program) *Error {
+	info, ok := b.programs[p]
+	return b.handle(call, "Program", p == nil, ok, p != nil && (p.Deleted() || ok && info.deleted))
+}
+
+func (b *Backend) checkTexture(call string, t *gg.Texture) *Error {
+	_, ok := b.textures[t]
+	return b.handle(call, "Texture", t == nil, ok, t != nil && t.Deleted())
+}
+
+func (b *Backend) checkFramebuffer(call string, fb *gg.Framebuffer) *Error {
+	_, ok := b.framebuffers[fb]
+	return b.handle(call, "Framebuffer", fb == nil, ok, fb != nil && fb.Deleted())
+}
+
+func (b *Backend) checkRenderbuffer(call string, rb *gg.Renderbuffer) *Error {
+	_, ok := b.renderbuffers[rb]
+	return b.handle(call, "Renderbuffer", rb == nil, ok, rb != nil && rb.Deleted())
+}
+
+func (b *Backend) checkLinked(call string, p *gg.Program) *Error {
+	if err := b.checkProgram(call, p); err != nil {
+		return err
+	}
+	if !b.programs[p].linked {
+		return b.errorf(call, "program has not been linked successfully")
+	}
+	return nil
+}
+
+func (b *Backend) Enable(c gg.Enum) {
+	if !isCapability(c) {
+		b.errorf("Enable", "invalid capability %s", hex(c))
+		return
+	}
+	b.b.Enable(c)
+}
+
+func (b *Backend) Disable(c gg.Enum) {
+	if !isCapability(c) {
+		b.errorf("Disable", "invalid capability %s", hex(c))
+		return
+	}
+	b.b.Disable(c)
+}
+
+func isCapability(c gg.Enum) bool {
+	return oneOf(c,
+		gg.BLEND, gg.CULL_FACE, gg.DEPTH_TEST, gg.DITHER,
+		gg.POLYGON_OFFSET_FILL, gg.SAMPLE_ALPHA_TO_COVERAGE,
+		gg.SAMPLE_COVERAGE, gg.SCISSOR_TEST, gg.STENCIL_TEST,
+	)
+}
+
+func (b *Backend) DepthFunc(f gg.Enum) {
+	if !oneOf(f, gg.NEVER, gg.LESS, gg.EQUAL, gg.LEQUAL, gg.GREATER, gg.NOTEQUAL, gg.GEQUAL, gg.ALWAYS) {
+		b.errorf("DepthFunc", "invalid function %s", hex(f))
+		return
+	}
+	b.b.DepthFunc(f)
+}
+
+func (b *Backend) BlendFunc(sfactor, dfactor gg.Enum) {
+	if !isBlendFactor(sfactor) && sfactor != gg.SRC_ALPHA_SATURATE {
+		b.errorf("BlendFunc", "invalid source factor %s", hex(sfactor))
+		return
+	}
+	if !isBlendFactor(dfactor) {
+		b.errorf("BlendFunc", "invalid destination factor %s", hex(dfactor))
+		return
+	}
+	b.b.BlendFunc(sfactor, dfactor)
+}
+
+func isBlendFactor(f gg.Enum) bool {
+	return oneOf(f,
+		gg.ZERO, gg.ONE,
+		gg.SRC_COLOR, gg.ONE_MINUS_SRC_COLOR, gg.DST_COLOR, gg.ONE_MINUS_DST_COLOR,
+		gg.SRC_ALPHA, gg.ONE_MINUS_SRC_ALPHA, gg.DST_ALPHA, gg.ONE_MINUS_DST_ALPHA,
+		gg.CONSTANT_COLOR, gg.ONE_MINUS_CONSTANT_COLOR,
+		gg.CONSTANT_ALPHA, gg.ONE_MINUS_CONSTANT_ALPHA,
+	)
+}
+
+func (b *Backend) Clear(mask gg.Enum) {
+	if mask&^(gg.COLOR_BUFFER_BIT|gg.DEPTH_BUFFER_BIT|gg.STENCIL_BUFFER_BIT) != 0 {
+		b.errorf("Clear", "invalid mask %s", hex(mask))
+		return
+	}
+	b.b.Clear(mask)
+}
+
+func (b *Backend) ClearColor(red, green, blue, alpha float32) {
+	b.b.ClearColor(red, green, blue, alpha)
+}
+
+func (b *Backend) Viewport(x, y, width, height int) {
+	if width < 0 || height < 0 {
+		b.errorf("Viewport", "negative size %dx%d", width, height)
+		return
+	}
+	b.b.Viewport(x, y, width, height)
+}
+
+func (b *Backend) Scissor(x, y, width, height int) {
+	if width < 0 || height < 0 {
+		b.errorf("Scissor", "negative size %dx%d", width, height)
+		return
+	}
+	b.b.Scissor(x, y, width, height)
+}
+
+func (b *Backend) CreateBuffer() *gg.Buffer {
+	buf := b.b.CreateBuffer()
+	b.buffers[buf] = &buffer{}
+	return buf
+}
+
+func isBufferTarget(typ gg.Enum) bool {
+	return typ == gg.ARRAY_BUFFER || typ == gg.ELEMENT_ARRAY_BUFFER
+}
+
+func (b *Backend) BindBuffer(typ gg.Enum, buf *gg.Buffer) {
+	if !isBufferTarget(typ) {
+		b.errorf("BindBuffer", "invalid target %s", hex(typ))
+		return
+	}
+	if buf != nil {
+		if b.checkBuffer("BindBuffer", buf) != nil {
+			return
+		}
+		info := b.buffers[buf]
+		if info.target != 0 && info.target != typ {
+			// WebGL and the software backend refuse this outright.
+			b.errorf("BindBuffer", "buffer bound to %s was bound to %s before", hex(typ), hex(info.target))
+			return
+		}
+		info.target = typ
+	}
+	b.bound[typ] = buf
+	b.b.BindBuffer(typ, buf)
+}
+
+// boundBuffer returns the buffer bound to typ.
+func (b *Backend) boundBuffer(call string, typ gg.Enum) (*buffer, *Error) {
+	if !isBufferTarget(typ) {
+		return nil, b.errorf(call, "invalid target %s", hex(typ))
+	}
+	buf := b.bound[typ]
+	if buf == nil {
+		return nil, b.errorf(call, "no buffer bound to %s", hex(typ))
+	}
+	return b.buffers[buf], nil
+}
+
+func (b *Backend) BufferData(typ gg.Enum, src []byte, usage gg.Enum) {
+	info, err := b.boundBuffer("BufferData", typ)
+	if err != nil {
+		return
+	}
+	if !oneOf(usage, gg.STREAM_DRAW, gg.STATIC_DRAW, gg.DYNAMIC_DRAW) {
+		b.errorf("BufferData", "invalid usage %s", hex(usage))
+		return
+	}
+	info.size = len(src)
+	if typ == gg.ELEMENT_ARRAY_BUFFER {
+		info.indices = append(info.indices[:0], src...)
+	}
+	b.b.BufferData(typ, src, usage)
+}
+
+func (b *Backend) BufferSubData(typ gg.Enum, offset int, src []byte) {
+	info, err := b.boundBuffer("BufferSubData", typ)
+	if err != nil {
+		return
+	}
+	if offset < 0 || offset+len(src) > info.size {
+		b.errorf("BufferSubData", "range [%d, %d) outside of buffer of %d bytes", offset, offset+len(src), info.size)
+		return
+	}
+	if typ == gg.ELEMENT_ARRAY_BUFFER {
+		copy(info.indices[offset:], src)
+	}
+	b.b.BufferSubData(typ, offset, src)
+}
+
+func (b *Backend) DeleteBuffer(buf *gg.Buffer) {
+	if b.checkBuffer("DeleteBuffer", buf) != nil {
+		return
+	}
+	for typ, bb := range b.bound {
+		if bb == buf {
+			b.bound[typ] = nil
+		}
+	}
+	delete(b.buffers, buf)
+	b.b.DeleteBuffer(buf)
+}
+
+// ShaderDialect reports the dialect of the wrapped backend, so that portable
+// shaders are translated for it.
+func (b *Backend) ShaderDialect() gg.Dialect {
+	if d, ok := b.b.(gg.ShaderDialectBackend); ok {
+		return d.ShaderDialect()
+	}
+	return 0
+}
+
+func (b *Backend) CreateShader(src []byte, typ gg.Enum) (*gg.Shader, error) {
+	if typ != gg.VERTEX_SHADER && typ != gg.FRAGMENT_SHADER {
+		return nil, b.errorf("CreateShader", "invalid shader type %s", hex(typ))
+	}
+	s, err := b.b.CreateShader(src, typ)
+	if err != nil {
+		return s, err
+	}
+	b.shaders[s] = &shader{typ: typ}
+	return s, nil
+}
+
+func (b *Backend) DeleteShader(s *gg.Shader) {
+	if b.checkShader("DeleteShader", s) != nil {
+		return
+	}
+	if info := b.shaders[s]; info.programs > 0 {
+		info.deleted = true
+	} else {
+		delete(b.shaders, s)
+	}
+	b.b.DeleteShader(s)
+}
+
+// detached records that s was detached from a program.
+func (b *Backend) detached(s *gg.Shader) {
+	info := b.shaders[s]
+	info.programs--
+	if info.programs == 0 && info.deleted {
+		delete(b.shaders, s)
+	}
+}
+
+func (b *Backend) AttachShader(p *gg.Program, s *gg.Shader) {
+	if b.checkProgram("AttachShader", p) != nil || b.checkShader("AttachShader", s) != nil {
+		return
+	}
+	info := b.programs[p]
+	for _, ss := range info.shaders {
+		if ss == s {
+			b.errorf("AttachShader", "shader is already attached")
+			return
+		}
+		if b.shaders[ss].typ == b.shaders[s].typ {
+			b.errorf("AttachShader", "program already has a shader of type %s", hex(b.shaders[s].typ))
+			return
+		}
+	}
+	info.shaders = append(info.shaders, s)
+	b.shaders[s].programs++
+	b.b.AttachShader(p, s)
+}
+
+func (b *Backend) DetachShader(p *gg.Program, s *gg.Shader) {
+	if b.checkProgram("DetachShader", p) != nil {
+		return
+	}
+	// A deleted shader can still be detached.
+	if _, ok := b.shaders[s]; b.handle("DetachShader", "Shader", s == nil, ok || s != nil && s.Deleted(), false) != nil {
+		return
+	}
+	info := b.programs[p]
+	for i, ss := range info.shaders {
+		if ss == s {
+			info.shaders = append(info.shaders[:i], info.shaders[i+1:]...)
+			b.detached(s)
+			b.b.DetachShader(p, s)
+			return
+		}
+	}
+	b.errorf("DetachShader", "shader is not attached to program")
+}
+
+func (b *Backend) CreateProgram() *gg.Program {
+	p := b.b.CreateProgram()
+	b.programs[p] = &program{attribs: make(map[int]bool)}
+	return p
+}
+
+func (b *Backend) DeleteProgram(p *gg.Program) {
+	if b.checkProgram("DeleteProgram", p) != nil {
+		return
+	}
+	info := b.programs[p]
+	for _, s := range info.shaders {
+		b.detached(s)
+	}
+	info.shaders = nil
+	if b.current == p {
+		info.deleted = true
+	} else {
+		b.forgetProgram(p)
+	}
+	b.b.DeleteProgram(p)
+}
+
+// forgetProgram removes the deleted program p and its uniform locations.
+func (b *Backend) forgetProgram(p *gg.Program) {
+	for _, u := range b.programs[p].uniforms {
+		delete(b.uniforms, u)
+	}
+	delete(b.programs, p)
+}
+
+func (b *Backend) LinkProgram(p *gg.Program) error {
+	if err := b.checkProgram("LinkProgram", p); err != nil {
+		return err
+	}
+	info := b.programs[p]
+	err := b.b.LinkProgram(p)
+	info.linked = err == nil
+	info.links++
+	info.attribs = make(map[int]bool)
+	return err
+}
+
+func (b *Backend) GetProgramParameter(p *gg.Program, pname gg.Enum) int {
+	if b.checkProgram("GetProgramParameter", p) != nil {
+		return 0
+	}
+	switch pname {
+	case gg.DELETE_STATUS, gg.LINK_STATUS, gg.VALIDATE_STATUS,
+		gg.ATTACHED_SHADERS, gg.ACTIVE_ATTRIBUTES, gg.ACTIVE_UNIFORMS:
+	default:
+		// The *_MAX_LENGTH parameters are not available in WebGL.
+		b.errorf("GetProgramParameter", "invalid parameter %s", hex(pname))
+		return 0
+	}
+	return b.b.GetProgramParameter(p, pname)
+}
+
+func (b *Backend) GetActiveUniform(p *gg.Program, index int) gg.ActiveInfo {
+	if b.checkActive("GetActiveUniform", p, gg.ACTIVE_UNIFORMS, index) != nil {
+		return gg.ActiveInfo{}
+	}
+	return b.b.GetActiveUniform(p, index)
+}
+
+func (b *Backend) GetActiveAttrib(p *gg.Program, index int) gg.ActiveInfo {
+	if b.checkActive("GetActiveAttrib", p, gg.ACTIVE_ATTRIBUTES, index) != nil {
+		return gg.ActiveInfo{}
+	}
+	return b.b.GetActiveAttrib(p, index)
+}
+
+// checkActive checks that p is linked and has more than index active
+// variables of the kind counted by count.
+func (b *Backend) checkActive(call string, p *gg.Program, count gg.Enum, index int) *Error {
+	if err := b.checkLinked(call, p); err != nil {
+		return err
+	}
+	if n := b.b.GetProgramParameter(p, count); index < 0 || index >= n {
+		return b.errorf(call, "index %d out of range [0, %d)", index, n)
+	}
+	return nil
+}
+
+func (b *Backend) UseProgram(p *gg.Program) {
+	if p != nil && b.checkLinked("UseProgram", p) != nil {
+		return
+	}
+	if prev := b.current; prev != nil && prev != p && b.programs[prev].deleted {
+		b.forgetProgram(prev)
+	}
+	b.current = p
+	b.b.UseProgram(p)
+}
+
+func (b *Backend) GetUniformLocation(p *gg.Program, name string) (*gg.Uniform, error) {
+	if err := b.checkLinked("GetUniformLocation", p); err != nil {
+		return nil, err
+	}
+	u, err := b.b.GetUniformLocation(p, name)
+	if err != nil {
+		return u, err
+	}
+	// Uniforms from before a relink are kept, marked stale by their links.
+	info := b.programs[p]
+	if _, ok := b.uniforms[u.ID]; !ok {
+		info.uniforms = append(info.uniforms, u.ID)
+	}
+	b.uniforms[u.ID] = uniform{p: p, links: info.links}
+	return u, nil
+}
+
+// checkUniform checks that u is a location of the program in use.
+func (b *Backend) checkUniform(call string, u *gg.Uniform) bool {
+	if u == nil {
+		b.errorf(call, "nil Uniform")
+		return false
+	}
+	info, ok := b.uniforms[u.ID]
+	if !ok {
+		b.errorf(call, "Uniform was not returned by GetUniformLocation on this backend")
+		return false
+	}
+	if b.current == nil {
+		b.errorf(call, "no program in use")
+		return false
+	}
+	if info.p != b.current {
+		b.errorf(call, "uniform belongs to a program that is not in use")
+		return false
+	}
+	if info.links != b.programs[info.p].links {
+		b.errorf(call, "uniform location is stale: the program was linked again")
+		return false
+	}
+	return true
+}
+
+func (b *Backend) Uniform1f(u *gg.Uniform, v0 float32) {
+	if b.checkUniform("Uniform1f", u) {
+		b.b.Uniform1f(u, v0)
+	}
+}
+
+func (b *Backend) Uniform2f(u *gg.Uniform, v0, v1 float32) {
+	if b.checkUniform("Uniform2f", u) {
+		b.b.Uniform2f(u, v0, v1)
+	}
+}
+
+func (b *Backend) Uniform3f(u *gg.Uniform, v0, v1, v2 float32) {
+	if b.checkUniform("Uniform3f", u) {
+		b.b.Uniform3f(u, v0, v1, v2)
+	}
+}
+
+func (b *Backend) Uniform4f(u *gg.Uniform, v0, v1, v2, v3 float32) {
+	if b.checkUniform("Uniform4f", u) {
+		b.b.Uniform4f(u, v0, v1, v2, v3)
+	}
+}
+
+func (b *Backend) Uniform1i(u *gg.Uniform, v0 int) {
+	if b.checkUniform("Uniform1i", u) {
+		b.b.Uniform1i(u, v0)
+	}
+}
+
+func (b *Backend) Uniform2i(u *gg.Uniform, v0, v1 int) {
+	if b.checkUniform("Uniform2i", u) {
+		b.b.Uniform2i(u, v0, v1)
+	}
+}
+
+func (b *Backend) Uniform3i(u *gg.Uniform, v0, v1, v2 int) {
+	if b.checkUniform("Uniform3i", u) {
+		b.b.Uniform3i(u, v0, v1, v2)
+	}
+}
+
+func (b *Backend) Uniform4i(u *gg.Uniform, v0, v1, v2, v3 int) {
+	if b.checkUniform("Uniform4i", u) {
+		b.b.Uniform4i(u, v0, v1, v2, v3)
+	}
+}
+
+// checkUniformv checks u and that n values make up whole elements of the
+// given size.
+func (b *Backend) checkUniformv(call string, u *gg.Uniform, n, size int) bool {
+	if !b.checkUniform(call, u) {
+		return false
+	}
+	if n == 0 || n%size != 0 {
+		b.errorf(call, "length %d is not a non-zero multiple of %d", n, size)
+		return false
+	}
+	return true
+}
+
+func (b *Backend) Uniform1fv(u *gg.Uniform, value []float32) {
+	if b.checkUniformv("Uniform1fv", u, len(value), 1) {
+		b.b.Uniform1fv(u, value)
+	}
+}
+
+func (b *Backend) Uniform2fv(u *gg.Uniform, value []float32) {
+	if b.checkUniformv("Uniform2fv", u, len(value), 2) {
+		b.b.Uniform2fv(u, value)
+	}
+}
+
+func (b *Backend) Uniform3fv(u *gg.Uniform, value []float32) {
+	if b.checkUniformv("Uniform3fv", u, len(value), 3) {
+		b.b.Uniform3fv(u, value)
+	}
+}
+
+func (b *Backend) Uniform4fv(u *gg.Uniform, value []float32) {
+	if b.checkUniformv("Uniform4fv", u, len(value), 4) {
+		b.b.Uniform4fv(u, value)
+	}
+}
+
+func (b *Backend) Uniform1iv(u *gg.Uniform, value []int32) {
+	if b.checkUniformv("Uniform1iv", u, len(value), 1) {
+		b.b.Uniform1iv(u, value)
+	}
+}
+
+func (b *Backend) Uniform2iv(u *gg.Uniform, value []int32) {
+	if b.checkUniformv("Uniform2iv", u, len(value), 2) {
+		b.b.Uniform2iv(u, value)
+	}
+}
+
+func (b *Backend) Uniform3iv(u *gg.Uniform, value []int32) {
+	if b.checkUniformv("Uniform3iv", u, len(value), 3) {
+		b.b.Uniform3iv(u, value)
+	}
+}
+
+func (b *Backend) Uniform4iv(u *gg.Uniform, value []int32) {
+	if b.checkUniformv("Uniform4iv", u, len(value), 4) {
+		b.b.Uniform4iv(u, value)
+	}
+}
+
+func (b *Backend) UniformMatrix2fv(u *gg.Uniform, value []float32) {
+	if b.checkUniformv("UniformMatrix2fv", u, len(value), 4) {
+		b.b.UniformMatrix2fv(u, value)
+	}
+}
+
+func (b *Backend) UniformMatrix3fv(u *gg.Uniform, value []float32) {
+	if b.checkUniformv("UniformMatrix3fv", u, len(value), 9) {
+		b.b.UniformMatrix3fv(u, value)
+	}
+}
+
+func (b *Backend) UniformMatrix4fv(u *gg.Uniform, value []float32) {
+	if b.checkUniformv("UniformMatrix4fv", u, len(value), 16) {
+		b.b.UniformMatrix4fv(u, value)
+	}
+}
+
+func (b *Backend) GetAttribLocation(p *gg.Program, name string) (*gg.Attribute, error) {
+	if err := b.checkLinked("GetAttribLocation", p); err != nil {
+		return nil, err
+	}
+	a, err := b.b.GetAttribLocation(p, name)
+	if err != nil {
+		return a, err
+	}
+	b.programs[p].attribs[a.Location] = true
+	if b.attribs[a.Location] == nil {
+		b.attribs[a.Location] = &attrib{}
+	}
+	return a, nil
+}
+
+// attrib returns the state of the attribute location a refers to.
+// Attribute state belongs to the location rather than to a program, so
+// handles from different programs may share it.
+func (b *Backend) attrib(call string, a *gg.Attribute) *attrib {
+	if a == nil {
+		b.errorf(call, "nil Attribute")
+		return nil
+	}
+	info := b.attribs[a.Location]
+	if info == nil {
+		b.errorf(call, "Attribute was not returned by GetAttribLocation on this backend")
+	}
+	return info
+}
+
+func (b *Backend) EnableVertexAttribArray(a *gg.Attribute) {
+	info := b.attrib("EnableVertexAttribArray", a)
+	if info == nil {
+		return
+	}
+	info.enabled = true
+	b.b.EnableVertexAttribArray(a)
+}
+
+func (b *Backend) VertexAttribPointer(a *gg.Attribute, size int, typ gg.Enum, normalized bool, stride, offset int) {
+	const call = "VertexAttribPointer"
+	info := b.attrib(call, a)
+	if info == nil {
+		return
+	}
+	if size < 1 || size > 4 {
+		b.errorf(call, "size %d is not between 1 and 4", size)
+		return
+	}
+	if typeSize(typ) == 0 || typ == gg.INT || typ == gg.UNSIGNED_INT {
+		b.errorf(call, "invalid type %s", hex(typ))
+		return
+	}
+	if stride < 0 || stride > 255 {
+		b.errorf(call, "stride %d is not between 0 and 255", stride)
+		return
+	}
+	if offset < 0 || offset%typeSize(typ) != 0 {
+		b.errorf(call, "offset %d is not a non-negative multiple of the type size", offset)
+		return
+	}
+	buf := b.bound[gg.ARRAY_BUFFER]
+	if buf == nil {
+		b.errorf(call, "no buffer bound to ARRAY_BUFFER")
+		return
+	}
+	*info = attrib{
+		enabled: info.enabled,
+		set:     true,
+		buf:     buf,
+		size:    size,
+		typ:     typ,
+		stride:  stride,
+		offset:  offset,
+	}
+	b.b.VertexAttribPointer(a, size, typ, normalized, stride, offset)
+}
+
+func typeSize(typ gg.Enum) int {
+	switch typ {
+	case gg.BYTE, gg.UNSIGNED_BYTE:
+		return 1
+	case gg.SHORT, gg.UNSIGNED_SHORT:
+		return 2
+	case gg.INT, gg.UNSIGNED_INT, gg.FLOAT, gg.FIXED:
+		return 4
+	}
+	return 0
+}
+
+func (b *Backend) CreateTexture() *gg.Texture {
+	t := b.b.CreateTexture()
+	b.textures[t] = 0
+	return t
+}
+
+func (b *Backend) ActiveTexture(tex gg.Enum) {
+	if tex < gg.TEXTURE0 || tex > gg.TEXTURE31 {
+		b.errorf("ActiveTexture", "invalid texture unit %s", hex(tex))
+		return
+	}
+	b.unit = int(tex - gg.TEXTURE0)
+	b.b.ActiveTexture(tex)
+}
+
+func (b *Backend) BindTexture(target gg.Enum, t *gg.Texture) {
+	if target != gg.TEXTURE_2D && target != gg.TEXTURE_CUBE_MAP {
+		b.errorf("BindTexture", "invalid target %s", hex(target))
+		return
+	}
+	if t != nil {
+		if b.checkTexture("BindTexture", t) != nil {
+			return
+		}
+		if prev := b.textures[t]; prev != 0 && prev != target {
+			b.errorf("BindTexture", "texture bound to %s was bound to %s before", hex(target), hex(prev))
+			return
+		}
+		b.textures[t] = target
+	}
+	b.boundTex[texBinding{b.unit, target}] = t
+	b.b.BindTexture(target, t)
+}
+
+func (b *Backend) DeleteTexture(t *gg.Texture) {
+	if b.checkTexture("DeleteTexture", t) != nil {
+		return
+	}
+	for k, tt := range b.boundTex {
+		if tt == t {
+			b.boundTex[k] = nil
+		}
+	}
+	delete(b.textures, t)
+	b.b.DeleteTexture(t)
+}
+
+func (b *Backend) CreateFramebuffer() *gg.Framebuffer {
+	fb := b.b.CreateFramebuffer()
+	b.framebuffers[fb] = true
+	return fb
+}
+
+func (b *Backend) BindFramebuffer(target gg.Enum, fb *gg.Framebuffer) {
+	if target != gg.FRAMEBUFFER {
+		b.errorf("BindFramebuffer", "invalid target %s", hex(target))
+		return
+	}
+	if fb != nil && b.checkFramebuffer("BindFramebuffer", fb) != nil {
+		return
+	}
+	b.framebuffer = fb
+	b.b.BindFramebuffer(target, fb)
+}
+
+func (b *Backend) DeleteFramebuffer(fb *gg.Framebuffer) {
+	if b.checkFramebuffer("DeleteFramebuffer", fb) != nil {
+		return
+	}
+	if b.framebuffer == fb {
+		b.framebuffer = nil
+	}
+	delete(b.framebuffers, fb)
+	b.b.DeleteFramebuffer(fb)
+}
+
+// checkAttachment checks that a framebuffer object is bound to target and
+// that attachment names one of its attachment points.
+func (b *Backend) checkAttachment(call string, target, attachment gg.Enum) bool {
+	if target != gg.FRAMEBUFFER {
+		b.errorf(call, "invalid target %s", hex(target))
+		return false
+	}
+	if b.framebuffer == nil {
+		b.errorf(call, "the default framebuffer is bound")
+		return false
+	}
+	if !oneOf(attachment, gg.COLOR_ATTACHMENT0, gg.DEPTH_ATTACHMENT, gg.STENCIL_ATTACHMENT, gg.DEPTH_STENCIL_ATTACHMENT) {
+		b.errorf(call, "invalid attachment %s", hex(attachment))
+		return false
+	}
+	return true
+}
+
+func (b *Backend) FramebufferTexture2D(target, attachment, textarget gg.Enum, t *gg.Texture, level int) {
+	const call = "FramebufferTexture2D"
+	if !b.checkAttachment(call, target, attachment) {
+		return
+	}
+	if t == nil {
+		b.b.FramebufferTexture2D(target, attachment, textarget, t, level)
+		return
+	}
+	if b.checkTexture(call, t) != nil {
+		return
+	}
+	switch {
+	case textarget == gg.TEXTURE_2D:
+		if b.textures[t] != gg.TEXTURE_2D {
+			b.errorf(call, "texture is not a TEXTURE_2D texture")
+			return
+		}
+	case textarget >= gg.TEXTURE_CUBE_MAP_POSITIVE_X && textarget <= gg.TEXTURE_CUBE_MAP_NEGATIVE_Z:
+		if b.textures[t] != gg.TEXTURE_CUBE_MAP {
+			b.errorf(call, "texture is not a TEXTURE_CUBE_MAP texture")
+			return
+		}
+	default:
+		b.errorf(call, "invalid texture target %s", hex(textarget))
+		return
+	}
+	if level != 0 {
+		b.errorf(call, "level %d is not 0", level)
+		return
+	}
+	b.b.FramebufferTexture2D(target, attachment, textarget, t, level)
+}
+
+func (b *Backend) FramebufferRenderbuffer(target, attachment, renderbuffertarget gg.Enum, rb *gg.Renderbuffer) {
+	const call = "FramebufferRenderbuffer"
+	if !b.checkAttachment(call, target, attachment) {
+		return
+	}
+	if renderbuffertarget != gg.RENDERBUFFER {
+		b.errorf(call, "invalid renderbuffer target %s", hex(renderbuffertarget))
+		return
+	}
+	if rb != nil && b.checkRenderbuffer(call, rb) != nil {
+		return
+	}
+	b.b.FramebufferRenderbuffer(target, attachment, renderbuffertarget, rb)
+}
+
+func (b *Backend) CheckFramebufferStatus(target gg.Enum) gg.Enum {
+	if target != gg.FRAMEBUFFER {
+		b.errorf("CheckFramebufferStatus", "invalid target %s", hex(target))
+		return 0
+	}
+	return b.b.CheckFramebufferStatus(target)
+}
+
+func (b *Backend) CreateRenderbuffer() *gg.Renderbuffer {
+	rb := b.b.CreateRenderbuffer()
+	b.renderbuffers[rb] = false
+	return rb
+}
+
+func (b *Backend) BindRenderbuffer(target gg.Enum, rb *gg.Renderbuffer) {
+	if target != gg.RENDERBUFFER {
+		b.errorf("BindRenderbuffer", "invalid target %s", hex(target))
+		return
+	}
+	if rb != nil && b.checkRenderbuffer("BindRenderbuffer", rb) != nil {
+		return
+	}
+	b.renderbuffer = rb
+	b.b.BindRenderbuffer(target, rb)
+}
+
+func (b *Backend) DeleteRenderbuffer(rb *gg.Renderbuffer) {
+	if b.checkRenderbuffer("DeleteRenderbuffer", rb) != nil {
+		return
+	}
+	if b.renderbuffer == rb {
+		b.renderbuffer = nil
+	}
+	delete(b.renderbuffers, rb)
+	b.b.DeleteRenderbuffer(rb)
+}
+
+func (b *Backend) RenderbufferStorage(target, internalFormat gg.Enum, width, height int) {
+	const call = "RenderbufferStorage"
+	if target != gg.RENDERBUFFER {
+		b.errorf(call, "invalid target %s", hex(target))
+		return
+	}
+	if b.renderbuffer == nil {
+		b.errorf(call, "no renderbuffer bound")
+		return
+	}
+	if !oneOf(internalFormat,
+		gg.RGBA4, gg.RGB565, gg.RGB5_A1, gg.DEPTH_COMPONENT16,
+		gg.STENCIL_INDEX8, gg.DEPTH_STENCIL, gg.DEPTH24_STENCIL8,
+	) {
+		b.errorf(call, "invalid internal format %s", hex(internalFormat))
+		return
+	}
+	if width < 0 || height < 0 {
+		b.errorf(call, "negative size %dx%d", width, height)
+		return
+	}
+	b.renderbuffers[b.renderbuffer] = true
+	b.b.RenderbufferStorage(target, internalFormat, width, height)
+}
+
+// boundTexture returns the texture bound to target on the active unit.
+// Cube map faces are looked up on the TEXTURE_CUBE_MAP binding.
+func (b *Backend) boundTexture(call string, target gg.Enum) *gg.Texture {
+	binding := target
+	switch {
+	case target == gg.TEXTURE_2D:
+	case target >= gg.TEXTURE_CUBE_MAP_POSITIVE_X && target <= gg.TEXTURE_CUBE_MAP_NEGATIVE_Z:
+		binding = gg.TEXTURE_CUBE_MAP
+	default:
+		b.errorf(call, "invalid target %s", hex(target))
+		return nil
+	}
+	t := b.boundTex[texBinding{b.unit, binding}]
+	if t == nil {
+		b.errorf(call, "no texture bound to %s on unit %d", hex(binding), b.unit)
+	}
+	return t
+}
+
+func isPixelFormat(format gg.Enum) bool {
+	return oneOf(format, gg.ALPHA, gg.LUMINANCE, gg.LUMINANCE_ALPHA, gg.RGB, gg.RGBA)
+}
+
+func (b *Backend) TexImage2D(target gg.Enum, level int, internalFormat gg.Enum, width, height, border int, format, typ gg.Enum, data []byte) {
+	const call = "TexImage2D"
+	if b.boundTexture(call, target) == nil {
+		return
+	}
+	if level < 0 {
+		b.errorf(call, "negative level %d", level)
+		return
+	}
+	if width < 0 || height < 0 {
+		b.errorf(call, "negative size %dx%d", width, height)
+		return
+	}
+	if border != 0 {
+		b.errorf(call, "border %d is not 0", border)
+		return
+	}
+	if !isPixelFormat(format) {
+		b.errorf(call, "invalid format %s", hex(format))
+		return
+	}
+	if internalFormat != format {
+		// OpenGL ES 2.0 and WebGL 1 do no format conversion on upload.
+		b.errorf(call, "internal format %s does not match format %s", hex(internalFormat), hex(format))
+		return
+	}
+	if !oneOf(typ, gg.UNSIGNED_BYTE, gg.UNSIGNED_SHORT_5_6_5, gg.UNSIGNED_SHORT_4_4_4_4, gg.UNSIGNED_SHORT_5_5_5_1, gg.FLOAT) {
+		b.errorf(call, "invalid type %s", hex(typ))
+		return
+	}
+	if (typ == gg.UNSIGNED_SHORT_5_6_5 && format != gg.RGB) ||
+		((typ == gg.UNSIGNED_SHORT_4_4_4_4 || typ == gg.UNSIGNED_SHORT_5_5_5_1) && format != gg.RGBA) {
+		b.errorf(call, "type %s does not match format %s", hex(typ), hex(format))
+		return
+	}
+	b.b.TexImage2D(target, level, internalFormat, width, height, border, format, typ, data)
+}
+
+func (b *Backend) TexParameteri(target, pname, param gg.Enum) {
+	const call = "TexParameteri"
+	if target != gg.TEXTURE_2D && target != gg.TEXTURE_CUBE_MAP {
+		b.errorf(call, "invalid target %s", hex(target))
+		return
+	}
+	if b.boundTexture(call, target) == nil {
+		return
+	}
+	var ok bool
+	switch pname {
+	case gg.TEXTURE_WRAP_S, gg.TEXTURE_WRAP_T:
+		ok = oneOf(param, gg.REPEAT, gg.CLAMP_TO_EDGE, gg.MIRRORED_REPEAT)
+	case gg.TEXTURE_MAG_FILTER:
+		ok = oneOf(param, gg.NEAREST, gg.LINEAR)
+	case gg.TEXTURE_MIN_FILTER:
+		ok = oneOf(param,
+			gg.NEAREST, gg.LINEAR,
+			gg.NEAREST_MIPMAP_NEAREST, gg.LINEAR_MIPMAP_NEAREST,
+			gg.NEAREST_MIPMAP_LINEAR, gg.LINEAR_MIPMAP_LINEAR,
+		)
+	default:
+		b.errorf(call, "invalid parameter %s", hex(pname))
+		return
+	}
+	if !ok {
+		b.errorf(call, "invalid value %s for parameter %s", hex(param), hex(pname))
+		return
+	}
+	b.b.TexParameteri(target, pname, param)
+}
+
+// checkDraw checks the state a draw call reads from: the program in use and
+// the vertex arrays of its attributes, which must hold at least maxVertex+1
+// vertices. A negative maxVertex means no vertices are read.
+func (b *Backend) checkDraw(call string, mode gg.Enum, maxVertex int) *Error {
+	if !oneOf(mode, gg.POINTS, gg.LINES, gg.LINE_LOOP, gg.LINE_STRIP, gg.TRIANGLES, gg.TRIANGLE_STRIP, gg.TRIANGLE_FAN) {
+		return b.errorf(call, "invalid mode %s", hex(mode))
+	}
+	p := b.current
+	if p == nil {
+		return b.errorf(call, "no program in use")
+	}
+	if p.Deleted() || b.programs[p].deleted {
+		return b.errorf(call, "program in use has been deleted")
+	}
+	if !b.programs[p].linked {
+		return b.errorf(call, "program in use failed to relink")
+	}
+	if b.framebuffer != nil {
+		if s := b.b.CheckFramebufferStatus(gg.FRAMEBUFFER); s != gg.FRAMEBUFFER_COMPLETE {
+			return b.errorf(call, "%v", &gg.FramebufferStatusError{Status: s})
+		}
+	}
+	if maxVertex < 0 {
+		return nil
+	}
+	for loc := range b.programs[p].attribs {
+		a := b.attribs[loc]
+		if !a.enabled {
+			continue
+		}
+		if !a.set {
+			return b.errorf(call, "attribute %v is enabled but VertexAttribPointer was not called", loc)
+		}
+		info, ok := b.buffers[a.buf]
+		if a.buf.Deleted() || !ok {
+			return b.errorf(call, "attribute %v reads from a deleted buffer", loc)
+		}
+		elem := a.size * typeSize(a.typ)
+		stride := a.stride
+		if stride == 0 {
+			stride = elem
+		}
+		need := a.offset + maxVertex*stride + elem
+		if size := info.size; need > size {
+			return b.errorf(call, "attribute %v reads %d bytes but its buffer holds %d", loc, need, size)
+		}
+	}
+	return nil
+}
+
+func (b *Backend) DrawArrays(mode gg.Enum, first, count int) {
+	if first < 0 || count < 0 {
+		b.errorf("DrawArrays", "negative first %d or count %d", first, count)
+		return
+	}
+	maxVertex := first + count - 1
+	if count == 0 {
+		maxVertex = -1
+	}
+	if b.checkDraw("DrawArrays", mode, maxVertex) != nil {
+		return
+	}
+	b.b.DrawArrays(mode, first, count)
+}
+
+func (b *Backend) DrawElements(mode gg.Enum, count int, typ gg.Enum, offset int) error {
+	const call = "DrawElements"
+	size := 0
+	switch typ {
+	case gg.UNSIGNED_BYTE:
+		size = 1
+	case gg.UNSIGNED_SHORT:
+		size = 2
+	case gg.UNSIGNED_INT:
+		size = 4
+	default:
+		return b.errorf(call, "invalid type %s", hex(typ))
+	}
+	if count < 0 || offset < 0 {
+		return b.errorf(call, "negative count %d or offset %d", count, offset)
+	}
+	if offset%size != 0 {
+		return b.errorf(call, "offset %d is not a multiple of the index size", offset)
+	}
+	info, err := b.boundBuffer(call, gg.ELEMENT_ARRAY_BUFFER)
+	if err != nil {
+		return err
+	}
+	end := offset + count*size
+	if end > info.size {
+		return b.errorf(call, "indices [%d, %d) outside of buffer of %d bytes", offset, end, info.size)
+	}
+	// Indices are stored in the byte order of the host, which is little
+	// endian on every platform gg runs on.
+	maxIndex := -1
+	for i := offset; i < end; i += size {
+		var v int
+		switch size {
+		case 1:
+			v = int(info.indices[i])
+		case 2:
+			v = int(binary.LittleEndian.Uint16(info.indices[i:]))
+		case 4:
+			v = int(binary.LittleEndian.Uint32(info.indices[i:]))
+		}
+		if v > maxIndex {
+			maxIndex = v
+		}
+	}
+	if err := b.checkDraw(call, mode, maxIndex); err != nil {
+		return err
+	}
+	return b.b.DrawElements(mode, count, typ, offset)
+}
+
+func (b *Backend) ReadPixels(x, y, width, height int, format, typ gg.Enum, dst []byte) {
+	if width < 0 || height < 0 {
+		b.errorf("ReadPixels", "negative size %dx%d", width, height)
+		return
+	}
+	if !isPixelFormat(format) {
+		b.errorf("ReadPixels", "invalid format %s", hex(format))
+		return
+	}
+	if typ != gg.UNSIGNED_BYTE && typ != gg.FLOAT {
+		b.errorf("ReadPixels", "invalid type %s", hex(typ))
+		return
+	}
+	b.b.ReadPixels(x, y, width, height, format, typ, dst)
+}
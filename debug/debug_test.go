@@ -0,0 +1,194 @@
+package gg_debug_test
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/dmac/gg"
+	"github.com/dmac/gg/debug"
+	"github.com/dmac/gg/soft"
+)
+
+const (
+	vertexSrc = `
+attribute vec2 position;
+void main() { gl_Position = vec4(position, 0.0, 1.0); }
+`
+	fragmentSrc = `
+uniform vec4 color;
+void main() { gl_FragColor = color; }
+`
+)
+
+func init() {
+	gg_soft.RegisterVertexShader(vertexSrc, func(v *gg_soft.Vertex) {
+		p := v.Attrib("position")
+		v.Position = [4]float32{p[0], p[1], 0, 1}
+	})
+	gg_soft.RegisterFragmentShader(fragmentSrc, func(f *gg_soft.Fragment) {
+		f.Color = f.Uniforms.Vec4("color")
+	})
+}
+
+func program(t *testing.T, c *gg.Context) *gg.Program {
+	t.Helper()
+	vs, err := c.CreateShader([]byte(vertexSrc), gg.VERTEX_SHADER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs, err := c.CreateShader([]byte(fragmentSrc), gg.FRAGMENT_SHADER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := c.CreateProgram()
+	c.AttachShader(p, vs)
+	c.AttachShader(p, fs)
+	if err := c.LinkProgram(p); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// triangle points the position attribute of p at a buffer holding the
+// three vertices of a triangle, and returns the attribute.
+func triangle(t *testing.T, c *gg.Context, p *gg.Program) *gg.Attribute {
+	t.Helper()
+	c.BindBuffer(gg.ARRAY_BUFFER, c.CreateBuffer())
+	c.BufferData(gg.ARRAY_BUFFER, floatBytes(-1, -1, 1, -1, -1, 1), gg.STATIC_DRAW)
+	a, err := c.GetAttribLocation(p, "position")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.EnableVertexAttribArray(a)
+	c.VertexAttribPointer(a, 2, gg.FLOAT, false, 0, 0)
+	return a
+}
+
+func floatBytes(values ...float32) []byte {
+	data := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(v))
+	}
+	return data
+}
+
+func TestValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		// run makes the invalid call and returns the message it should fail
+		// with.
+		run  func(t *testing.T, c *gg.Context) string
+		call string
+	}{
+		{
+			name: "no program",
+			run: func(t *testing.T, c *gg.Context) string {
+				c.DrawArrays(gg.TRIANGLES, 0, 3)
+				return "no program in use"
+			},
+			call: "DrawArrays",
+		},
+		{
+			name: "no array buffer",
+			run: func(t *testing.T, c *gg.Context) string {
+				p := program(t, c)
+				c.UseProgram(p)
+				a, err := c.GetAttribLocation(p, "position")
+				if err != nil {
+					t.Fatal(err)
+				}
+				c.VertexAttribPointer(a, 2, gg.FLOAT, false, 0, 0)
+				return "no buffer bound to ARRAY_BUFFER"
+			},
+			call: "VertexAttribPointer",
+		},
+		{
+			name: "DrawArrays past the vertex buffer",
+			run: func(t *testing.T, c *gg.Context) string {
+				p := program(t, c)
+				c.UseProgram(p)
+				a := triangle(t, c, p)
+				c.DrawArrays(gg.TRIANGLES, 0, 6)
+				return fmt.Sprintf("attribute %v reads 48 bytes but its buffer holds 24", a.Location)
+			},
+			call: "DrawArrays",
+		},
+		{
+			name: "DrawElements past the index buffer",
+			run: func(t *testing.T, c *gg.Context) string {
+				p := program(t, c)
+				c.UseProgram(p)
+				triangle(t, c, p)
+				c.BindBuffer(gg.ELEMENT_ARRAY_BUFFER, c.CreateBuffer())
+				c.BufferData(gg.ELEMENT_ARRAY_BUFFER, []byte{0, 1, 2}, gg.STATIC_DRAW)
+				c.DrawElements(gg.TRIANGLES, 6, gg.UNSIGNED_BYTE, 0)
+				return "indices [0, 6) outside of buffer of 3 bytes"
+			},
+			call: "DrawElements",
+		},
+		{
+			name: "DrawElements past the vertex buffer",
+			run: func(t *testing.T, c *gg.Context) string {
+				p := program(t, c)
+				c.UseProgram(p)
+				a := triangle(t, c, p)
+				c.BindBuffer(gg.ELEMENT_ARRAY_BUFFER, c.CreateBuffer())
+				c.BufferData(gg.ELEMENT_ARRAY_BUFFER, []byte{0, 1, 5}, gg.STATIC_DRAW)
+				c.DrawElements(gg.TRIANGLES, 3, gg.UNSIGNED_BYTE, 0)
+				return fmt.Sprintf("attribute %v reads 48 bytes but its buffer holds 24", a.Location)
+			},
+			call: "DrawElements",
+		},
+		{
+			name: "uniform of a program not in use",
+			run: func(t *testing.T, c *gg.Context) string {
+				p := program(t, c)
+				u, err := c.GetUniformLocation(p, "color")
+				if err != nil {
+					t.Fatal(err)
+				}
+				c.UseProgram(program(t, c))
+				c.Uniform4f(u, 1, 0, 0, 1)
+				return "uniform belongs to a program that is not in use"
+			},
+			call: "Uniform4f",
+		},
+		{
+			name: "invalid enum",
+			run: func(t *testing.T, c *gg.Context) string {
+				c.Enable(gg.TEXTURE_2D)
+				return fmt.Sprintf("invalid capability 0x%x", int(gg.TEXTURE_2D))
+			},
+			call: "Enable",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := gg_soft.New(4, 4)
+			d := gg_debug.New(s)
+			var reported []*gg_debug.Error
+			d.Report = func(e *gg_debug.Error) { reported = append(reported, e) }
+			msg := tt.run(t, gg.NewContext(d))
+
+			e, ok := d.Err().(*gg_debug.Error)
+			if !ok {
+				t.Fatalf("Err() = %v, want a *gg_debug.Error", d.Err())
+			}
+			if e.Call != tt.call || e.Msg != msg {
+				t.Errorf("Err() = %v, want gg: %s: %s", e, tt.call, msg)
+			}
+			if len(reported) != 1 || reported[0] != e {
+				t.Errorf("reported %v, want only %v", reported, e)
+			}
+			if !strings.HasPrefix(e.Stack, "github.com/dmac/gg/debug_test.") {
+				t.Errorf("Stack does not start at the test:\n%s", e.Stack)
+			}
+			if err := s.Err(); err != nil {
+				t.Errorf("invalid call reached the backend: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,386 @@
+package gg
+
+import "image"
+
+// The package-level functions issue calls to the default context, for
+// programs that only ever render to a single surface.
+
+var defaultContext *Context
+
+// Register sets the default context to a new Context for b. It panics if a
+// default context has already been set.
+func Register(b Backend) {
+	if b == nil {
+		panic("gg: Register with nil backend")
+	}
+	if defaultContext != nil {
+		panic("gg: Register called twice")
+	}
+	defaultContext = NewContext(b)
+}
+
+// SetDefault replaces the default context with c, which may be nil.
+func SetDefault(c *Context) {
+	defaultContext = c
+}
+
+// Default returns the default context, or nil if none has been set.
+func Default() *Context {
+	return defaultContext
+}
+
+// current returns the default context for the package-level functions.
+func current() *Context {
+	if defaultContext == nil {
+		panic("gg: no default context; call Register or SetDefault")
+	}
+	return defaultContext
+}
+
+func Enable(c Enum) {
+	current().Enable(c)
+}
+
+func Disable(c Enum) {
+	current().Disable(c)
+}
+
+func DepthFunc(f Enum) {
+	current().DepthFunc(f)
+}
+
+func BlendFunc(sfactor, dfactor Enum) {
+	current().BlendFunc(sfactor, dfactor)
+}
+
+func Clear(mask Enum) {
+	current().Clear(mask)
+}
+
+func ClearColor(r, g, b, a float32) {
+	current().ClearColor(r, g, b, a)
+}
+
+func Viewport(x, y, width, height int) {
+	current().Viewport(x, y, width, height)
+}
+
+func Scissor(x, y, width, height int) {
+	current().Scissor(x, y, width, height)
+}
+
+func CreateBuffer() *Buffer {
+	return current().CreateBuffer()
+}
+
+func BindBuffer(typ Enum, b *Buffer) error {
+	return current().BindBuffer(typ, b)
+}
+
+func BufferData(typ Enum, src []byte, usage Enum) {
+	current().BufferData(typ, src, usage)
+}
+
+func BufferSubData(typ Enum, offset int, src []byte) {
+	current().BufferSubData(typ, offset, src)
+}
+
+func DeleteBuffer(b *Buffer) error {
+	return current().DeleteBuffer(b)
+}
+
+func CreateShader(src []byte, typ Enum) (*Shader, error) {
+	return current().CreateShader(src, typ)
+}
+
+func CreateShaderFrom(src *ShaderSource, typ Enum) (*Shader, error) {
+	return current().CreateShaderFrom(src, typ)
+}
+
+func DeleteShader(s *Shader) error {
+	return current().DeleteShader(s)
+}
+
+func CreateProgram() *Program {
+	return current().CreateProgram()
+}
+
+func DeleteProgram(p *Program) error {
+	return current().DeleteProgram(p)
+}
+
+func AttachShader(p *Program, s *Shader) error {
+	return current().AttachShader(p, s)
+}
+
+func DetachShader(p *Program, s *Shader) error {
+	return current().DetachShader(p, s)
+}
+
+func LinkProgram(p *Program) error {
+	return current().LinkProgram(p)
+}
+
+func GetProgramParameter(p *Program, pname Enum) (int, error) {
+	return current().GetProgramParameter(p, pname)
+}
+
+func GetActiveUniform(p *Program, index int) (ActiveInfo, error) {
+	return current().GetActiveUniform(p, index)
+}
+
+func GetActiveAttrib(p *Program, index int) (ActiveInfo, error) {
+	return current().GetActiveAttrib(p, index)
+}
+
+func UseProgram(p *Program) error {
+	return current().UseProgram(p)
+}
+
+func GetUniformLocation(p *Program, name string) (*Uniform, error) {
+	return current().GetUniformLocation(p, name)
+}
+
+func Uniform1f(u *Uniform, v0 float32) error {
+	return current().Uniform1f(u, v0)
+}
+
+func Uniform2f(u *Uniform, v0, v1 float32) error {
+	return current().Uniform2f(u, v0, v1)
+}
+
+func Uniform3f(u *Uniform, v0, v1, v2 float32) error {
+	return current().Uniform3f(u, v0, v1, v2)
+}
+
+func Uniform4f(u *Uniform, v0, v1, v2, v3 float32) error {
+	return current().Uniform4f(u, v0, v1, v2, v3)
+}
+
+func Uniform1i(u *Uniform, v0 int) error {
+	return current().Uniform1i(u, v0)
+}
+
+func Uniform2i(u *Uniform, v0, v1 int) error {
+	return current().Uniform2i(u, v0, v1)
+}
+
+func Uniform3i(u *Uniform, v0, v1, v2 int) error {
+	return current().Uniform3i(u, v0, v1, v2)
+}
+
+func Uniform4i(u *Uniform, v0, v1, v2, v3 int) error {
+	return current().Uniform4i(u, v0, v1, v2, v3)
+}
+
+func Uniform1fv(u *Uniform, value []float32) error {
+	return current().Uniform1fv(u, value)
+}
+
+func Uniform2fv(u *Uniform, value []float32) error {
+	return current().Uniform2fv(u, value)
+}
+
+func Uniform3fv(u *Uniform, value []float32) error {
+	return current().Uniform3fv(u, value)
+}
+
+func Uniform4fv(u *Uniform, value []float32) error {
+	return current().Uniform4fv(u, value)
+}
+
+func Uniform1iv(u *Uniform, value []int32) error {
+	return current().Uniform1iv(u, value)
+}
+
+func Uniform2iv(u *Uniform, value []int32) error {
+	return current().Uniform2iv(u, value)
+}
+
+func Uniform3iv(u *Uniform, value []int32) error {
+	return current().Uniform3iv(u, value)
+}
+
+func Uniform4iv(u *Uniform, value []int32) error {
+	return current().Uniform4iv(u, value)
+}
+
+func UniformMatrix2fv(u *Uniform, value []float32) error {
+	return current().UniformMatrix2fv(u, value)
+}
+
+func UniformMatrix3fv(u *Uniform, value []float32) error {
+	return current().UniformMatrix3fv(u, value)
+}
+
+func UniformMatrix4fv(u *Uniform, value []float32) error {
+	return current().UniformMatrix4fv(u, value)
+}
+
+func GetAttribLocation(p *Program, name string) (*Attribute, error) {
+	return current().GetAttribLocation(p, name)
+}
+
+func EnableVertexAttribArray(a *Attribute) error {
+	return current().EnableVertexAttribArray(a)
+}
+
+func VertexAttribPointer(a *Attribute, size int, typ Enum, normalized bool, stride, offset int) error {
+	return current().VertexAttribPointer(a, size, typ, normalized, stride, offset)
+}
+
+func CreateTexture() *Texture {
+	return current().CreateTexture()
+}
+
+func ActiveTexture(tex Enum) {
+	current().ActiveTexture(tex)
+}
+
+func BindTexture(target Enum, texture *Texture) error {
+	return current().BindTexture(target, texture)
+}
+
+func DeleteTexture(t *Texture) error {
+	return current().DeleteTexture(t)
+}
+
+func TexImage2D(
+	target Enum, level int, internalFormat Enum,
+	width, height, border int,
+	format, typ Enum,
+	data []byte,
+) error {
+	return current().TexImage2D(target, level, internalFormat, width, height, border, format, typ, data)
+}
+
+func TexParameteri(target Enum, pname Enum, param Enum) {
+	current().TexParameteri(target, pname, param)
+}
+
+func DrawArrays(mode Enum, first, count int) {
+	current().DrawArrays(mode, first, count)
+}
+
+func DrawElements(mode Enum, count int, typ Enum, offset int) error {
+	return current().DrawElements(mode, count, typ, offset)
+}
+
+func CreateFramebuffer() *Framebuffer {
+	return current().CreateFramebuffer()
+}
+
+func BindFramebuffer(target Enum, fb *Framebuffer) error {
+	return current().BindFramebuffer(target, fb)
+}
+
+func DeleteFramebuffer(fb *Framebuffer) error {
+	return current().DeleteFramebuffer(fb)
+}
+
+func FramebufferTexture2D(
+	target, attachment, textarget Enum,
+	texture *Texture, level int,
+) error {
+	return current().FramebufferTexture2D(target, attachment, textarget, texture, level)
+}
+
+func FramebufferRenderbuffer(
+	target, attachment, renderbuffertarget Enum,
+	rb *Renderbuffer,
+) error {
+	return current().FramebufferRenderbuffer(target, attachment, renderbuffertarget, rb)
+}
+
+func CheckFramebufferStatus(target Enum) error {
+	return current().CheckFramebufferStatus(target)
+}
+
+func CreateRenderbuffer() *Renderbuffer {
+	return current().CreateRenderbuffer()
+}
+
+func BindRenderbuffer(target Enum, rb *Renderbuffer) error {
+	return current().BindRenderbuffer(target, rb)
+}
+
+func DeleteRenderbuffer(rb *Renderbuffer) error {
+	return current().DeleteRenderbuffer(rb)
+}
+
+func RenderbufferStorage(target, internalFormat Enum, width, height int) {
+	current().RenderbufferStorage(target, internalFormat, width, height)
+}
+
+func ReadPixels(x, y, width, height int, format, typ Enum, dst []byte) error {
+	return current().ReadPixels(x, y, width, height, format, typ, dst)
+}
+
+func Supports(f Feature) bool {
+	return current().Supports(f)
+}
+
+func ShaderDialect() Dialect {
+	return current().ShaderDialect()
+}
+
+func CreateVertexArray() (*VertexArray, error) {
+	return current().CreateVertexArray()
+}
+
+func BindVertexArray(va *VertexArray) error {
+	return current().BindVertexArray(va)
+}
+
+func DeleteVertexArray(va *VertexArray) error {
+	return current().DeleteVertexArray(va)
+}
+
+func VertexAttribDivisor(a *Attribute, divisor int) error {
+	return current().VertexAttribDivisor(a, divisor)
+}
+
+func DrawArraysInstanced(mode Enum, first, count, instances int) error {
+	return current().DrawArraysInstanced(mode, first, count, instances)
+}
+
+func DrawElementsInstanced(mode Enum, count int, typ Enum, offset, instances int) error {
+	return current().DrawElementsInstanced(mode, count, typ, offset, instances)
+}
+
+func TexImage3D(
+	target Enum, level int, internalFormat Enum,
+	width, height, depth, border int,
+	format, typ Enum,
+	data []byte,
+) error {
+	return current().TexImage3D(target, level, internalFormat, width, height, depth, border, format, typ, data)
+}
+
+func DrawBuffers(bufs []Enum) error {
+	return current().DrawBuffers(bufs)
+}
+
+func GetUniformBlockIndex(p *Program, name string) (int, error) {
+	return current().GetUniformBlockIndex(p, name)
+}
+
+func UniformBlockBinding(p *Program, index, binding int) error {
+	return current().UniformBlockBinding(p, index, binding)
+}
+
+func BindBufferBase(target Enum, index int, buf *Buffer) error {
+	return current().BindBufferBase(target, index, buf)
+}
+
+func VertexAttribIPointer(a *Attribute, size int, typ Enum, stride, offset int) error {
+	return current().VertexAttribIPointer(a, size, typ, stride, offset)
+}
+
+func TexImage2DFromSource(target Enum, level int, internalFormat, format, typ Enum, source interface{}) error {
+	return current().TexImage2DFromSource(target, level, internalFormat, format, typ, source)
+}
+
+func TexImage2DFromImage(target Enum, level int, img image.Image) error {
+	return current().TexImage2DFromImage(target, level, img)
+}
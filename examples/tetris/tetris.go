@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/dmac/gg"
+	"github.com/dmac/gg/helpers"
 	mgl "github.com/go-gl/mathgl/mgl32"
 )
 
@@ -21,7 +22,32 @@ const (
 	WindowHeight = 2*Padding + CellSize*HeightCells
 )
 
+// The shaders are portable sources, which gg translates for the GLSL dialect
+// of each backend.
+const vertShader = `
+uniform mat4 proj, model;
+attribute vec3 vertex_position;
+attribute vec2 vertex_texture;
+varying highp vec2 texture_coordinates;
+
+void main() {
+	gl_Position = proj * model * vec4(vertex_position, 1);
+	texture_coordinates = vertex_texture;
+}
+`
+
+const fragShader = `
+uniform sampler2D tex_loc;
+varying highp vec2 texture_coordinates;
+
+void main() {
+	gl_FragColor = texture2D(tex_loc, texture_coordinates);
+}
+`
+
 type Tetris struct {
+	program *helpers.Program
+
 	textures map[string]*gg.Texture
 	bg       *Sprite
 	board    *Board
@@ -31,7 +57,7 @@ type Tetris struct {
 	gameOver bool
 }
 
-func NewTetris(vertShader, fragShader string) (*Tetris, error) {
+func NewTetris() (*Tetris, error) {
 	gg.Enable(gg.BLEND)
 	gg.BlendFunc(gg.SRC_ALPHA, gg.ONE_MINUS_SRC_ALPHA)
 
@@ -44,23 +70,13 @@ func NewTetris(vertShader, fragShader string) (*Tetris, error) {
 	if err != nil {
 		return nil, err
 	}
-	program := gg.CreateProgram()
-	gg.AttachShader(program, vshader)
-	gg.AttachShader(program, fshader)
-	if err := gg.LinkProgram(program); err != nil {
-		return nil, err
-	}
-
-	// Set the global projection matrix
-	gg.UseProgram(program)
-	proj := mgl.Ortho(0, float32(WindowWidth), float32(WindowHeight), 0, 0, 1)
-	projUniform, err := gg.GetUniformLocation(program, "proj")
+	program, err := helpers.LinkProgram(gg.Default(), vshader, fshader)
 	if err != nil {
 		return nil, err
 	}
-	gg.UniformMatrix4fv(projUniform, proj[:])
 
-	tetris := &Tetris{}
+	tetris := &Tetris{program: program}
+	tetris.Resize(WindowWidth, WindowHeight, WindowWidth, WindowHeight)
 
 	tetris.textures, err = LoadTextures()
 	if err != nil {
@@ -90,6 +106,16 @@ func NewTetris(vertShader, fragShader string) (*Tetris, error) {
 	return tetris, nil
 }
 
+// Resize updates the viewport to cover a drawable surface of fbWidth by
+// fbHeight pixels, and the projection to its size of width by height in
+// screen coordinates. The two differ on HiDPI displays, where a framebuffer
+// pixel is smaller than a screen coordinate.
+func (t *Tetris) Resize(fbWidth, fbHeight, width, height int) {
+	gg.Viewport(0, 0, fbWidth, fbHeight)
+	t.program.Use()
+	t.program.SetMat4("proj", mgl.Ortho(0, float32(width), float32(height), 0, 0, 1))
+}
+
 func (t *Tetris) Draw() {
 	gg.ClearColor(0.5, 0.5, 0.5, 1.0)
 	gg.Clear(gg.COLOR_BUFFER_BIT | gg.DEPTH_BUFFER_BIT)
@@ -171,7 +197,7 @@ type Board struct {
 	current       *Piece
 }
 
-func NewBoard(width, height int, program *gg.Program, textures map[string]*gg.Texture) *Board {
+func NewBoard(width, height int, program *helpers.Program, textures map[string]*gg.Texture) *Board {
 	const boardOriginX, boardOriginY = CellSize * 4, CellSize * 4
 	board := &Board{
 		x:      boardOriginX,
@@ -357,13 +383,14 @@ type Sprite struct {
 	width    float32
 	height   float32
 
-	program *gg.Program
+	program *helpers.Program
 	pbuf    *gg.Buffer
 	tbuf    *gg.Buffer
+	ibuf    *gg.Buffer
 	tex     *gg.Texture
 }
 
-func NewSprite(width, height float32, program *gg.Program, texture *gg.Texture) *Sprite {
+func NewSprite(width, height float32, program *helpers.Program, texture *gg.Texture) *Sprite {
 	s := &Sprite{
 		scale:   1,
 		width:   width,
@@ -399,45 +426,45 @@ func NewSprite(width, height float32, program *gg.Program, texture *gg.Texture)
 	s.tbuf = gg.CreateBuffer()
 	gg.BindBuffer(gg.ARRAY_BUFFER, s.tbuf)
 	gg.BufferData(gg.ARRAY_BUFFER, buf.Bytes(), gg.STATIC_DRAW)
+
+	indices := []uint16{
+		0, 1, 2,
+		0, 2, 3,
+	}
+	buf.Reset()
+	if err := binary.Write(buf, binary.LittleEndian, indices); err != nil {
+		panic(err)
+	}
+	s.ibuf = gg.CreateBuffer()
+	gg.BindBuffer(gg.ELEMENT_ARRAY_BUFFER, s.ibuf)
+	gg.BufferData(gg.ELEMENT_ARRAY_BUFFER, buf.Bytes(), gg.STATIC_DRAW)
 	return s
 }
 
 func (s *Sprite) Draw() error {
-	gg.UseProgram(s.program)
-
-	model := s.transform()
-	modelUniform, err := gg.GetUniformLocation(s.program, "model")
-	if err != nil {
+	s.program.Use()
+	if err := s.program.SetMat4("model", s.transform()); err != nil {
 		return err
 	}
-	gg.UniformMatrix4fv(modelUniform, model[:])
 
 	gg.ActiveTexture(gg.TEXTURE0)
 	gg.BindTexture(gg.TEXTURE_2D, s.tex)
-	textureUniform, err := gg.GetUniformLocation(s.program, "tex_loc")
-	if err != nil {
+	if err := s.program.SetInt("tex_loc", 0); err != nil {
 		return err
 	}
-	gg.Uniform1i(textureUniform, 0)
 
-	vattrib, err := gg.GetAttribLocation(s.program, "vertex_position")
-	if err != nil {
-		return err
-	}
+	vattrib := s.program.Attribute("vertex_position")
 	gg.EnableVertexAttribArray(vattrib)
 	gg.BindBuffer(gg.ARRAY_BUFFER, s.pbuf)
 	gg.VertexAttribPointer(vattrib, 3, gg.FLOAT, false, 0, 0)
 
-	tattrib, err := gg.GetAttribLocation(s.program, "vertex_texture")
-	if err != nil {
-		return err
-	}
+	tattrib := s.program.Attribute("vertex_texture")
 	gg.EnableVertexAttribArray(tattrib)
 	gg.BindBuffer(gg.ARRAY_BUFFER, s.tbuf)
 	gg.VertexAttribPointer(tattrib, 2, gg.FLOAT, false, 0, 0)
 
-	gg.DrawArrays(gg.TRIANGLE_FAN, 0, 4)
-	return nil
+	gg.BindBuffer(gg.ELEMENT_ARRAY_BUFFER, s.ibuf)
+	return gg.DrawElements(gg.TRIANGLES, 6, gg.UNSIGNED_SHORT, 0)
 }
 
 func (s *Sprite) transform() mgl.Mat4 {
@@ -551,3 +578,30 @@ var orientations = map[PieceKind][]Orientation{
 		},
 	},
 }
+
+// LoadTextures loads the block and background images concurrently and
+// creates a texture for each.
+func LoadTextures() (map[string]*gg.Texture, error) {
+	names := []string{
+		"bg", "board",
+		"red", "orange", "yellow", "green", "blue", "cyan", "purple",
+	}
+	paths := make([]string, len(names))
+	for i, name := range names {
+		paths[i] = "images/" + name + ".png"
+	}
+	assets := helpers.LoadAssets(nil, paths...)
+	if err := assets.Wait(); err != nil {
+		return nil, err
+	}
+	gg.ActiveTexture(gg.TEXTURE0)
+	textures := make(map[string]*gg.Texture)
+	for i, name := range names {
+		t, err := assets.Texture(gg.Default(), paths[i], nil)
+		if err != nil {
+			return nil, err
+		}
+		textures[name] = t
+	}
+	return textures, nil
+}
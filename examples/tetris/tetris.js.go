@@ -26,32 +26,9 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	ggwebgl.Init(gl)
+	gg.SetDefault(ggwebgl.NewContext(gl))
 
-	const vshader = `#version 100
-
-uniform mat4 proj, model;
-attribute vec3 vertex_position;
-attribute vec2 vertex_texture;
-varying highp vec2 texture_coordinates;
-
-void main() {
-	gl_Position = proj * model * vec4(vertex_position, 1);
-	texture_coordinates = vertex_texture;
-}
-`
-
-	const fshader = `#version 100
-
-uniform sampler2D tex_loc;
-varying highp vec2 texture_coordinates;
-
-void main() {
-	gl_FragColor = texture2D(tex_loc, texture_coordinates);
-}
-`
-
-	tetris, err := NewTetris(vshader, fshader)
+	tetris, err := NewTetris()
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -59,6 +36,9 @@ void main() {
 	dom.GetWindow().Document().AddEventListener("keypress", false, tetris.handleKeyPress)
 
 	for {
+		if width, height, ok := fitCanvas(canvas); ok {
+			tetris.Resize(width, height, width, height)
+		}
 		tetris.Draw()
 		time.Sleep(16 * time.Millisecond)
 	}
@@ -83,47 +63,15 @@ func (t *Tetris) handleKeyPress(e dom.Event) {
 	}
 }
 
-func LoadTextures() (map[string]*gg.Texture, error) {
-	textures := make(map[string]*gg.Texture)
-	textures["bg"] = NewTextureFromImage(openImage("bg.png"))
-	textures["board"] = NewTextureFromImage(openImage("board.png"))
-	textures["blue"] = NewTextureFromImage(openImage("blue.png"))
-	textures["cyan"] = NewTextureFromImage(openImage("cyan.png"))
-	textures["green"] = NewTextureFromImage(openImage("green.png"))
-	textures["orange"] = NewTextureFromImage(openImage("orange.png"))
-	textures["purple"] = NewTextureFromImage(openImage("purple.png"))
-	textures["red"] = NewTextureFromImage(openImage("red.png"))
-	textures["yellow"] = NewTextureFromImage(openImage("yellow.png"))
-	return textures, nil
-}
-
-func NewTextureFromImage(img *js.Object) *gg.Texture {
-	tex := gg.CreateTexture()
-	gg.ActiveTexture(gg.TEXTURE0)
-	gg.BindTexture(gg.TEXTURE_2D, tex)
-	gg.TexImage2D(
-		gg.TEXTURE_2D,
-		0,
-		gg.RGBA,
-		-1, -1, -1, // ignored by gg/webgl
-		gg.RGBA,
-		gg.UNSIGNED_BYTE,
-		img,
-	)
-	gg.TexParameteri(gg.TEXTURE_2D, gg.TEXTURE_WRAP_S, gg.CLAMP_TO_EDGE)
-	gg.TexParameteri(gg.TEXTURE_2D, gg.TEXTURE_WRAP_T, gg.CLAMP_TO_EDGE)
-	gg.TexParameteri(gg.TEXTURE_2D, gg.TEXTURE_MAG_FILTER, gg.LINEAR)
-	gg.TexParameteri(gg.TEXTURE_2D, gg.TEXTURE_MIN_FILTER, gg.LINEAR)
-
-	return tex
-}
-
-func openImage(path string) *js.Object {
-	img := js.Global.Get("Image").New()
-	img.Set("src", "images/"+path)
-	img.Set("crossOrigin", "")
-	c := make(chan struct{})
-	img.Call("addEventListener", "load", func() { close(c) }, false)
-	<-c
-	return img
+// fitCanvas resizes the canvas drawing buffer to match the size the canvas is
+// displayed at, reporting whether the size changed.
+func fitCanvas(canvas *js.Object) (width, height int, changed bool) {
+	width = canvas.Get("clientWidth").Int()
+	height = canvas.Get("clientHeight").Int()
+	if width == canvas.Get("width").Int() && height == canvas.Get("height").Int() {
+		return width, height, false
+	}
+	canvas.Set("width", width)
+	canvas.Set("height", height)
+	return width, height, true
 }
@@ -6,17 +6,42 @@ import (
 	"log"
 
 	"github.com/dmac/gg"
+	"github.com/dmac/gg/helpers"
 	mgl "github.com/go-gl/mathgl/mgl32"
 )
 
 const WindowWidth = 640
 const WindowHeight = 480
 
+// The shaders are portable sources, which gg translates for the GLSL dialect
+// of each backend.
+const vertShader = `
+uniform mat4 proj;
+attribute vec3 vertex_position;
+attribute vec2 vertex_texture;
+varying highp vec2 texture_coordinates;
+
+void main() {
+	gl_Position = proj * vec4(vertex_position, 1);
+	texture_coordinates = vertex_texture;
+}
+`
+
+const fragShader = `
+uniform sampler2D tex_loc;
+varying highp vec2 texture_coordinates;
+
+void main() {
+	gl_FragColor = texture2D(tex_loc, texture_coordinates);
+}
+`
+
 type Scene struct {
-	sprite *Sprite
+	program *helpers.Program
+	sprite  *Sprite
 }
 
-func NewScene(vertShader, fragShader string, texture *gg.Texture) (*Scene, error) {
+func NewScene(texture *gg.Texture) (*Scene, error) {
 	gg.Enable(gg.DEPTH_TEST)
 	gg.Enable(gg.CULL_FACE)
 	gg.DepthFunc(gg.LESS)
@@ -33,21 +58,10 @@ func NewScene(vertShader, fragShader string, texture *gg.Texture) (*Scene, error
 	if err != nil {
 		return nil, err
 	}
-	program := gg.CreateProgram()
-	gg.AttachShader(program, vshader)
-	gg.AttachShader(program, fshader)
-	if err := gg.LinkProgram(program); err != nil {
-		return nil, err
-	}
-
-	// Set the global projection matrix
-	gg.UseProgram(program)
-	proj := mgl.Ortho(0, float32(WindowWidth), float32(WindowHeight), 0, 0, 1)
-	projUniform, err := gg.GetUniformLocation(program, "proj")
+	program, err := helpers.LinkProgram(gg.Default(), vshader, fshader)
 	if err != nil {
 		return nil, err
 	}
-	gg.UniformMatrix4fv(projUniform, proj[:])
 
 	vertices := []float32{
 		float32(WindowWidth)/2 - 50, float32(WindowHeight)/2 - 50, 0,
@@ -62,7 +76,22 @@ func NewScene(vertShader, fragShader string, texture *gg.Texture) (*Scene, error
 		return nil, err
 	}
 
-	return &Scene{sprite: sprite}, nil
+	scene := &Scene{
+		program: program,
+		sprite:  sprite,
+	}
+	scene.Resize(WindowWidth, WindowHeight, WindowWidth, WindowHeight)
+	return scene, nil
+}
+
+// Resize updates the viewport to cover a drawable surface of fbWidth by
+// fbHeight pixels, and the projection to its size of width by height in
+// screen coordinates. The two differ on HiDPI displays, where a framebuffer
+// pixel is smaller than a screen coordinate.
+func (s *Scene) Resize(fbWidth, fbHeight, width, height int) {
+	gg.Viewport(0, 0, fbWidth, fbHeight)
+	s.program.Use()
+	s.program.SetMat4("proj", mgl.Ortho(0, float32(width), float32(height), 0, 0, 1))
 }
 
 func (s *Scene) Draw() {
@@ -74,11 +103,11 @@ func (s *Scene) Draw() {
 type Sprite struct {
 	pvbo    *gg.Buffer
 	tvbo    *gg.Buffer
-	program *gg.Program
+	program *helpers.Program
 	tex     *gg.Texture
 }
 
-func NewSprite(vertices []float32, program *gg.Program, texture *gg.Texture) (*Sprite, error) {
+func NewSprite(vertices []float32, program *helpers.Program, texture *gg.Texture) (*Sprite, error) {
 	buf := new(bytes.Buffer)
 	if err := binary.Write(buf, binary.LittleEndian, vertices); err != nil {
 		return nil, err
@@ -110,31 +139,29 @@ func NewSprite(vertices []float32, program *gg.Program, texture *gg.Texture) (*S
 }
 
 func (s *Sprite) Draw() {
-	gg.UseProgram(s.program)
+	s.program.Use()
 
-	vattrib, err := gg.GetAttribLocation(s.program, "vertex_position")
-	if err != nil {
-		log.Fatal(err)
-	}
+	vattrib := s.program.Attribute("vertex_position")
 	gg.EnableVertexAttribArray(vattrib)
 	gg.BindBuffer(gg.ARRAY_BUFFER, s.pvbo)
 	gg.VertexAttribPointer(vattrib, 3, gg.FLOAT, false, 0, 0)
 
-	tattrib, err := gg.GetAttribLocation(s.program, "vertex_texture")
-	if err != nil {
-		log.Fatal(err)
-	}
+	tattrib := s.program.Attribute("vertex_texture")
 	gg.EnableVertexAttribArray(tattrib)
 	gg.BindBuffer(gg.ARRAY_BUFFER, s.tvbo)
 	gg.VertexAttribPointer(tattrib, 2, gg.FLOAT, false, 0, 0)
 
 	gg.ActiveTexture(gg.TEXTURE0)
 	gg.BindTexture(gg.TEXTURE_2D, s.tex)
-	texUniform, err := gg.GetUniformLocation(s.program, "tex_loc")
-	if err != nil {
+	if err := s.program.SetInt("tex_loc", 0); err != nil {
 		log.Fatal(err)
 	}
-	gg.Uniform1i(texUniform, 0)
 
 	gg.DrawArrays(gg.TRIANGLE_FAN, 0, 4)
 }
+
+func newImageTexture(path string) (*gg.Texture, error) {
+	assets := helpers.LoadAssets(nil, path)
+	gg.ActiveTexture(gg.TEXTURE0)
+	return assets.Texture(gg.Default(), path, nil)
+}
@@ -25,68 +25,35 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	ggwebgl.Init(gl)
+	gg.SetDefault(ggwebgl.NewContext(gl))
 
-	const vertShader = `
-
-uniform mat4 proj;
-attribute vec3 vertex_position;
-attribute vec2 vertex_texture;
-varying highp vec2 texture_coordinates;
-
-void main() {
-	gl_Position = proj * vec4(vertex_position, 1);
-	texture_coordinates = vertex_texture;
-}
-`
-
-	const fragShader = `#version 100
-
-uniform sampler2D tex_loc;
-varying highp vec2 texture_coordinates;
-
-void main() {
-	gl_FragColor = texture2D(tex_loc, texture_coordinates);
-}
-`
-
-	texture := newImageTexture("sq.png")
-	scene, err := NewScene(vertShader, fragShader, texture)
+	texture, err := newImageTexture("sq.png")
+	if err != nil {
+		log.Fatal(err)
+	}
+	scene, err := NewScene(texture)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	for {
+		if width, height, ok := fitCanvas(canvas); ok {
+			scene.Resize(width, height, width, height)
+		}
 		scene.Draw()
 		time.Sleep(16 * time.Millisecond)
 	}
 }
 
-// TODO(dmac) Move into gg helpers package
-func newImageTexture(path string) *gg.Texture {
-	img := js.Global.Get("Image").New()
-	img.Set("src", path)
-	img.Set("crossOrigin", "")
-	c := make(chan struct{})
-	img.Call("addEventListener", "load", func() { close(c) }, false)
-	<-c
-
-	tex := gg.CreateTexture()
-	gg.ActiveTexture(gg.TEXTURE0)
-	gg.BindTexture(gg.TEXTURE_2D, tex)
-	gg.TexImage2D(
-		gg.TEXTURE_2D,
-		0,
-		gg.RGBA,
-		-1, -1, -1, // These args are ignored in the webgl backend.
-		gg.RGBA,
-		gg.UNSIGNED_BYTE,
-		img,
-	)
-	gg.TexParameteri(gg.TEXTURE_2D, gg.TEXTURE_WRAP_S, gg.CLAMP_TO_EDGE)
-	gg.TexParameteri(gg.TEXTURE_2D, gg.TEXTURE_WRAP_T, gg.CLAMP_TO_EDGE)
-	gg.TexParameteri(gg.TEXTURE_2D, gg.TEXTURE_MAG_FILTER, gg.LINEAR)
-	gg.TexParameteri(gg.TEXTURE_2D, gg.TEXTURE_MIN_FILTER, gg.LINEAR)
-
-	return tex
+// fitCanvas resizes the canvas drawing buffer to match the size the canvas is
+// displayed at, reporting whether the size changed.
+func fitCanvas(canvas *js.Object) (width, height int, changed bool) {
+	width = canvas.Get("clientWidth").Int()
+	height = canvas.Get("clientHeight").Int()
+	if width == canvas.Get("width").Int() && height == canvas.Get("height").Int() {
+		return width, height, false
+	}
+	canvas.Set("width", width)
+	canvas.Set("height", height)
+	return width, height, true
 }
@@ -6,17 +6,38 @@ import (
 	"log"
 
 	"github.com/dmac/gg"
+	"github.com/dmac/gg/helpers"
 	mgl "github.com/go-gl/mathgl/mgl32"
 )
 
 const WindowWidth = 640
 const WindowHeight = 480
 
+// The shaders are portable sources, which gg translates for the GLSL dialect
+// of each backend.
+const vertShader = `
+uniform mat4 proj;
+attribute vec3 vertex_position;
+
+void main() {
+	gl_Position = proj * vec4(vertex_position, 1);
+}
+`
+
+const fragShader = `
+uniform highp vec4 color;
+
+void main() {
+	gl_FragColor = color;
+}
+`
+
 type Scene struct {
+	program  *helpers.Program
 	triangle *Triangle
 }
 
-func NewScene(vertShader, fragShader string) (*Scene, error) {
+func NewScene() (*Scene, error) {
 	gg.Enable(gg.DEPTH_TEST)
 	gg.Enable(gg.CULL_FACE)
 	gg.DepthFunc(gg.LESS)
@@ -30,21 +51,10 @@ func NewScene(vertShader, fragShader string) (*Scene, error) {
 	if err != nil {
 		return nil, err
 	}
-	program := gg.CreateProgram()
-	gg.AttachShader(program, vshader)
-	gg.AttachShader(program, fshader)
-	if err := gg.LinkProgram(program); err != nil {
-		return nil, err
-	}
-
-	// Set the global projection matrix
-	gg.UseProgram(program)
-	proj := mgl.Ortho(0, float32(WindowWidth), float32(WindowHeight), 0, 0, 1)
-	projUniform, err := gg.GetUniformLocation(program, "proj")
+	program, err := helpers.LinkProgram(gg.Default(), vshader, fshader)
 	if err != nil {
 		return nil, err
 	}
-	gg.UniformMatrix4fv(projUniform, proj[:])
 
 	vertices := []float32{
 		float32(WindowWidth) / 2, float32(WindowHeight)/2 - 50, 0,
@@ -58,7 +68,22 @@ func NewScene(vertShader, fragShader string) (*Scene, error) {
 		return nil, err
 	}
 
-	return &Scene{triangle: triangle}, nil
+	scene := &Scene{
+		program:  program,
+		triangle: triangle,
+	}
+	scene.Resize(WindowWidth, WindowHeight, WindowWidth, WindowHeight)
+	return scene, nil
+}
+
+// Resize updates the viewport to cover a drawable surface of fbWidth by
+// fbHeight pixels, and the projection to its size of width by height in
+// screen coordinates. The two differ on HiDPI displays, where a framebuffer
+// pixel is smaller than a screen coordinate.
+func (s *Scene) Resize(fbWidth, fbHeight, width, height int) {
+	gg.Viewport(0, 0, fbWidth, fbHeight)
+	s.program.Use()
+	s.program.SetMat4("proj", mgl.Ortho(0, float32(width), float32(height), 0, 0, 1))
 }
 
 func (s *Scene) Draw() {
@@ -69,10 +94,10 @@ func (s *Scene) Draw() {
 
 type Triangle struct {
 	vbo     *gg.Buffer
-	program *gg.Program
+	program *helpers.Program
 }
 
-func NewTriangle(vertices []float32, program *gg.Program) (*Triangle, error) {
+func NewTriangle(vertices []float32, program *helpers.Program) (*Triangle, error) {
 	buf := new(bytes.Buffer)
 	if err := binary.Write(buf, binary.LittleEndian, vertices); err != nil {
 		return nil, err
@@ -87,17 +112,12 @@ func NewTriangle(vertices []float32, program *gg.Program) (*Triangle, error) {
 }
 
 func (t *Triangle) Draw() {
-	gg.UseProgram(t.program)
-	colorUniform, err := gg.GetUniformLocation(t.program, "color")
-	if err != nil {
+	t.program.Use()
+	if err := t.program.SetVec4("color", mgl.Vec4{1.0, 0.0, 1.0, 1.0}); err != nil {
 		log.Fatal(err)
 	}
-	gg.Uniform4f(colorUniform, 1.0, 0.0, 1.0, 1.0)
 
-	vattrib, err := gg.GetAttribLocation(t.program, "vertex_position")
-	if err != nil {
-		log.Fatal(err)
-	}
+	vattrib := t.program.Attribute("vertex_position")
 	gg.EnableVertexAttribArray(vattrib)
 	gg.BindBuffer(gg.ARRAY_BUFFER, t.vbo)
 	gg.VertexAttribPointer(vattrib, 3, gg.FLOAT, false, 0, 0)
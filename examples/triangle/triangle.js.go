@@ -6,6 +6,7 @@ import (
 	"log"
 	"time"
 
+	"github.com/dmac/gg"
 	ggwebgl "github.com/dmac/gg/webgl"
 	"github.com/gopherjs/gopherjs/js"
 	"github.com/gopherjs/webgl"
@@ -24,33 +25,31 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	ggwebgl.Init(gl)
+	gg.SetDefault(ggwebgl.NewContext(gl))
 
-	const vertShader = `#version 100
-
-uniform mat4 proj;
-attribute vec3 vertex_position;
-
-void main() {
-	gl_Position = proj * vec4(vertex_position, 1);
-}
-`
-
-	const fragShader = `#version 100
-
-uniform highp vec4 color;
-
-void main() {
-	gl_FragColor = color;
-}
-`
-	scene, err := NewScene(vertShader, fragShader)
+	scene, err := NewScene()
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	for {
+		if width, height, ok := fitCanvas(canvas); ok {
+			scene.Resize(width, height, width, height)
+		}
 		scene.Draw()
 		time.Sleep(16 * time.Millisecond)
 	}
 }
+
+// fitCanvas resizes the canvas drawing buffer to match the size the canvas is
+// displayed at, reporting whether the size changed.
+func fitCanvas(canvas *js.Object) (width, height int, changed bool) {
+	width = canvas.Get("clientWidth").Int()
+	height = canvas.Get("clientHeight").Int()
+	if width == canvas.Get("width").Int() && height == canvas.Get("height").Int() {
+		return width, height, false
+	}
+	canvas.Set("width", width)
+	canvas.Set("height", height)
+	return width, height, true
+}
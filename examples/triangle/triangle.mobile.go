@@ -0,0 +1,58 @@
+// +build android ios
+
+package main
+
+import (
+	"log"
+
+	"github.com/dmac/gg"
+	ggmobile "github.com/dmac/gg/mobile"
+	"golang.org/x/mobile/app"
+	"golang.org/x/mobile/event/lifecycle"
+	"golang.org/x/mobile/event/paint"
+	"golang.org/x/mobile/event/size"
+	"golang.org/x/mobile/gl"
+)
+
+func main() {
+	app.Main(func(a app.App) {
+		var scene *Scene
+		var sz size.Event
+		for e := range a.Events() {
+			switch e := a.Filter(e).(type) {
+			case lifecycle.Event:
+				switch e.Crosses(lifecycle.StageVisible) {
+				case lifecycle.CrossOn:
+					// The app services the GL worker; every GL object is
+					// lost with the context, so the scene is rebuilt each
+					// time the app becomes visible.
+					gg.SetDefault(ggmobile.NewContext(e.DrawContext.(gl.Context)))
+					var err error
+					scene, err = NewScene()
+					if err != nil {
+						log.Fatal(err)
+					}
+					if sz.WidthPx > 0 {
+						scene.Resize(sz.WidthPx, sz.HeightPx, sz.WidthPx, sz.HeightPx)
+					}
+					a.Send(paint.Event{})
+				case lifecycle.CrossOff:
+					scene = nil
+					gg.SetDefault(nil)
+				}
+			case size.Event:
+				sz = e
+				if scene != nil {
+					scene.Resize(e.WidthPx, e.HeightPx, e.WidthPx, e.HeightPx)
+				}
+			case paint.Event:
+				if scene == nil || e.External {
+					continue
+				}
+				scene.Draw()
+				a.Publish()
+				a.Send(paint.Event{})
+			}
+		}
+	})
+}
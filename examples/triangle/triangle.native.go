@@ -1,4 +1,4 @@
-// +build !js
+// +build !js,!android,!ios
 
 package main
 
@@ -6,7 +6,8 @@ import (
 	"log"
 	"runtime"
 
-	_ "github.com/dmac/gg/v2.1"
+	"github.com/dmac/gg"
+	gg21 "github.com/dmac/gg/v2.1"
 	"github.com/go-gl/gl/v2.1/gl"
 	"github.com/go-gl/glfw/v3.1/glfw"
 )
@@ -27,28 +28,21 @@ func main() {
 		log.Fatal(err)
 	}
 	window.MakeContextCurrent()
+	gg.SetDefault(gg21.NewContext())
 
-	const vertShader = `#version 120
-uniform mat4 proj;
-attribute vec3 vertex_position;
-
-void main() {
-	gl_Position = proj * vec4(vertex_position, 1);
-}
-`
-
-	const fragShader = `#version 120
-uniform vec4 color;
-
-void main() {
-	gl_FragColor = color;
-}
-`
-	scene, err := NewScene(vertShader, fragShader)
+	scene, err := NewScene()
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	resize := func() {
+		fbWidth, fbHeight := window.GetFramebufferSize()
+		width, height := window.GetSize()
+		scene.Resize(fbWidth, fbHeight, width, height)
+	}
+	window.SetFramebufferSizeCallback(func(*glfw.Window, int, int) { resize() })
+	resize()
+
 	for !window.ShouldClose() {
 		scene.Draw()
 		glfw.PollEvents()
@@ -0,0 +1,305 @@
+package gg
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Feature identifies an optional group of calls that not every backend
+// supports, such as the WebGL 2 and OpenGL 3 additions to the base API.
+type Feature int
+
+const (
+	// FeatureVertexArrays: CreateVertexArray, BindVertexArray and
+	// DeleteVertexArray.
+	FeatureVertexArrays Feature = iota + 1
+	// FeatureInstancing: VertexAttribDivisor, DrawArraysInstanced and
+	// DrawElementsInstanced.
+	FeatureInstancing
+	// FeatureTexture3D: TexImage3D with TEXTURE_3D and TEXTURE_2D_ARRAY.
+	FeatureTexture3D
+	// FeatureDrawBuffers: DrawBuffers, for rendering to several color
+	// attachments.
+	FeatureDrawBuffers
+	// FeatureUniformBuffers: GetUniformBlockIndex, UniformBlockBinding and
+	// BindBufferBase.
+	FeatureUniformBuffers
+	// FeatureIntegerTextures: integer internal formats such as RGBA8UI in
+	// TexImage2D, and VertexAttribIPointer.
+	FeatureIntegerTextures
+	// FeatureImageSources: TexImage2DFromSource, for uploading browser
+	// image, canvas and video elements.
+	FeatureImageSources
+)
+
+func (f Feature) String() string {
+	switch f {
+	case FeatureVertexArrays:
+		return "VertexArrays"
+	case FeatureInstancing:
+		return "Instancing"
+	case FeatureTexture3D:
+		return "Texture3D"
+	case FeatureDrawBuffers:
+		return "DrawBuffers"
+	case FeatureUniformBuffers:
+		return "UniformBuffers"
+	case FeatureIntegerTextures:
+		return "IntegerTextures"
+	case FeatureImageSources:
+		return "ImageSources"
+	}
+	return fmt.Sprintf("Feature(%d)", int(f))
+}
+
+// ErrUnsupported is returned by calls belonging to a Feature the backend
+// does not support.
+var ErrUnsupported = errors.New("gg: feature not supported by backend")
+
+// FeatureBackend is implemented by backends that support optional
+// features. A backend supporting a feature must also implement the
+// interface holding its calls, for example VertexArrayBackend for
+// FeatureVertexArrays.
+type FeatureBackend interface {
+	Supports(Feature) bool
+}
+
+type VertexArrayBackend interface {
+	CreateVertexArray() *VertexArray
+	BindVertexArray(*VertexArray)
+	DeleteVertexArray(*VertexArray)
+}
+
+type InstancingBackend interface {
+	VertexAttribDivisor(a *Attribute, divisor int)
+	DrawArraysInstanced(mode Enum, first, count, instances int)
+	DrawElementsInstanced(mode Enum, count int, typ Enum, offset, instances int) error
+}
+
+type Texture3DBackend interface {
+	TexImage3D(
+		target Enum, level int, internalFormat Enum,
+		width, height, depth, border int,
+		format, typ Enum,
+		data []byte,
+	)
+}
+
+type DrawBuffersBackend interface {
+	DrawBuffers(bufs []Enum)
+}
+
+type UniformBufferBackend interface {
+	GetUniformBlockIndex(p *Program, name string) (int, error)
+	UniformBlockBinding(p *Program, index, binding int)
+	BindBufferBase(target Enum, index int, b *Buffer)
+}
+
+type IntegerBackend interface {
+	VertexAttribIPointer(a *Attribute, size int, typ Enum, stride, offset int)
+}
+
+// ImageSourceBackend is implemented by the web backends. The type of source
+// depends on the backend: *js.Object for GopherJS and js.Value for
+// syscall/js.
+type ImageSourceBackend interface {
+	TexImage2DFromSource(target Enum, level int, internalFormat, format, typ Enum, source interface{}) error
+}
+
+// Supports reports whether the backend of c supports f. Backends that wrap
+// another backend, such as the debug and trace backends, do not pass
+// optional features through.
+func (c *Context) Supports(f Feature) bool {
+	fb, ok := c.backend.(FeatureBackend)
+	return ok && fb.Supports(f)
+}
+
+func (c *Context) vertexArrays() (VertexArrayBackend, error) {
+	b, ok := c.backend.(VertexArrayBackend)
+	if !ok || !c.Supports(FeatureVertexArrays) {
+		return nil, ErrUnsupported
+	}
+	return b, nil
+}
+
+func (c *Context) instancing() (InstancingBackend, error) {
+	b, ok := c.backend.(InstancingBackend)
+	if !ok || !c.Supports(FeatureInstancing) {
+		return nil, ErrUnsupported
+	}
+	return b, nil
+}
+
+func (c *Context) CreateVertexArray() (*VertexArray, error) {
+	b, err := c.vertexArrays()
+	if err != nil {
+		return nil, err
+	}
+	va := b.CreateVertexArray()
+	c.own(va.obj())
+	return va, nil
+}
+
+// BindVertexArray makes va the source of vertex attribute state. A nil va
+// binds the default vertex array.
+func (c *Context) BindVertexArray(va *VertexArray) error {
+	b, err := c.vertexArrays()
+	if err != nil {
+		return err
+	}
+	if err := c.checkOptional("VertexArray", va.obj()); err != nil {
+		return err
+	}
+	b.BindVertexArray(va)
+	return nil
+}
+
+func (c *Context) DeleteVertexArray(va *VertexArray) error {
+	b, err := c.vertexArrays()
+	if err != nil {
+		return err
+	}
+	if err := c.check("VertexArray", va.obj()); err != nil {
+		return err
+	}
+	b.DeleteVertexArray(va)
+	va.deleted = true
+	return nil
+}
+
+// VertexAttribDivisor sets the number of instances drawn before a advances
+// to its next element. A divisor of 0 advances it every vertex.
+func (c *Context) VertexAttribDivisor(a *Attribute, divisor int) error {
+	b, err := c.instancing()
+	if err != nil {
+		return err
+	}
+	if err := c.check("Attribute", a.obj()); err != nil {
+		return err
+	}
+	if divisor < 0 {
+		return fmt.Errorf("gg: VertexAttribDivisor: negative divisor %d", divisor)
+	}
+	b.VertexAttribDivisor(a, divisor)
+	return nil
+}
+
+func (c *Context) DrawArraysInstanced(mode Enum, first, count, instances int) error {
+	b, err := c.instancing()
+	if err != nil {
+		return err
+	}
+	b.DrawArraysInstanced(mode, first, count, instances)
+	return nil
+}
+
+func (c *Context) DrawElementsInstanced(mode Enum, count int, typ Enum, offset, instances int) error {
+	b, err := c.instancing()
+	if err != nil {
+		return err
+	}
+	return b.DrawElementsInstanced(mode, count, typ, offset, instances)
+}
+
+// TexImage3D specifies a three-dimensional texture, or a two-dimensional
+// texture array, for the texture bound to target.
+func (c *Context) TexImage3D(
+	target Enum, level int, internalFormat Enum,
+	width, height, depth, border int,
+	format, typ Enum,
+	data []byte,
+) error {
+	b, ok := c.backend.(Texture3DBackend)
+	if !ok || !c.Supports(FeatureTexture3D) {
+		return ErrUnsupported
+	}
+	b.TexImage3D(target, level, internalFormat, width, height, depth, border, format, typ, data)
+	return nil
+}
+
+// DrawBuffers selects the color attachments that fragment shader outputs
+// are written to, in order. NONE disables an output.
+func (c *Context) DrawBuffers(bufs []Enum) error {
+	b, ok := c.backend.(DrawBuffersBackend)
+	if !ok || !c.Supports(FeatureDrawBuffers) {
+		return ErrUnsupported
+	}
+	b.DrawBuffers(bufs)
+	return nil
+}
+
+func (c *Context) uniformBuffers() (UniformBufferBackend, error) {
+	b, ok := c.backend.(UniformBufferBackend)
+	if !ok || !c.Supports(FeatureUniformBuffers) {
+		return nil, ErrUnsupported
+	}
+	return b, nil
+}
+
+func (c *Context) GetUniformBlockIndex(p *Program, name string) (int, error) {
+	b, err := c.uniformBuffers()
+	if err != nil {
+		return 0, err
+	}
+	if err := c.check("Program", p.obj()); err != nil {
+		return 0, err
+	}
+	return b.GetUniformBlockIndex(p, name)
+}
+
+// UniformBlockBinding assigns the uniform block at index in p to the
+// uniform buffer binding point binding.
+func (c *Context) UniformBlockBinding(p *Program, index, binding int) error {
+	b, err := c.uniformBuffers()
+	if err != nil {
+		return err
+	}
+	if err := c.check("Program", p.obj()); err != nil {
+		return err
+	}
+	b.UniformBlockBinding(p, index, binding)
+	return nil
+}
+
+// BindBufferBase binds buf to the binding point index of target, which is
+// typically UNIFORM_BUFFER. A nil buf unbinds the binding point.
+func (c *Context) BindBufferBase(target Enum, index int, buf *Buffer) error {
+	b, err := c.uniformBuffers()
+	if err != nil {
+		return err
+	}
+	if err := c.checkOptional("Buffer", buf.obj()); err != nil {
+		return err
+	}
+	b.BindBufferBase(target, index, buf)
+	return nil
+}
+
+// VertexAttribIPointer is like VertexAttribPointer for integer attributes
+// (int, ivec and uvec), whose values are not converted to floating point.
+func (c *Context) VertexAttribIPointer(a *Attribute, size int, typ Enum, stride, offset int) error {
+	b, ok := c.backend.(IntegerBackend)
+	if !ok || !c.Supports(FeatureIntegerTextures) {
+		return ErrUnsupported
+	}
+	if err := c.check("Attribute", a.obj()); err != nil {
+		return err
+	}
+	b.VertexAttribIPointer(a, size, typ, stride, offset)
+	return nil
+}
+
+// TexImage2DFromSource specifies level of the texture bound to target from a
+// browser image source, such as an HTMLImageElement, HTMLCanvasElement,
+// HTMLVideoElement or ImageBitmap. The size of the level is that of the
+// source, and its top row is the row at t = 0.
+func (c *Context) TexImage2DFromSource(target Enum, level int, internalFormat, format, typ Enum, source interface{}) error {
+	b, ok := c.backend.(ImageSourceBackend)
+	if !ok || !c.Supports(FeatureImageSources) {
+		return ErrUnsupported
+	}
+	if source == nil {
+		return fmt.Errorf("gg: TexImage2DFromSource: nil source")
+	}
+	return b.TexImage2DFromSource(target, level, internalFormat, format, typ, source)
+}
@@ -1,23 +1,54 @@
 package gg
 
+import (
+	"bytes"
+	"fmt"
+)
+
 type Backend interface {
 	Enable(Enum)
+	Disable(Enum)
 	DepthFunc(f Enum)
 	BlendFunc(sfactor, dfactor Enum)
 	Clear(mask Enum)
 	ClearColor(r, g, b, a float32)
+	Viewport(x, y, width, height int)
+	Scissor(x, y, width, height int)
 	CreateBuffer() *Buffer
 	BindBuffer(typ Enum, b *Buffer)
 	BufferData(typ Enum, src []byte, usage Enum)
+	BufferSubData(typ Enum, offset int, src []byte)
+	DeleteBuffer(*Buffer)
 	CreateShader(src []byte, typ Enum) (*Shader, error)
+	DeleteShader(*Shader)
 	AttachShader(*Program, *Shader)
+	DetachShader(*Program, *Shader)
 	CreateProgram() *Program
+	DeleteProgram(*Program)
 	LinkProgram(*Program) error
+	GetProgramParameter(p *Program, pname Enum) int
+	GetActiveUniform(p *Program, index int) ActiveInfo
+	GetActiveAttrib(p *Program, index int) ActiveInfo
 	UseProgram(*Program)
 	GetUniformLocation(*Program, string) (*Uniform, error)
 	Uniform1f(*Uniform, float32)
-	Uniform1i(*Uniform, int)
+	Uniform2f(*Uniform, float32, float32)
+	Uniform3f(*Uniform, float32, float32, float32)
 	Uniform4f(*Uniform, float32, float32, float32, float32)
+	Uniform1i(*Uniform, int)
+	Uniform2i(*Uniform, int, int)
+	Uniform3i(*Uniform, int, int, int)
+	Uniform4i(*Uniform, int, int, int, int)
+	Uniform1fv(*Uniform, []float32)
+	Uniform2fv(*Uniform, []float32)
+	Uniform3fv(*Uniform, []float32)
+	Uniform4fv(*Uniform, []float32)
+	Uniform1iv(*Uniform, []int32)
+	Uniform2iv(*Uniform, []int32)
+	Uniform3iv(*Uniform, []int32)
+	Uniform4iv(*Uniform, []int32)
+	UniformMatrix2fv(*Uniform, []float32)
+	UniformMatrix3fv(*Uniform, []float32)
 	UniformMatrix4fv(*Uniform, []float32)
 	GetAttribLocation(*Program, string) (*Attribute, error)
 	EnableVertexAttribArray(*Attribute)
@@ -28,168 +59,778 @@ type Backend interface {
 	CreateTexture() *Texture
 	ActiveTexture(tex Enum)
 	BindTexture(target Enum, texture *Texture)
+	DeleteTexture(*Texture)
+	CreateFramebuffer() *Framebuffer
+	BindFramebuffer(target Enum, fb *Framebuffer)
+	DeleteFramebuffer(*Framebuffer)
+	FramebufferTexture2D(
+		target, attachment, textarget Enum,
+		texture *Texture, level int,
+	)
+	FramebufferRenderbuffer(
+		target, attachment, renderbuffertarget Enum,
+		rb *Renderbuffer,
+	)
+	CheckFramebufferStatus(target Enum) Enum
+	CreateRenderbuffer() *Renderbuffer
+	BindRenderbuffer(target Enum, rb *Renderbuffer)
+	DeleteRenderbuffer(*Renderbuffer)
+	RenderbufferStorage(target, internalFormat Enum, width, height int)
 	TexImage2D(
 		target Enum, level int, internalFormat Enum,
 		width, height, border int,
 		format, typ Enum,
-		data interface{},
+		data []byte,
 	)
 	TexParameteri(target Enum, pname Enum, param Enum)
 	DrawArrays(mode Enum, first, count int)
+	DrawElements(mode Enum, count int, typ Enum, offset int) error
+	ReadPixels(x, y, width, height int, format, typ Enum, dst []byte)
+}
+
+// FRAMEBUFFER_INCOMPLETE_DIMENSIONS is returned by CheckFramebufferStatus on
+// WebGL and OpenGL ES 2.0 when attachments have different sizes.
+const FRAMEBUFFER_INCOMPLETE_DIMENSIONS Enum = 0x8CD9
+
+// FramebufferStatusError is returned by CheckFramebufferStatus when the
+// framebuffer is not complete.
+type FramebufferStatusError struct {
+	Status Enum
+}
+
+func (e *FramebufferStatusError) Error() string {
+	var s string
+	switch e.Status {
+	case FRAMEBUFFER_UNDEFINED:
+		s = "undefined"
+	case FRAMEBUFFER_INCOMPLETE_ATTACHMENT:
+		s = "incomplete attachment"
+	case FRAMEBUFFER_INCOMPLETE_MISSING_ATTACHMENT:
+		s = "missing attachment"
+	case FRAMEBUFFER_INCOMPLETE_DIMENSIONS:
+		s = "attachment dimensions differ"
+	case FRAMEBUFFER_INCOMPLETE_DRAW_BUFFER:
+		s = "incomplete draw buffer"
+	case FRAMEBUFFER_INCOMPLETE_READ_BUFFER:
+		s = "incomplete read buffer"
+	case FRAMEBUFFER_INCOMPLETE_MULTISAMPLE:
+		s = "inconsistent multisampling"
+	case FRAMEBUFFER_INCOMPLETE_LAYER_TARGETS:
+		s = "incomplete layer targets"
+	case FRAMEBUFFER_UNSUPPORTED:
+		s = "unsupported attachment combination"
+	default:
+		s = fmt.Sprintf("status 0x%x", uint32(e.Status))
+	}
+	return "gg: framebuffer incomplete: " + s
 }
 
-type Buffer struct {
-	Value interface{}
+type Enum uint32
+
+// Context issues gg calls to a Backend. Each Context is independent, so a
+// program can drive several windows or offscreen surfaces at once, and tests
+// can use their own Context in place of a real one.
+type Context struct {
+	backend Backend
 }
 
-type Program struct {
-	Value interface{}
+// NewContext returns a Context that issues calls to b.
+func NewContext(b Backend) *Context {
+	if b == nil {
+		panic("gg: NewContext with nil backend")
+	}
+	return &Context{backend: b}
 }
 
-type Shader struct {
-	Value interface{}
+// Backend returns the backend c issues calls to.
+func (c *Context) Backend() Backend {
+	return c.backend
 }
 
-type Uniform struct {
-	Value interface{}
+func (c *Context) Enable(capability Enum) {
+	c.backend.Enable(capability)
 }
 
-type Attribute struct {
-	Value interface{}
+func (c *Context) Disable(capability Enum) {
+	c.backend.Disable(capability)
 }
 
-type Texture struct {
-	Value interface{}
+func (c *Context) DepthFunc(f Enum) {
+	c.backend.DepthFunc(f)
 }
 
-type Enum uint32
+func (c *Context) BlendFunc(sfactor, dfactor Enum) {
+	c.backend.BlendFunc(sfactor, dfactor)
+}
 
-var backend Backend
+func (c *Context) Clear(mask Enum) {
+	c.backend.Clear(mask)
+}
 
-func Register(b Backend) {
-	if b == nil {
-		panic("gg: Register with nil backend")
+func (c *Context) ClearColor(r, g, b, a float32) {
+	c.backend.ClearColor(r, g, b, a)
+}
+
+// Viewport sets the window-space rectangle, in pixels, that normalized
+// device coordinates map to. It should be updated whenever the drawable
+// surface changes size.
+func (c *Context) Viewport(x, y, width, height int) {
+	c.backend.Viewport(x, y, width, height)
+}
+
+// Scissor sets the rectangle, in window pixels, outside of which drawing is
+// discarded while SCISSOR_TEST is enabled.
+func (c *Context) Scissor(x, y, width, height int) {
+	c.backend.Scissor(x, y, width, height)
+}
+
+func (c *Context) CreateBuffer() *Buffer {
+	b := c.backend.CreateBuffer()
+	c.own(b.obj())
+	return b
+}
+
+// BindBuffer binds b to the target typ. A nil b unbinds the target.
+func (c *Context) BindBuffer(typ Enum, b *Buffer) error {
+	if err := c.checkOptional("Buffer", b.obj()); err != nil {
+		return err
+	}
+	c.backend.BindBuffer(typ, b)
+	return nil
+}
+
+func (c *Context) BufferData(typ Enum, src []byte, usage Enum) {
+	c.backend.BufferData(typ, src, usage)
+}
+
+// BufferSubData replaces len(src) bytes of the data store of the buffer bound
+// to typ, starting at byte offset, without reallocating it.
+func (c *Context) BufferSubData(typ Enum, offset int, src []byte) {
+	c.backend.BufferSubData(typ, offset, src)
+}
+
+func (c *Context) DeleteBuffer(b *Buffer) error {
+	if err := c.check("Buffer", b.obj()); err != nil {
+		return err
+	}
+	c.backend.DeleteBuffer(b)
+	b.deleted = true
+	return nil
+}
+
+// CreateShader compiles src as a shader of type typ. A portable source, one
+// without a #version directive, is first translated with TranslateShader into
+// the dialect the backend compiles, so the same source works on every
+// backend.
+func (c *Context) CreateShader(src []byte, typ Enum) (*Shader, error) {
+	return c.createShader(src, typ, nil)
+}
+
+// CreateShaderFrom compiles the output of PreprocessShader like CreateShader,
+// reporting compile errors at the files and lines they came from.
+func (c *Context) CreateShaderFrom(src *ShaderSource, typ Enum) (*Shader, error) {
+	return c.createShader(src.Text, typ, src.Lines)
+}
+
+// createShader compiles src, whose lines came from lines, or from src itself
+// if lines is nil.
+func (c *Context) createShader(src []byte, typ Enum, lines []SourceLine) (*Shader, error) {
+	if lines == nil {
+		n := bytes.Count(src, []byte("\n")) + 1
+		lines = make([]SourceLine, n)
+		for i := range lines {
+			lines[i].Line = i + 1
+		}
+	}
+	if d := c.ShaderDialect(); d != 0 && IsPortableShader(src) {
+		out, origin, err := translateShader(src, typ, d)
+		if err != nil {
+			return nil, err
+		}
+		translated := make([]SourceLine, len(origin))
+		for i, o := range origin {
+			if o > 0 && o <= len(lines) {
+				translated[i] = lines[o-1]
+			}
+		}
+		src, lines = out, translated
+	}
+	s, err := c.backend.CreateShader(src, typ)
+	c.own(s.obj())
+	if se, ok := err.(*ShaderError); ok {
+		se.remap(lines)
+	}
+	return s, err
+}
+
+func (c *Context) DeleteShader(s *Shader) error {
+	if err := c.check("Shader", s.obj()); err != nil {
+		return err
+	}
+	c.backend.DeleteShader(s)
+	s.deleted = true
+	return nil
+}
+
+func (c *Context) CreateProgram() *Program {
+	p := c.backend.CreateProgram()
+	c.own(p.obj())
+	return p
+}
+
+func (c *Context) DeleteProgram(p *Program) error {
+	if err := c.check("Program", p.obj()); err != nil {
+		return err
+	}
+	c.backend.DeleteProgram(p)
+	p.deleted = true
+	return nil
+}
+
+func (c *Context) AttachShader(p *Program, s *Shader) error {
+	if err := c.check("Program", p.obj()); err != nil {
+		return err
+	}
+	if err := c.check("Shader", s.obj()); err != nil {
+		return err
+	}
+	c.backend.AttachShader(p, s)
+	return nil
+}
+
+// DetachShader detaches s from p. Unlike AttachShader, s may already have
+// been deleted: a deleted shader stays alive until it is detached from every
+// program.
+func (c *Context) DetachShader(p *Program, s *Shader) error {
+	if err := c.check("Program", p.obj()); err != nil {
+		return err
+	}
+	if s == nil {
+		return c.check("Shader", nil)
+	}
+	if s.ctx != c {
+		return fmt.Errorf("%w: Shader", ErrWrongContext)
+	}
+	c.backend.DetachShader(p, s)
+	return nil
+}
+
+func (c *Context) LinkProgram(p *Program) error {
+	if err := c.check("Program", p.obj()); err != nil {
+		return err
+	}
+	return c.backend.LinkProgram(p)
+}
+
+// GetProgramParameter returns the value of the parameter pname of p, such as
+// LINK_STATUS, ATTACHED_SHADERS, ACTIVE_UNIFORMS or ACTIVE_ATTRIBUTES.
+// Boolean parameters are 1 for true and 0 for false.
+func (c *Context) GetProgramParameter(p *Program, pname Enum) (int, error) {
+	if err := c.check("Program", p.obj()); err != nil {
+		return 0, err
+	}
+	return c.backend.GetProgramParameter(p, pname), nil
+}
+
+// ActiveInfo describes an active uniform or attribute of a linked program.
+type ActiveInfo struct {
+	// Name is the name of the variable. The name of an array ends in "[0]".
+	Name string
+	// Size is the length of an array, or 1.
+	Size int
+	// Type is the variable's type, such as FLOAT_VEC4 or SAMPLER_2D.
+	Type Enum
+}
+
+// GetActiveUniform describes the uniform at index, from 0 to
+// ACTIVE_UNIFORMS-1, of the linked program p.
+func (c *Context) GetActiveUniform(p *Program, index int) (ActiveInfo, error) {
+	if err := c.checkActive("GetActiveUniform", p, ACTIVE_UNIFORMS, index); err != nil {
+		return ActiveInfo{}, err
+	}
+	return c.backend.GetActiveUniform(p, index), nil
+}
+
+// GetActiveAttrib describes the attribute at index, from 0 to
+// ACTIVE_ATTRIBUTES-1, of the linked program p.
+func (c *Context) GetActiveAttrib(p *Program, index int) (ActiveInfo, error) {
+	if err := c.checkActive("GetActiveAttrib", p, ACTIVE_ATTRIBUTES, index); err != nil {
+		return ActiveInfo{}, err
+	}
+	return c.backend.GetActiveAttrib(p, index), nil
+}
+
+// checkActive checks that index is less than the count parameter of p.
+func (c *Context) checkActive(call string, p *Program, count Enum, index int) error {
+	if err := c.check("Program", p.obj()); err != nil {
+		return err
+	}
+	if c.backend.GetProgramParameter(p, LINK_STATUS) == 0 {
+		return fmt.Errorf("gg: %s: program is not linked", call)
+	}
+	if n := c.backend.GetProgramParameter(p, count); index < 0 || index >= n {
+		return fmt.Errorf("gg: %s: index %d out of range [0, %d)", call, index, n)
+	}
+	return nil
+}
+
+// UseProgram installs p as part of the current rendering state. A nil p
+// uninstalls the current program.
+func (c *Context) UseProgram(p *Program) error {
+	if err := c.checkOptional("Program", p.obj()); err != nil {
+		return err
+	}
+	c.backend.UseProgram(p)
+	return nil
+}
+
+func (c *Context) GetUniformLocation(p *Program, name string) (*Uniform, error) {
+	if err := c.check("Program", p.obj()); err != nil {
+		return nil, err
+	}
+	u, err := c.backend.GetUniformLocation(p, name)
+	if u != nil {
+		c.own(u.obj())
+		u.program = p
 	}
-	if backend != nil {
-		panic("gg: Register called twice")
+	return u, err
+}
+
+func (c *Context) Uniform1f(u *Uniform, v0 float32) error {
+	if err := c.checkUniform(u); err != nil {
+		return err
 	}
-	backend = b
+	c.backend.Uniform1f(u, v0)
+	return nil
 }
 
-func Enable(c Enum) {
-	backend.Enable(c)
+func (c *Context) Uniform2f(u *Uniform, v0, v1 float32) error {
+	if err := c.checkUniform(u); err != nil {
+		return err
+	}
+	c.backend.Uniform2f(u, v0, v1)
+	return nil
 }
 
-func DepthFunc(f Enum) {
-	backend.DepthFunc(f)
+func (c *Context) Uniform3f(u *Uniform, v0, v1, v2 float32) error {
+	if err := c.checkUniform(u); err != nil {
+		return err
+	}
+	c.backend.Uniform3f(u, v0, v1, v2)
+	return nil
 }
 
-func BlendFunc(sfactor, dfactor Enum) {
-	backend.BlendFunc(sfactor, dfactor)
+func (c *Context) Uniform4f(u *Uniform, v0, v1, v2, v3 float32) error {
+	if err := c.checkUniform(u); err != nil {
+		return err
+	}
+	c.backend.Uniform4f(u, v0, v1, v2, v3)
+	return nil
 }
 
-func Clear(mask Enum) {
-	backend.Clear(mask)
+func (c *Context) Uniform1i(u *Uniform, v0 int) error {
+	if err := c.checkUniform(u); err != nil {
+		return err
+	}
+	c.backend.Uniform1i(u, v0)
+	return nil
 }
 
-func ClearColor(r, g, b, a float32) {
-	backend.ClearColor(r, g, b, a)
+func (c *Context) Uniform2i(u *Uniform, v0, v1 int) error {
+	if err := c.checkUniform(u); err != nil {
+		return err
+	}
+	c.backend.Uniform2i(u, v0, v1)
+	return nil
 }
 
-func CreateBuffer() *Buffer {
-	return backend.CreateBuffer()
+func (c *Context) Uniform3i(u *Uniform, v0, v1, v2 int) error {
+	if err := c.checkUniform(u); err != nil {
+		return err
+	}
+	c.backend.Uniform3i(u, v0, v1, v2)
+	return nil
 }
 
-func BindBuffer(typ Enum, b *Buffer) {
-	backend.BindBuffer(typ, b)
+func (c *Context) Uniform4i(u *Uniform, v0, v1, v2, v3 int) error {
+	if err := c.checkUniform(u); err != nil {
+		return err
+	}
+	c.backend.Uniform4i(u, v0, v1, v2, v3)
+	return nil
 }
 
-func BufferData(typ Enum, src []byte, usage Enum) {
-	backend.BufferData(typ, src, usage)
+// Uniform1fv sets a float uniform, or consecutive elements of a float array
+// uniform starting at u, from value. len(value) must be non-zero.
+func (c *Context) Uniform1fv(u *Uniform, value []float32) error {
+	if err := checkUniformLen("Uniform1fv", len(value), 1); err != nil {
+		return err
+	}
+	if err := c.checkUniform(u); err != nil {
+		return err
+	}
+	c.backend.Uniform1fv(u, value)
+	return nil
 }
 
-func CreateShader(src []byte, typ Enum) (*Shader, error) {
-	return backend.CreateShader(src, typ)
+// Uniform2fv is like Uniform1fv for vec2 uniforms, taking two components per
+// element, so len(value) must be a non-zero multiple of 2.
+func (c *Context) Uniform2fv(u *Uniform, value []float32) error {
+	if err := checkUniformLen("Uniform2fv", len(value), 2); err != nil {
+		return err
+	}
+	if err := c.checkUniform(u); err != nil {
+		return err
+	}
+	c.backend.Uniform2fv(u, value)
+	return nil
 }
 
-func CreateProgram() *Program {
-	return backend.CreateProgram()
+// Uniform3fv is like Uniform1fv for vec3 uniforms. len(value) must be a
+// non-zero multiple of 3.
+func (c *Context) Uniform3fv(u *Uniform, value []float32) error {
+	if err := checkUniformLen("Uniform3fv", len(value), 3); err != nil {
+		return err
+	}
+	if err := c.checkUniform(u); err != nil {
+		return err
+	}
+	c.backend.Uniform3fv(u, value)
+	return nil
 }
 
-func AttachShader(p *Program, s *Shader) {
-	backend.AttachShader(p, s)
+// Uniform4fv is like Uniform1fv for vec4 uniforms. len(value) must be a
+// non-zero multiple of 4.
+func (c *Context) Uniform4fv(u *Uniform, value []float32) error {
+	if err := checkUniformLen("Uniform4fv", len(value), 4); err != nil {
+		return err
+	}
+	if err := c.checkUniform(u); err != nil {
+		return err
+	}
+	c.backend.Uniform4fv(u, value)
+	return nil
 }
 
-func LinkProgram(p *Program) error {
-	return backend.LinkProgram(p)
+// Uniform1iv sets an int, bool or sampler uniform, or consecutive elements of
+// an array of them starting at u, from value. len(value) must be non-zero.
+func (c *Context) Uniform1iv(u *Uniform, value []int32) error {
+	if err := checkUniformLen("Uniform1iv", len(value), 1); err != nil {
+		return err
+	}
+	if err := c.checkUniform(u); err != nil {
+		return err
+	}
+	c.backend.Uniform1iv(u, value)
+	return nil
 }
 
-func UseProgram(p *Program) {
-	backend.UseProgram(p)
+// Uniform2iv is like Uniform1iv for ivec2 and bvec2 uniforms. len(value)
+// must be a non-zero multiple of 2.
+func (c *Context) Uniform2iv(u *Uniform, value []int32) error {
+	if err := checkUniformLen("Uniform2iv", len(value), 2); err != nil {
+		return err
+	}
+	if err := c.checkUniform(u); err != nil {
+		return err
+	}
+	c.backend.Uniform2iv(u, value)
+	return nil
 }
 
-func GetUniformLocation(p *Program, name string) (*Uniform, error) {
-	return backend.GetUniformLocation(p, name)
+// Uniform3iv is like Uniform1iv for ivec3 and bvec3 uniforms. len(value)
+// must be a non-zero multiple of 3.
+func (c *Context) Uniform3iv(u *Uniform, value []int32) error {
+	if err := checkUniformLen("Uniform3iv", len(value), 3); err != nil {
+		return err
+	}
+	if err := c.checkUniform(u); err != nil {
+		return err
+	}
+	c.backend.Uniform3iv(u, value)
+	return nil
 }
 
-func Uniform1f(u *Uniform, v0 float32) {
-	backend.Uniform1f(u, v0)
+// Uniform4iv is like Uniform1iv for ivec4 and bvec4 uniforms. len(value)
+// must be a non-zero multiple of 4.
+func (c *Context) Uniform4iv(u *Uniform, value []int32) error {
+	if err := checkUniformLen("Uniform4iv", len(value), 4); err != nil {
+		return err
+	}
+	if err := c.checkUniform(u); err != nil {
+		return err
+	}
+	c.backend.Uniform4iv(u, value)
+	return nil
 }
 
-func Uniform4f(u *Uniform, v0, v1, v2, v3 float32) {
-	backend.Uniform4f(u, v0, v1, v2, v3)
+// UniformMatrix2fv sets a mat2 uniform, or consecutive elements of a mat2
+// array uniform, from value, which holds one or more matrices of 4 values
+// each in column-major order.
+func (c *Context) UniformMatrix2fv(u *Uniform, value []float32) error {
+	if err := checkUniformLen("UniformMatrix2fv", len(value), 4); err != nil {
+		return err
+	}
+	if err := c.checkUniform(u); err != nil {
+		return err
+	}
+	c.backend.UniformMatrix2fv(u, value)
+	return nil
 }
 
-func Uniform1i(u *Uniform, v0 int) {
-	backend.Uniform1i(u, v0)
+// UniformMatrix3fv is like UniformMatrix2fv for mat3 uniforms, with 9
+// values per matrix.
+func (c *Context) UniformMatrix3fv(u *Uniform, value []float32) error {
+	if err := checkUniformLen("UniformMatrix3fv", len(value), 9); err != nil {
+		return err
+	}
+	if err := c.checkUniform(u); err != nil {
+		return err
+	}
+	c.backend.UniformMatrix3fv(u, value)
+	return nil
 }
 
-func UniformMatrix4fv(u *Uniform, value []float32) {
-	backend.UniformMatrix4fv(u, value)
+// UniformMatrix4fv is like UniformMatrix2fv for mat4 uniforms, with 16
+// values per matrix.
+func (c *Context) UniformMatrix4fv(u *Uniform, value []float32) error {
+	if err := checkUniformLen("UniformMatrix4fv", len(value), 16); err != nil {
+		return err
+	}
+	if err := c.checkUniform(u); err != nil {
+		return err
+	}
+	c.backend.UniformMatrix4fv(u, value)
+	return nil
 }
 
-func GetAttribLocation(p *Program, name string) (*Attribute, error) {
-	return backend.GetAttribLocation(p, name)
+// checkUniform is like check for u, and also fails once the program u
+// belongs to has been deleted.
+func (c *Context) checkUniform(u *Uniform) error {
+	if err := c.check("Uniform", u.obj()); err != nil {
+		return err
+	}
+	if u.program != nil && u.program.deleted {
+		return fmt.Errorf("%w: Program of Uniform", ErrDeleted)
+	}
+	return nil
 }
 
-func EnableVertexAttribArray(a *Attribute) {
-	backend.EnableVertexAttribArray(a)
+func checkUniformLen(fn string, n, size int) error {
+	if n == 0 || n%size != 0 {
+		return fmt.Errorf("gg: %s: length %d is not a non-zero multiple of %d", fn, n, size)
+	}
+	return nil
 }
 
-func VertexAttribPointer(a *Attribute, size int, typ Enum, normalized bool, stride, offset int) {
-	backend.VertexAttribPointer(a, size, typ, normalized, stride, offset)
+func (c *Context) GetAttribLocation(p *Program, name string) (*Attribute, error) {
+	if err := c.check("Program", p.obj()); err != nil {
+		return nil, err
+	}
+	a, err := c.backend.GetAttribLocation(p, name)
+	c.own(a.obj())
+	return a, err
 }
 
-func CreateTexture() *Texture {
-	return backend.CreateTexture()
+func (c *Context) EnableVertexAttribArray(a *Attribute) error {
+	if err := c.check("Attribute", a.obj()); err != nil {
+		return err
+	}
+	c.backend.EnableVertexAttribArray(a)
+	return nil
 }
 
-func ActiveTexture(tex Enum) {
-	backend.ActiveTexture(tex)
+func (c *Context) VertexAttribPointer(a *Attribute, size int, typ Enum, normalized bool, stride, offset int) error {
+	if err := c.check("Attribute", a.obj()); err != nil {
+		return err
+	}
+	c.backend.VertexAttribPointer(a, size, typ, normalized, stride, offset)
+	return nil
 }
 
-func BindTexture(target Enum, texture *Texture) {
-	backend.BindTexture(target, texture)
+func (c *Context) CreateTexture() *Texture {
+	t := c.backend.CreateTexture()
+	c.own(t.obj())
+	return t
 }
 
-func TexImage2D(
+func (c *Context) ActiveTexture(tex Enum) {
+	c.backend.ActiveTexture(tex)
+}
+
+// BindTexture binds texture to target. A nil texture unbinds the target.
+func (c *Context) BindTexture(target Enum, texture *Texture) error {
+	if err := c.checkOptional("Texture", texture.obj()); err != nil {
+		return err
+	}
+	c.backend.BindTexture(target, texture)
+	return nil
+}
+
+func (c *Context) DeleteTexture(t *Texture) error {
+	if err := c.check("Texture", t.obj()); err != nil {
+		return err
+	}
+	c.backend.DeleteTexture(t)
+	t.deleted = true
+	return nil
+}
+
+// TexImage2D specifies level of the texture bound to target, which is
+// TEXTURE_2D or one of the cube map faces, as width by height pixels of the
+// given format and type stored with internalFormat. The first row of data is
+// the row at t = 0, and rows are padded to a multiple of 4 bytes. A nil data
+// allocates the level without initializing it.
+//
+// To upload from a Go image use TexImage2DFromImage, and from a browser
+// image, canvas or video element use TexImage2DFromSource.
+func (c *Context) TexImage2D(
 	target Enum, level int, internalFormat Enum,
 	width, height, border int,
 	format, typ Enum,
-	data interface{},
-) {
-	backend.TexImage2D(
+	data []byte,
+) error {
+	if width < 0 || height < 0 || level < 0 {
+		return fmt.Errorf("gg: TexImage2D: invalid level %d or size %dx%d", level, width, height)
+	}
+	if data != nil {
+		n := imageSize(width, height, format, typ)
+		if n < 0 {
+			return fmt.Errorf("gg: TexImage2D: unsupported format 0x%x and type 0x%x", uint32(format), uint32(typ))
+		}
+		if len(data) < n {
+			return fmt.Errorf("gg: TexImage2D: need %d bytes, have %d", n, len(data))
+		}
+	}
+	c.backend.TexImage2D(
 		target, level, internalFormat,
 		width, height, border,
 		format, typ,
 		data,
 	)
+	return nil
 }
 
-func TexParameteri(target Enum, pname Enum, param Enum) {
-	backend.TexParameteri(target, pname, param)
+func (c *Context) TexParameteri(target Enum, pname Enum, param Enum) {
+	c.backend.TexParameteri(target, pname, param)
 }
 
-func DrawArrays(mode Enum, first, count int) {
-	backend.DrawArrays(mode, first, count)
+func (c *Context) DrawArrays(mode Enum, first, count int) {
+	c.backend.DrawArrays(mode, first, count)
+}
+
+// DrawElements renders primitives using count indices of type typ read from
+// the buffer bound to ELEMENT_ARRAY_BUFFER, starting at byte offset.
+// Valid index types are UNSIGNED_BYTE, UNSIGNED_SHORT and, where the backend
+// supports it, UNSIGNED_INT.
+func (c *Context) DrawElements(mode Enum, count int, typ Enum, offset int) error {
+	return c.backend.DrawElements(mode, count, typ, offset)
+}
+
+func (c *Context) CreateFramebuffer() *Framebuffer {
+	fb := c.backend.CreateFramebuffer()
+	c.own(fb.obj())
+	return fb
+}
+
+// BindFramebuffer binds fb to target. A nil fb binds the default
+// framebuffer.
+func (c *Context) BindFramebuffer(target Enum, fb *Framebuffer) error {
+	if err := c.checkOptional("Framebuffer", fb.obj()); err != nil {
+		return err
+	}
+	c.backend.BindFramebuffer(target, fb)
+	return nil
+}
+
+func (c *Context) DeleteFramebuffer(fb *Framebuffer) error {
+	if err := c.check("Framebuffer", fb.obj()); err != nil {
+		return err
+	}
+	c.backend.DeleteFramebuffer(fb)
+	fb.deleted = true
+	return nil
+}
+
+// FramebufferTexture2D attaches level of texture to attachment of the
+// framebuffer bound to target. A nil texture detaches the current
+// attachment.
+func (c *Context) FramebufferTexture2D(
+	target, attachment, textarget Enum,
+	texture *Texture, level int,
+) error {
+	if err := c.checkOptional("Texture", texture.obj()); err != nil {
+		return err
+	}
+	c.backend.FramebufferTexture2D(target, attachment, textarget, texture, level)
+	return nil
+}
+
+// FramebufferRenderbuffer attaches rb to attachment of the framebuffer bound
+// to target. A nil rb detaches the current attachment.
+func (c *Context) FramebufferRenderbuffer(
+	target, attachment, renderbuffertarget Enum,
+	rb *Renderbuffer,
+) error {
+	if err := c.checkOptional("Renderbuffer", rb.obj()); err != nil {
+		return err
+	}
+	c.backend.FramebufferRenderbuffer(target, attachment, renderbuffertarget, rb)
+	return nil
+}
+
+// CheckFramebufferStatus returns nil if the framebuffer bound to target is
+// complete and a *FramebufferStatusError otherwise.
+func (c *Context) CheckFramebufferStatus(target Enum) error {
+	status := c.backend.CheckFramebufferStatus(target)
+	if status == FRAMEBUFFER_COMPLETE {
+		return nil
+	}
+	return &FramebufferStatusError{Status: status}
+}
+
+func (c *Context) CreateRenderbuffer() *Renderbuffer {
+	rb := c.backend.CreateRenderbuffer()
+	c.own(rb.obj())
+	return rb
+}
+
+// BindRenderbuffer binds rb to target. A nil rb unbinds the target.
+func (c *Context) BindRenderbuffer(target Enum, rb *Renderbuffer) error {
+	if err := c.checkOptional("Renderbuffer", rb.obj()); err != nil {
+		return err
+	}
+	c.backend.BindRenderbuffer(target, rb)
+	return nil
+}
+
+func (c *Context) DeleteRenderbuffer(rb *Renderbuffer) error {
+	if err := c.check("Renderbuffer", rb.obj()); err != nil {
+		return err
+	}
+	c.backend.DeleteRenderbuffer(rb)
+	rb.deleted = true
+	return nil
+}
+
+func (c *Context) RenderbufferStorage(target, internalFormat Enum, width, height int) {
+	c.backend.RenderbufferStorage(target, internalFormat, width, height)
+}
+
+// ReadPixels reads a block of pixels from the current framebuffer into dst.
+// As in OpenGL, x and y give the lower left corner of the block and rows are
+// stored bottom-up.
+func (c *Context) ReadPixels(x, y, width, height int, format, typ Enum, dst []byte) error {
+	n := imageSize(width, height, format, typ)
+	if n < 0 {
+		return fmt.Errorf("gg: ReadPixels: unsupported format 0x%x and type 0x%x", uint32(format), uint32(typ))
+	}
+	if len(dst) < n {
+		return fmt.Errorf("gg: ReadPixels: need %d bytes, have %d", n, len(dst))
+	}
+	if n == 0 {
+		return nil
+	}
+	c.backend.ReadPixels(x, y, width, height, format, typ, dst)
+	return nil
 }
@@ -0,0 +1,497 @@
+// Package gg_gl33 implements gg.Backend on an OpenGL 3.3 core profile
+// context.
+//
+// Core profile contexts have no default vertex array object, so the backend
+// creates one and keeps it bound; code written against the
+// VertexAttribPointer and EnableVertexAttribArray API runs unchanged. The
+// context must be created as a 3.3 core profile context, which with GLFW
+// means setting these window hints before creating the window:
+//
+//	glfw.WindowHint(glfw.ContextVersionMajor, 3)
+//	glfw.WindowHint(glfw.ContextVersionMinor, 3)
+//	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+//	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+//
+// Shaders must be written in a GLSL version supported by core profile, such
+// as "#version 330 core", or be portable sources, which gg translates to GLSL
+// 3.30.
+package gg_gl33
+
+import (
+	"fmt"
+	"strings"
+	"unsafe"
+
+	"github.com/dmac/gg"
+	"github.com/go-gl/gl/v3.3-core/gl"
+)
+
+type backend struct {
+	// vao is the vertex array object standing in for the default one that
+	// compatibility contexts provide.
+	vao uint32
+
+	objs gg.Table
+}
+
+var _ gg.Backend = (*backend)(nil)
+
+// NewContext returns a gg.Context that issues calls to the OpenGL context
+// current on the calling thread. gl.Init must have been called first.
+func NewContext() *gg.Context {
+	b := &backend{}
+	gl.GenVertexArrays(1, &b.vao)
+	gl.BindVertexArray(b.vao)
+	return gg.NewContext(b)
+}
+
+// name returns the OpenGL name of the object with the given ID, or 0 if
+// there is none.
+func (b *backend) name(id gg.ID) uint32 {
+	v, _ := b.objs.Get(id).(uint32)
+	return v
+}
+
+// uniform returns the location u refers to, or -1, which OpenGL ignores, if
+// there is none.
+func (b *backend) uniform(u *gg.Uniform) int32 {
+	if v, ok := b.objs.Get(u.ID).(int32); ok {
+		return v
+	}
+	return -1
+}
+
+func (*backend) Enable(c gg.Enum) {
+	gl.Enable(uint32(c))
+}
+
+func (*backend) Disable(c gg.Enum) {
+	gl.Disable(uint32(c))
+}
+
+func (*backend) DepthFunc(f gg.Enum) {
+	gl.DepthFunc(uint32(f))
+}
+
+func (*backend) BlendFunc(sfactor, dfactor gg.Enum) {
+	gl.BlendFunc(uint32(sfactor), uint32(dfactor))
+}
+
+func (*backend) Clear(mask gg.Enum) {
+	gl.Clear(uint32(mask))
+}
+
+func (*backend) ClearColor(r, g, b, a float32) {
+	gl.ClearColor(r, g, b, a)
+}
+
+func (*backend) Viewport(x, y, width, height int) {
+	gl.Viewport(int32(x), int32(y), int32(width), int32(height))
+}
+
+func (*backend) Scissor(x, y, width, height int) {
+	gl.Scissor(int32(x), int32(y), int32(width), int32(height))
+}
+
+func (b *backend) CreateBuffer() *gg.Buffer {
+	var buf uint32
+	gl.GenBuffers(1, &buf)
+	return &gg.Buffer{ID: b.objs.Add(buf)}
+}
+
+func (b *backend) BindBuffer(typ gg.Enum, buf *gg.Buffer) {
+	var v uint32
+	if buf != nil {
+		v = b.name(buf.ID)
+	}
+	gl.BindBuffer(uint32(typ), v)
+}
+
+func (*backend) BufferData(typ gg.Enum, src []byte, usage gg.Enum) {
+	gl.BufferData(uint32(typ), len(src), gl.Ptr(src), uint32(usage))
+}
+
+func (*backend) BufferSubData(typ gg.Enum, offset int, src []byte) {
+	if len(src) == 0 {
+		return
+	}
+	gl.BufferSubData(uint32(typ), offset, len(src), gl.Ptr(src))
+}
+
+func (b *backend) DeleteBuffer(buf *gg.Buffer) {
+	v := b.name(buf.ID)
+	gl.DeleteBuffers(1, &v)
+	b.objs.Delete(buf.ID)
+}
+
+func (*backend) ShaderDialect() gg.Dialect {
+	return gg.GLSL330
+}
+
+func (b *backend) CreateShader(src []byte, typ gg.Enum) (*gg.Shader, error) {
+	csrc, free := gl.Strs(string(src) + "\x00")
+	defer free()
+	shader := gl.CreateShader(uint32(typ))
+	gl.ShaderSource(shader, 1, csrc, nil)
+	gl.CompileShader(shader)
+	var status int32
+	gl.GetShaderiv(shader, gl.COMPILE_STATUS, &status)
+	if status == gl.TRUE {
+		return &gg.Shader{ID: b.objs.Add(shader)}, nil
+	}
+	var logLength int32
+	gl.GetShaderiv(shader, gl.INFO_LOG_LENGTH, &logLength)
+	log := strings.Repeat("\x00", int(logLength+1))
+	gl.GetShaderInfoLog(shader, logLength, nil, gl.Str(log))
+	gl.DeleteShader(shader)
+	return nil, gg.NewShaderError(typ, src, log)
+}
+
+func (b *backend) DeleteShader(s *gg.Shader) {
+	gl.DeleteShader(b.name(s.ID))
+	// A shader that is still attached keeps its ID until it is detached.
+	b.objs.Delete(s.ID)
+}
+
+func (b *backend) CreateProgram() *gg.Program {
+	p := gl.CreateProgram()
+	return &gg.Program{ID: b.objs.Add(p)}
+}
+
+func (b *backend) DeleteProgram(p *gg.Program) {
+	gl.DeleteProgram(b.name(p.ID))
+	b.objs.Delete(p.ID)
+}
+
+func (b *backend) AttachShader(p *gg.Program, s *gg.Shader) {
+	gl.AttachShader(b.name(p.ID), b.name(s.ID))
+	b.objs.Attach(p.ID, s.ID)
+}
+
+func (b *backend) DetachShader(p *gg.Program, s *gg.Shader) {
+	gl.DetachShader(b.name(p.ID), b.name(s.ID))
+	b.objs.Detach(p.ID, s.ID)
+}
+
+func (b *backend) LinkProgram(p *gg.Program) error {
+	pv := b.name(p.ID)
+	gl.LinkProgram(pv)
+	var status int32
+	gl.GetProgramiv(pv, gl.LINK_STATUS, &status)
+	if status == gl.TRUE {
+		return nil
+	}
+	var logLength int32
+	gl.GetProgramiv(pv, gl.INFO_LOG_LENGTH, &logLength)
+	log := strings.Repeat("\x00", int(logLength+1))
+	gl.GetProgramInfoLog(pv, logLength, nil, gl.Str(log))
+	return gg.NewLinkError(log)
+}
+
+func (b *backend) GetProgramParameter(p *gg.Program, pname gg.Enum) int {
+	var v int32
+	gl.GetProgramiv(b.name(p.ID), uint32(pname), &v)
+	return int(v)
+}
+
+func (b *backend) GetActiveUniform(p *gg.Program, index int) gg.ActiveInfo {
+	return b.activeInfo(p, index, gl.ACTIVE_UNIFORM_MAX_LENGTH, gl.GetActiveUniform)
+}
+
+func (b *backend) GetActiveAttrib(p *gg.Program, index int) gg.ActiveInfo {
+	return b.activeInfo(p, index, gl.ACTIVE_ATTRIBUTE_MAX_LENGTH, gl.GetActiveAttrib)
+}
+
+func (b *backend) activeInfo(
+	p *gg.Program, index int, maxLength uint32,
+	get func(program, index uint32, bufSize int32, length, size *int32, typ *uint32, name *uint8),
+) gg.ActiveInfo {
+	pv := b.name(p.ID)
+	var n int32
+	gl.GetProgramiv(pv, maxLength, &n)
+	name := make([]byte, n+1)
+	var length, size int32
+	var typ uint32
+	get(pv, uint32(index), int32(len(name)), &length, &size, &typ, &name[0])
+	return gg.ActiveInfo{Name: string(name[:length]), Size: int(size), Type: gg.Enum(typ)}
+}
+
+func (b *backend) UseProgram(p *gg.Program) {
+	var v uint32
+	if p != nil {
+		v = b.name(p.ID)
+	}
+	gl.UseProgram(v)
+}
+
+func (b *backend) GetUniformLocation(p *gg.Program, name string) (*gg.Uniform, error) {
+	u := gl.GetUniformLocation(b.name(p.ID), gl.Str(name+"\x00"))
+	if u < 0 {
+		return nil, fmt.Errorf("gg: no uniform named %s", name)
+	}
+	return &gg.Uniform{ID: b.objs.Named(p.ID, name, u)}, nil
+}
+
+func (b *backend) Uniform1f(u *gg.Uniform, v0 float32) {
+	gl.Uniform1f(b.uniform(u), v0)
+}
+
+func (b *backend) Uniform2f(u *gg.Uniform, v0, v1 float32) {
+	gl.Uniform2f(b.uniform(u), v0, v1)
+}
+
+func (b *backend) Uniform3f(u *gg.Uniform, v0, v1, v2 float32) {
+	gl.Uniform3f(b.uniform(u), v0, v1, v2)
+}
+
+func (b *backend) Uniform4f(u *gg.Uniform, v0, v1, v2, v3 float32) {
+	gl.Uniform4f(b.uniform(u), v0, v1, v2, v3)
+}
+
+func (b *backend) Uniform1i(u *gg.Uniform, v0 int) {
+	gl.Uniform1i(b.uniform(u), int32(v0))
+}
+
+func (b *backend) Uniform2i(u *gg.Uniform, v0, v1 int) {
+	gl.Uniform2i(b.uniform(u), int32(v0), int32(v1))
+}
+
+func (b *backend) Uniform3i(u *gg.Uniform, v0, v1, v2 int) {
+	gl.Uniform3i(b.uniform(u), int32(v0), int32(v1), int32(v2))
+}
+
+func (b *backend) Uniform4i(u *gg.Uniform, v0, v1, v2, v3 int) {
+	gl.Uniform4i(b.uniform(u), int32(v0), int32(v1), int32(v2), int32(v3))
+}
+
+func (b *backend) Uniform1fv(u *gg.Uniform, values []float32) {
+	gl.Uniform1fv(b.uniform(u), int32(len(values)/1), &values[0])
+}
+
+func (b *backend) Uniform2fv(u *gg.Uniform, values []float32) {
+	gl.Uniform2fv(b.uniform(u), int32(len(values)/2), &values[0])
+}
+
+func (b *backend) Uniform3fv(u *gg.Uniform, values []float32) {
+	gl.Uniform3fv(b.uniform(u), int32(len(values)/3), &values[0])
+}
+
+func (b *backend) Uniform4fv(u *gg.Uniform, values []float32) {
+	gl.Uniform4fv(b.uniform(u), int32(len(values)/4), &values[0])
+}
+
+func (b *backend) Uniform1iv(u *gg.Uniform, values []int32) {
+	gl.Uniform1iv(b.uniform(u), int32(len(values)/1), &values[0])
+}
+
+func (b *backend) Uniform2iv(u *gg.Uniform, values []int32) {
+	gl.Uniform2iv(b.uniform(u), int32(len(values)/2), &values[0])
+}
+
+func (b *backend) Uniform3iv(u *gg.Uniform, values []int32) {
+	gl.Uniform3iv(b.uniform(u), int32(len(values)/3), &values[0])
+}
+
+func (b *backend) Uniform4iv(u *gg.Uniform, values []int32) {
+	gl.Uniform4iv(b.uniform(u), int32(len(values)/4), &values[0])
+}
+
+func (b *backend) UniformMatrix2fv(u *gg.Uniform, values []float32) {
+	gl.UniformMatrix2fv(b.uniform(u), int32(len(values)/4), false, &values[0])
+}
+
+func (b *backend) UniformMatrix3fv(u *gg.Uniform, values []float32) {
+	gl.UniformMatrix3fv(b.uniform(u), int32(len(values)/9), false, &values[0])
+}
+
+func (b *backend) UniformMatrix4fv(u *gg.Uniform, values []float32) {
+	gl.UniformMatrix4fv(b.uniform(u), int32(len(values)/16), false, &values[0])
+}
+
+func (b *backend) GetAttribLocation(p *gg.Program, name string) (*gg.Attribute, error) {
+	a := gl.GetAttribLocation(b.name(p.ID), gl.Str(name+"\x00"))
+	if a < 0 {
+		return nil, fmt.Errorf("gg: no attribute named %s", name)
+	}
+	return &gg.Attribute{Location: int(a)}, nil
+}
+
+func (*backend) EnableVertexAttribArray(a *gg.Attribute) {
+	gl.EnableVertexAttribArray(uint32(a.Location))
+}
+
+func (*backend) VertexAttribPointer(a *gg.Attribute, size int, typ gg.Enum, normalized bool, stride, offset int) {
+	gl.VertexAttribPointer(
+		uint32(a.Location),
+		int32(size),
+		uint32(typ),
+		normalized,
+		int32(stride),
+		gl.PtrOffset(offset),
+	)
+}
+
+func (b *backend) CreateTexture() *gg.Texture {
+	var t uint32
+	gl.GenTextures(1, &t)
+	return &gg.Texture{ID: b.objs.Add(t)}
+}
+
+func (*backend) ActiveTexture(tex gg.Enum) {
+	gl.ActiveTexture(uint32(tex))
+}
+
+func (b *backend) BindTexture(target gg.Enum, texture *gg.Texture) {
+	var v uint32
+	if texture != nil {
+		v = b.name(texture.ID)
+	}
+	gl.BindTexture(uint32(target), v)
+}
+
+func (b *backend) DeleteTexture(t *gg.Texture) {
+	v := b.name(t.ID)
+	gl.DeleteTextures(1, &v)
+	b.objs.Delete(t.ID)
+}
+
+func (*backend) TexImage2D(
+	target gg.Enum, level int, internalFormat gg.Enum,
+	width, height, border int,
+	format, typ gg.Enum,
+	data []byte,
+) {
+	// The luminance and alpha formats were removed from core profile. Their
+	// data is uploaded as red or red-green and swizzled back on sampling.
+	var swizzle *[4]int32
+	switch internalFormat {
+	case gg.ALPHA:
+		internalFormat = gl.R8
+		swizzle = &[4]int32{gl.ZERO, gl.ZERO, gl.ZERO, gl.RED}
+	case gg.LUMINANCE:
+		internalFormat = gl.R8
+		swizzle = &[4]int32{gl.RED, gl.RED, gl.RED, gl.ONE}
+	case gg.LUMINANCE_ALPHA:
+		internalFormat = gl.RG8
+		swizzle = &[4]int32{gl.RED, gl.RED, gl.RED, gl.GREEN}
+	}
+	switch format {
+	case gg.ALPHA, gg.LUMINANCE:
+		format = gl.RED
+	case gg.LUMINANCE_ALPHA:
+		format = gl.RG
+	}
+	var pixels unsafe.Pointer
+	if len(data) > 0 {
+		pixels = gl.Ptr(data)
+	}
+	gl.TexImage2D(
+		uint32(target), int32(level), int32(internalFormat),
+		int32(width), int32(height), int32(border),
+		uint32(format), uint32(typ),
+		pixels,
+	)
+	if swizzle != nil {
+		binding := uint32(target)
+		if target >= gg.TEXTURE_CUBE_MAP_POSITIVE_X && target <= gg.TEXTURE_CUBE_MAP_NEGATIVE_Z {
+			binding = gl.TEXTURE_CUBE_MAP
+		}
+		gl.TexParameteriv(binding, gl.TEXTURE_SWIZZLE_RGBA, &swizzle[0])
+	}
+}
+
+func (*backend) TexParameteri(target gg.Enum, pname gg.Enum, param gg.Enum) {
+	gl.TexParameteri(uint32(target), uint32(pname), int32(param))
+}
+
+func (*backend) DrawArrays(mode gg.Enum, first, count int) {
+	gl.DrawArrays(uint32(mode), int32(first), int32(count))
+}
+
+func (*backend) DrawElements(mode gg.Enum, count int, typ gg.Enum, offset int) error {
+	switch typ {
+	case gg.UNSIGNED_BYTE, gg.UNSIGNED_SHORT, gg.UNSIGNED_INT:
+	default:
+		return fmt.Errorf("gg: invalid index type 0x%x", uint32(typ))
+	}
+	gl.DrawElements(uint32(mode), int32(count), uint32(typ), gl.PtrOffset(offset))
+	return nil
+}
+
+func (b *backend) CreateFramebuffer() *gg.Framebuffer {
+	var fb uint32
+	gl.GenFramebuffers(1, &fb)
+	return &gg.Framebuffer{ID: b.objs.Add(fb)}
+}
+
+func (b *backend) BindFramebuffer(target gg.Enum, fb *gg.Framebuffer) {
+	var v uint32
+	if fb != nil {
+		v = b.name(fb.ID)
+	}
+	gl.BindFramebuffer(uint32(target), v)
+}
+
+func (b *backend) DeleteFramebuffer(fb *gg.Framebuffer) {
+	v := b.name(fb.ID)
+	gl.DeleteFramebuffers(1, &v)
+	b.objs.Delete(fb.ID)
+}
+
+func (b *backend) FramebufferTexture2D(
+	target, attachment, textarget gg.Enum,
+	texture *gg.Texture, level int,
+) {
+	var v uint32
+	if texture != nil {
+		v = b.name(texture.ID)
+	}
+	gl.FramebufferTexture2D(uint32(target), uint32(attachment), uint32(textarget), v, int32(level))
+}
+
+func (b *backend) FramebufferRenderbuffer(
+	target, attachment, renderbuffertarget gg.Enum,
+	rb *gg.Renderbuffer,
+) {
+	var v uint32
+	if rb != nil {
+		v = b.name(rb.ID)
+	}
+	gl.FramebufferRenderbuffer(uint32(target), uint32(attachment), uint32(renderbuffertarget), v)
+}
+
+func (*backend) CheckFramebufferStatus(target gg.Enum) gg.Enum {
+	return gg.Enum(gl.CheckFramebufferStatus(uint32(target)))
+}
+
+func (b *backend) CreateRenderbuffer() *gg.Renderbuffer {
+	var rb uint32
+	gl.GenRenderbuffers(1, &rb)
+	return &gg.Renderbuffer{ID: b.objs.Add(rb)}
+}
+
+func (b *backend) BindRenderbuffer(target gg.Enum, rb *gg.Renderbuffer) {
+	var v uint32
+	if rb != nil {
+		v = b.name(rb.ID)
+	}
+	gl.BindRenderbuffer(uint32(target), v)
+}
+
+func (b *backend) DeleteRenderbuffer(rb *gg.Renderbuffer) {
+	v := b.name(rb.ID)
+	gl.DeleteRenderbuffers(1, &v)
+	b.objs.Delete(rb.ID)
+}
+
+func (*backend) RenderbufferStorage(target, internalFormat gg.Enum, width, height int) {
+	gl.RenderbufferStorage(uint32(target), uint32(internalFormat), int32(width), int32(height))
+}
+
+func (*backend) ReadPixels(x, y, width, height int, format, typ gg.Enum, dst []byte) {
+	gl.ReadPixels(
+		int32(x), int32(y), int32(width), int32(height),
+		uint32(format), uint32(typ),
+		gl.Ptr(dst),
+	)
+}
@@ -0,0 +1,301 @@
+package gg
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ID identifies an object in the Table of the backend that created it. IDs
+// mean nothing outside that table; the zero ID is never allocated.
+type ID uint32
+
+// Table maps IDs to a backend's native objects, such as OpenGL object names
+// or WebGL objects. Backends add each object they create to their own Table,
+// hand out its ID in a handle, and look the object up again when the handle
+// comes back. The zero Table is ready to use.
+type Table struct {
+	objs     map[ID]*tableEntry
+	named    map[ID]map[string]ID // owner to the objects named under it
+	attached map[ID]map[ID]bool   // owner to the objects attached to it
+	next     ID
+}
+
+type tableEntry struct {
+	obj   interface{}
+	owner ID
+	name  string
+
+	owners  int  // the number of objects this one is attached to
+	deleted bool // deleted while attached, and kept until detached
+}
+
+// Add adds obj to t and returns its new ID.
+func (t *Table) Add(obj interface{}) ID {
+	return t.add(&tableEntry{obj: obj})
+}
+
+func (t *Table) add(e *tableEntry) ID {
+	if t.objs == nil {
+		t.objs = make(map[ID]*tableEntry)
+		t.named = make(map[ID]map[string]ID)
+		t.attached = make(map[ID]map[ID]bool)
+	}
+	t.next++
+	t.objs[t.next] = e
+	return t.next
+}
+
+// Named adds obj to t as the object called name belonging to owner, such as a
+// uniform location in a program, and returns its ID. Adding the same name
+// under the same owner again replaces the object and returns the same ID, so
+// looking a name up repeatedly does not grow t.
+func (t *Table) Named(owner ID, name string, obj interface{}) ID {
+	if id, ok := t.named[owner][name]; ok {
+		t.objs[id].obj = obj
+		return id
+	}
+	id := t.add(&tableEntry{obj: obj, owner: owner, name: name})
+	if t.named[owner] == nil {
+		t.named[owner] = make(map[string]ID)
+	}
+	t.named[owner][name] = id
+	return id
+}
+
+// Get returns the object with the given ID, or nil if there is none.
+func (t *Table) Get(id ID) interface{} {
+	if e, ok := t.objs[id]; ok {
+		return e.obj
+	}
+	return nil
+}
+
+// Attach records that the object id is attached to owner, as a shader is to
+// a program. An attached object that is deleted stays in t, so that it can
+// still be detached, until it is detached from every owner.
+func (t *Table) Attach(owner, id ID) {
+	e, ok := t.objs[id]
+	if !ok || t.attached[owner][id] {
+		return
+	}
+	if t.attached[owner] == nil {
+		t.attached[owner] = make(map[ID]bool)
+	}
+	t.attached[owner][id] = true
+	e.owners++
+}
+
+// Detach records that the object id is no longer attached to owner, and
+// removes it if it was deleted while attached.
+func (t *Table) Detach(owner, id ID) {
+	if !t.attached[owner][id] {
+		return
+	}
+	delete(t.attached[owner], id)
+	e := t.objs[id]
+	e.owners--
+	if e.owners == 0 && e.deleted {
+		t.remove(id)
+	}
+}
+
+// Delete removes the object with the given ID from t, along with any objects
+// named under it, and detaches the objects attached to it. An object that is
+// still attached is removed once it is detached.
+func (t *Table) Delete(id ID) {
+	e, ok := t.objs[id]
+	if !ok || e.deleted {
+		return
+	}
+	if e.owners > 0 {
+		e.deleted = true
+		return
+	}
+	t.remove(id)
+}
+
+func (t *Table) remove(id ID) {
+	e := t.objs[id]
+	delete(t.objs, id)
+	if e.owner != 0 {
+		delete(t.named[e.owner], e.name)
+	}
+	for _, child := range t.named[id] {
+		delete(t.objs, child)
+	}
+	delete(t.named, id)
+	for child := range t.attached[id] {
+		t.Detach(id, child)
+	}
+	delete(t.attached, id)
+}
+
+// Len returns the number of objects in t.
+func (t *Table) Len() int {
+	return len(t.objs)
+}
+
+// object is the state a Context keeps in each handle it returns.
+type object struct {
+	ctx     *Context
+	deleted bool
+}
+
+// Deleted reports whether the object has been deleted.
+func (o *object) Deleted() bool { return o.deleted }
+
+// Buffer, Program, Shader, Texture, Framebuffer, Renderbuffer and
+// VertexArray are handles to backend objects. Their ID is meaningful only to
+// the backend that created them, and a handle may only be passed to the
+// Context that returned it.
+type Buffer struct {
+	ID ID
+	object
+}
+
+type Program struct {
+	ID ID
+	object
+}
+
+type Shader struct {
+	ID ID
+	object
+}
+
+type Texture struct {
+	ID ID
+	object
+}
+
+type Framebuffer struct {
+	ID ID
+	object
+}
+
+type Renderbuffer struct {
+	ID ID
+	object
+}
+
+type VertexArray struct {
+	ID ID
+	object
+}
+
+// Uniform is the location of a uniform in a linked program. Getting the
+// location of the same name in the same program again returns a Uniform with
+// the same ID.
+type Uniform struct {
+	ID ID
+	object
+	program *Program
+}
+
+// Attribute is the location of a vertex attribute. Unlike the other handles
+// it holds no backend object: attribute locations are small integers shared
+// by every program.
+type Attribute struct {
+	Location int
+	object
+}
+
+func (b *Buffer) obj() *object {
+	if b == nil {
+		return nil
+	}
+	return &b.object
+}
+
+func (p *Program) obj() *object {
+	if p == nil {
+		return nil
+	}
+	return &p.object
+}
+
+func (s *Shader) obj() *object {
+	if s == nil {
+		return nil
+	}
+	return &s.object
+}
+
+func (t *Texture) obj() *object {
+	if t == nil {
+		return nil
+	}
+	return &t.object
+}
+
+func (fb *Framebuffer) obj() *object {
+	if fb == nil {
+		return nil
+	}
+	return &fb.object
+}
+
+func (rb *Renderbuffer) obj() *object {
+	if rb == nil {
+		return nil
+	}
+	return &rb.object
+}
+
+func (va *VertexArray) obj() *object {
+	if va == nil {
+		return nil
+	}
+	return &va.object
+}
+
+func (u *Uniform) obj() *object {
+	if u == nil {
+		return nil
+	}
+	return &u.object
+}
+
+func (a *Attribute) obj() *object {
+	if a == nil {
+		return nil
+	}
+	return &a.object
+}
+
+// ErrDeleted is returned when an object is used after it has been deleted.
+var ErrDeleted = errors.New("gg: use of deleted object")
+
+// ErrWrongContext is returned when a handle is passed to a Context other than
+// the one that created it.
+var ErrWrongContext = errors.New("gg: object belongs to a different context")
+
+// own records c as the creator of o.
+func (c *Context) own(o *object) {
+	if o != nil {
+		o.ctx = c
+	}
+}
+
+// check returns an error if the handle of the given kind with state o is nil,
+// was created by another Context or has been deleted.
+func (c *Context) check(kind string, o *object) error {
+	if o == nil {
+		return fmt.Errorf("gg: nil %s", kind)
+	}
+	if o.ctx != c {
+		return fmt.Errorf("%w: %s", ErrWrongContext, kind)
+	}
+	if o.deleted {
+		return fmt.Errorf("%w: %s", ErrDeleted, kind)
+	}
+	return nil
+}
+
+// checkOptional is like check but allows a nil handle, which unbinds.
+func (c *Context) checkOptional(kind string, o *object) error {
+	if o == nil {
+		return nil
+	}
+	return c.check(kind, o)
+}
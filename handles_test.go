@@ -0,0 +1,176 @@
+package gg_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dmac/gg"
+)
+
+func TestTable(t *testing.T) {
+	type step struct {
+		op        string // add, named, attach, detach, delete
+		name      string // the object the step adds or acts on
+		owner     string
+		wantLen   int
+		wantGone  []string
+		wantAlive []string
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "delete removes named objects",
+			steps: []step{
+				{op: "add", name: "p", wantLen: 1},
+				{op: "named", name: "u", owner: "p", wantLen: 2},
+				{op: "named", name: "u", owner: "p", wantLen: 2},
+				{op: "named", name: "v", owner: "p", wantLen: 3},
+				{op: "delete", name: "p", wantLen: 0, wantGone: []string{"p", "u", "v"}},
+			},
+		},
+		{
+			name: "names are per owner",
+			steps: []step{
+				{op: "add", name: "p", wantLen: 1},
+				{op: "add", name: "q", wantLen: 2},
+				{op: "named", name: "u", owner: "p", wantLen: 3},
+				{op: "named", name: "u2", owner: "q", wantLen: 4},
+				{op: "delete", name: "p", wantLen: 2, wantGone: []string{"p", "u"}, wantAlive: []string{"q", "u2"}},
+			},
+		},
+		{
+			name: "deleted shader stays until detached",
+			steps: []step{
+				{op: "add", name: "p", wantLen: 1},
+				{op: "add", name: "s", wantLen: 2},
+				{op: "attach", name: "s", owner: "p", wantLen: 2},
+				{op: "attach", name: "s", owner: "p", wantLen: 2},
+				{op: "delete", name: "s", wantLen: 2, wantAlive: []string{"s"}},
+				{op: "detach", name: "s", owner: "p", wantLen: 1, wantGone: []string{"s"}},
+			},
+		},
+		{
+			name: "deleting the program frees a deleted shader",
+			steps: []step{
+				{op: "add", name: "p", wantLen: 1},
+				{op: "add", name: "q", wantLen: 2},
+				{op: "add", name: "s", wantLen: 3},
+				{op: "attach", name: "s", owner: "p", wantLen: 3},
+				{op: "attach", name: "s", owner: "q", wantLen: 3},
+				{op: "delete", name: "s", wantLen: 3, wantAlive: []string{"s"}},
+				{op: "delete", name: "p", wantLen: 2, wantAlive: []string{"s"}},
+				{op: "delete", name: "q", wantLen: 0, wantGone: []string{"s"}},
+			},
+		},
+		{
+			name: "detached shader is kept until deleted",
+			steps: []step{
+				{op: "add", name: "p", wantLen: 1},
+				{op: "add", name: "s", wantLen: 2},
+				{op: "attach", name: "s", owner: "p", wantLen: 2},
+				{op: "detach", name: "s", owner: "p", wantLen: 2, wantAlive: []string{"s"}},
+				{op: "delete", name: "p", wantLen: 1, wantAlive: []string{"s"}},
+				{op: "delete", name: "s", wantLen: 0, wantGone: []string{"s"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tab gg.Table
+			ids := make(map[string]gg.ID)
+			for i, s := range tt.steps {
+				switch s.op {
+				case "add":
+					ids[s.name] = tab.Add(s.name)
+				case "named":
+					id := tab.Named(ids[s.owner], s.name, s.name)
+					if prev, ok := ids[s.name]; ok && prev != id {
+						t.Fatalf("step %d: Named(%s) = %d, want the earlier ID %d", i, s.name, id, prev)
+					}
+					ids[s.name] = id
+				case "attach":
+					tab.Attach(ids[s.owner], ids[s.name])
+				case "detach":
+					tab.Detach(ids[s.owner], ids[s.name])
+				case "delete":
+					tab.Delete(ids[s.name])
+				}
+				if got := tab.Len(); got != s.wantLen {
+					t.Errorf("step %d (%s %s): Len() = %d, want %d", i, s.op, s.name, got, s.wantLen)
+				}
+				for _, name := range s.wantGone {
+					if got := tab.Get(ids[name]); got != nil {
+						t.Errorf("step %d: Get(%s) = %v, want nil", i, name, got)
+					}
+				}
+				for _, name := range s.wantAlive {
+					if got := tab.Get(ids[name]); got != name {
+						t.Errorf("step %d: Get(%s) = %v, want %s", i, name, got, name)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestTableIDsAreNotReused(t *testing.T) {
+	var tab gg.Table
+	a := tab.Add("a")
+	tab.Delete(a)
+	if b := tab.Add("b"); b == a {
+		t.Errorf("Add after Delete reused ID %d", a)
+	}
+	if got := tab.Get(0); got != nil {
+		t.Errorf("Get(0) = %v, want nil", got)
+	}
+}
+
+// uniformBackend looks up uniforms the way the WebGL backends do: the browser
+// returns null for a name the program does not have.
+type uniformBackend struct {
+	gg.Backend
+	objs     gg.Table
+	uniforms map[string]interface{}
+}
+
+func (b *uniformBackend) CreateProgram() *gg.Program {
+	return &gg.Program{ID: b.objs.Add("program")}
+}
+
+func (b *uniformBackend) GetUniformLocation(p *gg.Program, name string) (*gg.Uniform, error) {
+	u := b.uniforms[name]
+	if u == nil {
+		return nil, fmt.Errorf("gg: no uniform named %s", name)
+	}
+	return &gg.Uniform{ID: b.objs.Named(p.ID, name, u)}, nil
+}
+
+func TestMissingUniform(t *testing.T) {
+	b := &uniformBackend{uniforms: map[string]interface{}{"color": 0}}
+	ctx := gg.NewContext(b)
+	p := ctx.CreateProgram()
+	tests := []struct {
+		name    string
+		wantErr bool
+		wantLen int
+	}{
+		{"missing", true, 1},
+		{"color", false, 2},
+		{"color", false, 2},
+		{"missing", true, 2},
+	}
+	for _, tt := range tests {
+		u, err := ctx.GetUniformLocation(p, tt.name)
+		if gotErr := err != nil; gotErr != tt.wantErr || gotErr != (u == nil) {
+			t.Errorf("GetUniformLocation(%q) = %v, %v, want error %v", tt.name, u, err, tt.wantErr)
+		}
+		if got := b.objs.Len(); got != tt.wantLen {
+			t.Errorf("after GetUniformLocation(%q): Len() = %d, want %d", tt.name, got, tt.wantLen)
+		}
+	}
+	if err := ctx.Uniform1f(nil, 1); err == nil {
+		t.Error("Uniform1f with the nil uniform of a failed lookup succeeded")
+	}
+}
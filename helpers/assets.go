@@ -0,0 +1,216 @@
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif" // register decoders for LoadAssets
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/dmac/gg"
+)
+
+// An AssetSource resolves asset paths, which are slash-separated and relative,
+// to their contents.
+type AssetSource interface {
+	ReadAsset(name string) ([]byte, error)
+}
+
+// FSAssets returns an AssetSource that reads from fsys, such as an embed.FS or
+// the result of os.DirFS.
+func FSAssets(fsys fs.FS) AssetSource {
+	return fsSource{fsys}
+}
+
+type fsSource struct{ fsys fs.FS }
+
+func (s fsSource) ReadAsset(name string) ([]byte, error) {
+	return fs.ReadFile(s.fsys, name)
+}
+
+// HTTPAssets returns an AssetSource that fetches paths relative to the base
+// URL. In browsers requests go through fetch or XMLHttpRequest, so assets are
+// subject to the page's CORS rules.
+func HTTPAssets(base string) AssetSource {
+	return httpSource{base}
+}
+
+type httpSource struct{ base string }
+
+func (s httpSource) ReadAsset(name string) ([]byte, error) {
+	base, err := url.Parse(s.base)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := url.Parse(name)
+	if err != nil {
+		return nil, err
+	}
+	u := base.ResolveReference(ref).String()
+	resp, err := http.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", u, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// maxAssetLoads is the number of assets LoadAssets reads at once.
+const maxAssetLoads = 8
+
+// Assets is a set of assets being loaded concurrently by LoadAssets.
+// Its methods may be called from any goroutine, except that Texture must be
+// called on the goroutine that owns the gg context.
+type Assets struct {
+	names  []string
+	assets map[string]*asset
+	done   chan struct{}
+
+	mu     sync.Mutex
+	loaded int
+}
+
+type asset struct {
+	done chan struct{}
+	data []byte
+	img  image.Image
+	err  error
+}
+
+// LoadAssets starts loading the named assets from src and returns without
+// waiting for them. If src is nil, DefaultAssets is used. Files ending in
+// .png, .jpg, .jpeg or .gif are also decoded as images while loading.
+func LoadAssets(src AssetSource, names ...string) *Assets {
+	if src == nil {
+		src = DefaultAssets()
+	}
+	a := &Assets{
+		assets: make(map[string]*asset),
+		done:   make(chan struct{}),
+	}
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxAssetLoads)
+	for _, name := range names {
+		if _, ok := a.assets[name]; ok {
+			continue
+		}
+		as := &asset{done: make(chan struct{})}
+		a.names = append(a.names, name)
+		a.assets[name] = as
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			as.load(src, name)
+			<-sem
+			a.mu.Lock()
+			a.loaded++
+			a.mu.Unlock()
+			close(as.done)
+		}(name)
+	}
+	go func() {
+		wg.Wait()
+		close(a.done)
+	}()
+	return a
+}
+
+func (as *asset) load(src AssetSource, name string) {
+	as.data, as.err = src.ReadAsset(name)
+	if as.err != nil {
+		as.err = fmt.Errorf("gg: loading %s: %v", name, as.err)
+		return
+	}
+	switch strings.ToLower(path.Ext(name)) {
+	case ".png", ".jpg", ".jpeg", ".gif":
+		as.img, _, as.err = image.Decode(bytes.NewReader(as.data))
+		if as.err != nil {
+			as.err = fmt.Errorf("gg: decoding %s: %v", name, as.err)
+		}
+	}
+}
+
+// Progress reports how many of the assets have finished loading, successfully
+// or not, out of the total.
+func (a *Assets) Progress() (loaded, total int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.loaded, len(a.names)
+}
+
+// Done returns a channel that is closed once every asset has finished loading.
+func (a *Assets) Done() <-chan struct{} {
+	return a.done
+}
+
+// Wait waits for every asset to finish loading and returns the error of the
+// first asset, in the order they were named, that failed.
+func (a *Assets) Wait() error {
+	<-a.done
+	for _, name := range a.names {
+		if err := a.assets[name].err; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// get waits for the named asset to finish loading.
+func (a *Assets) get(name string) (*asset, error) {
+	as, ok := a.assets[name]
+	if !ok {
+		return nil, fmt.Errorf("gg: asset %s was not loaded", name)
+	}
+	<-as.done
+	return as, as.err
+}
+
+// Bytes waits for the named asset and returns its contents.
+func (a *Assets) Bytes(name string) ([]byte, error) {
+	as, err := a.get(name)
+	if err != nil {
+		return nil, err
+	}
+	return as.data, nil
+}
+
+// Text waits for the named asset and returns its contents as a string, such
+// as GLSL source.
+func (a *Assets) Text(name string) (string, error) {
+	b, err := a.Bytes(name)
+	return string(b), err
+}
+
+// Image waits for the named asset and returns it decoded.
+func (a *Assets) Image(name string) (image.Image, error) {
+	as, err := a.get(name)
+	if err != nil {
+		return nil, err
+	}
+	if as.img == nil {
+		return nil, fmt.Errorf("gg: asset %s is not an image", name)
+	}
+	return as.img, nil
+}
+
+// Texture waits for the named image asset and creates a texture in ctx from
+// it with NewTexture.
+func (a *Assets) Texture(ctx *gg.Context, name string, opts *TextureOptions) (*gg.Texture, error) {
+	img, err := a.Image(name)
+	if err != nil {
+		return nil, err
+	}
+	return NewTexture(ctx, img, opts)
+}
@@ -0,0 +1,12 @@
+//go:build js
+// +build js
+
+package helpers
+
+import "syscall/js"
+
+// DefaultAssets returns the AssetSource LoadAssets uses when given nil: files
+// relative to the working directory, or relative to the page in browsers.
+func DefaultAssets() AssetSource {
+	return HTTPAssets(js.Global().Get("document").Get("baseURI").String())
+}
@@ -0,0 +1,12 @@
+//go:build !js
+// +build !js
+
+package helpers
+
+import "os"
+
+// DefaultAssets returns the AssetSource LoadAssets uses when given nil: files
+// relative to the working directory, or relative to the page in browsers.
+func DefaultAssets() AssetSource {
+	return FSAssets(os.DirFS("."))
+}
@@ -0,0 +1,294 @@
+package helpers
+
+import (
+	"fmt"
+
+	"github.com/dmac/gg"
+)
+
+// Program is a linked program along with the locations of its active
+// uniforms and attributes, which are looked up once when the Program is
+// created rather than by name on every draw.
+//
+// The Set methods set a uniform by name after checking that the value
+// matches the uniform's type in the shader. Like the Uniform functions of
+// gg, they set the uniform of the program in use, so call Use first.
+type Program struct {
+	ctx      *gg.Context
+	program  *gg.Program
+	info     *ProgramInfo
+	uniforms map[string]*UniformInfo
+	attribs  map[string]*AttribInfo
+}
+
+// LinkProgram creates a program in ctx from shaders, links it and resolves
+// the locations of its active uniforms and attributes.
+func LinkProgram(ctx *gg.Context, shaders ...*gg.Shader) (*Program, error) {
+	p := ctx.CreateProgram()
+	for _, s := range shaders {
+		if err := ctx.AttachShader(p, s); err != nil {
+			ctx.DeleteProgram(p)
+			return nil, err
+		}
+	}
+	if err := ctx.LinkProgram(p); err != nil {
+		ctx.DeleteProgram(p)
+		return nil, err
+	}
+	return NewProgram(ctx, p)
+}
+
+// NewProgram resolves the locations of the active uniforms and attributes of
+// p, which must already be linked. If p is linked again, call NewProgram
+// again for the new locations.
+func NewProgram(ctx *gg.Context, p *gg.Program) (*Program, error) {
+	info, err := ReflectProgram(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	prog := &Program{
+		ctx:      ctx,
+		program:  p,
+		info:     info,
+		uniforms: make(map[string]*UniformInfo, len(info.Uniforms)),
+		attribs:  make(map[string]*AttribInfo, len(info.Attributes)),
+	}
+	for i := range info.Uniforms {
+		prog.uniforms[info.Uniforms[i].Name] = &info.Uniforms[i]
+	}
+	for i := range info.Attributes {
+		prog.attribs[info.Attributes[i].Name] = &info.Attributes[i]
+	}
+	return prog, nil
+}
+
+// Program returns the underlying program.
+func (p *Program) Program() *gg.Program { return p.program }
+
+// Info returns the program's active uniforms and attributes.
+func (p *Program) Info() *ProgramInfo { return p.info }
+
+// Use installs the program as part of the current rendering state.
+func (p *Program) Use() error { return p.ctx.UseProgram(p.program) }
+
+// Delete deletes the underlying program.
+func (p *Program) Delete() error { return p.ctx.DeleteProgram(p.program) }
+
+// Uniform returns the location of the active uniform called name, or nil if
+// the program has none. Arrays are named without "[0]".
+func (p *Program) Uniform(name string) *gg.Uniform {
+	if u, ok := p.uniforms[name]; ok {
+		return u.Uniform
+	}
+	return nil
+}
+
+// Attribute returns the location of the active attribute called name, or nil
+// if the program has none.
+func (p *Program) Attribute(name string) *gg.Attribute {
+	if a, ok := p.attribs[name]; ok {
+		return a.Attribute
+	}
+	return nil
+}
+
+// uniform returns the active uniform called name if its type is one of
+// types, which want describes.
+func (p *Program) uniform(name, want string, types ...gg.Enum) (*gg.Uniform, error) {
+	u, ok := p.uniforms[name]
+	if !ok {
+		// The driver removes uniforms that do not contribute to the output,
+		// so this is also the error for a uniform that the shaders declare
+		// but do not use.
+		return nil, fmt.Errorf("gg: program has no active uniform %s", name)
+	}
+	for _, t := range types {
+		if u.Type == t {
+			return u.Uniform, nil
+		}
+	}
+	return nil, fmt.Errorf("gg: uniform %s is %s, not %s", name, TypeName(u.Type), want)
+}
+
+// SetFloat sets a float uniform.
+func (p *Program) SetFloat(name string, v float32) error {
+	u, err := p.uniform(name, "float", gg.FLOAT)
+	if err != nil {
+		return err
+	}
+	return p.ctx.Uniform1f(u, v)
+}
+
+// SetVec2 sets a vec2 uniform.
+func (p *Program) SetVec2(name string, v [2]float32) error {
+	u, err := p.uniform(name, "vec2", gg.FLOAT_VEC2)
+	if err != nil {
+		return err
+	}
+	return p.ctx.Uniform2f(u, v[0], v[1])
+}
+
+// SetVec3 sets a vec3 uniform.
+func (p *Program) SetVec3(name string, v [3]float32) error {
+	u, err := p.uniform(name, "vec3", gg.FLOAT_VEC3)
+	if err != nil {
+		return err
+	}
+	return p.ctx.Uniform3f(u, v[0], v[1], v[2])
+}
+
+// SetVec4 sets a vec4 uniform.
+func (p *Program) SetVec4(name string, v [4]float32) error {
+	u, err := p.uniform(name, "vec4", gg.FLOAT_VEC4)
+	if err != nil {
+		return err
+	}
+	return p.ctx.Uniform4f(u, v[0], v[1], v[2], v[3])
+}
+
+// SetMat2 sets a mat2 uniform from a matrix in column-major order.
+func (p *Program) SetMat2(name string, m [4]float32) error {
+	u, err := p.uniform(name, "mat2", gg.FLOAT_MAT2)
+	if err != nil {
+		return err
+	}
+	return p.ctx.UniformMatrix2fv(u, m[:])
+}
+
+// SetMat3 sets a mat3 uniform from a matrix in column-major order.
+func (p *Program) SetMat3(name string, m [9]float32) error {
+	u, err := p.uniform(name, "mat3", gg.FLOAT_MAT3)
+	if err != nil {
+		return err
+	}
+	return p.ctx.UniformMatrix3fv(u, m[:])
+}
+
+// SetMat4 sets a mat4 uniform from a matrix in column-major order, such as an
+// mgl32.Mat4.
+func (p *Program) SetMat4(name string, m [16]float32) error {
+	u, err := p.uniform(name, "mat4", gg.FLOAT_MAT4)
+	if err != nil {
+		return err
+	}
+	return p.ctx.UniformMatrix4fv(u, m[:])
+}
+
+// SetInt sets an int or bool uniform, or a sampler uniform to a texture unit
+// number.
+func (p *Program) SetInt(name string, v int) error {
+	u, err := p.uniform(name, "int, bool or sampler", intTypes...)
+	if err != nil {
+		return err
+	}
+	return p.ctx.Uniform1i(u, v)
+}
+
+// intTypes are the uniform types set with a single int.
+var intTypes = []gg.Enum{
+	gg.INT,
+	gg.BOOL,
+	gg.SAMPLER_2D,
+	gg.SAMPLER_CUBE,
+	gg.SAMPLER_3D,
+	gg.SAMPLER_2D_ARRAY,
+	gg.SAMPLER_2D_SHADOW,
+}
+
+// Components of each element of the float and int uniform types that
+// SetFloats and SetInts accept.
+var (
+	floatComponents = map[gg.Enum]int{
+		gg.FLOAT:      1,
+		gg.FLOAT_VEC2: 2,
+		gg.FLOAT_VEC3: 3,
+		gg.FLOAT_VEC4: 4,
+		gg.FLOAT_MAT2: 4,
+		gg.FLOAT_MAT3: 9,
+		gg.FLOAT_MAT4: 16,
+	}
+	intComponents = map[gg.Enum]int{
+		gg.INT:       1,
+		gg.INT_VEC2:  2,
+		gg.INT_VEC3:  3,
+		gg.INT_VEC4:  4,
+		gg.BOOL:      1,
+		gg.BOOL_VEC2: 2,
+		gg.BOOL_VEC3: 3,
+		gg.BOOL_VEC4: 4,
+	}
+)
+
+func init() {
+	for _, t := range intTypes {
+		intComponents[t] = 1
+	}
+}
+
+// SetFloats sets a float, vector or square matrix uniform, or consecutive
+// elements of an array of them starting at the first. len(v) must be a
+// multiple of the number of components of the uniform's type, and cover no
+// more elements than the array has.
+func (p *Program) SetFloats(name string, v []float32) error {
+	u, n, err := p.uniformArray(name, "float, vec or mat", floatComponents, len(v))
+	if err != nil {
+		return err
+	}
+	switch p.uniforms[name].Type {
+	case gg.FLOAT_MAT2:
+		return p.ctx.UniformMatrix2fv(u, v)
+	case gg.FLOAT_MAT3:
+		return p.ctx.UniformMatrix3fv(u, v)
+	case gg.FLOAT_MAT4:
+		return p.ctx.UniformMatrix4fv(u, v)
+	}
+	switch n {
+	case 1:
+		return p.ctx.Uniform1fv(u, v)
+	case 2:
+		return p.ctx.Uniform2fv(u, v)
+	case 3:
+		return p.ctx.Uniform3fv(u, v)
+	}
+	return p.ctx.Uniform4fv(u, v)
+}
+
+// SetInts sets an int, bool or sampler uniform, a vector of ints or bools, or
+// consecutive elements of an array of them starting at the first, in the same
+// way as SetFloats.
+func (p *Program) SetInts(name string, v []int32) error {
+	u, n, err := p.uniformArray(name, "int, ivec, bool, bvec or sampler", intComponents, len(v))
+	if err != nil {
+		return err
+	}
+	switch n {
+	case 1:
+		return p.ctx.Uniform1iv(u, v)
+	case 2:
+		return p.ctx.Uniform2iv(u, v)
+	case 3:
+		return p.ctx.Uniform3iv(u, v)
+	}
+	return p.ctx.Uniform4iv(u, v)
+}
+
+// uniformArray returns the active uniform called name if its type is in
+// components, which want describes, and count values fill whole elements of
+// it, along with the number of components of its type.
+func (p *Program) uniformArray(name, want string, components map[gg.Enum]int, count int) (*gg.Uniform, int, error) {
+	u, ok := p.uniforms[name]
+	if !ok {
+		return nil, 0, fmt.Errorf("gg: program has no active uniform %s", name)
+	}
+	n, ok := components[u.Type]
+	if !ok {
+		return nil, 0, fmt.Errorf("gg: uniform %s is %s, not %s", name, TypeName(u.Type), want)
+	}
+	if count == 0 || count%n != 0 {
+		return nil, 0, fmt.Errorf("gg: %d values are not whole elements of %s uniform %s", count, TypeName(u.Type), name)
+	}
+	if count > n*u.Size {
+		return nil, 0, fmt.Errorf("gg: %d values overflow %s[%d] uniform %s", count, TypeName(u.Type), u.Size, name)
+	}
+	return u.Uniform, n, nil
+}
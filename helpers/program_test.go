@@ -0,0 +1,221 @@
+package helpers_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dmac/gg"
+	"github.com/dmac/gg/helpers"
+	"github.com/dmac/gg/soft"
+)
+
+const (
+	vertexSrc = `
+attribute vec2 position;
+uniform mat2 rotation;
+uniform mat3 normalMatrix;
+uniform mat4 mvp;
+void main() { gl_Position = mvp * vec4(rotation * position, 0.0, 1.0); }
+`
+	fragmentSrc = `
+uniform vec4 color;
+uniform vec3 lights[2];
+uniform float weights[3];
+uniform sampler2D tex;
+void main() { gl_FragColor = color; }
+`
+)
+
+func init() {
+	gg_soft.RegisterVertexShader(vertexSrc, func(v *gg_soft.Vertex) {
+		p := v.Attrib("position")
+		v.Position = [4]float32{p[0], p[1], 0, 1}
+	})
+	gg_soft.RegisterFragmentShader(fragmentSrc, func(f *gg_soft.Fragment) {
+		f.Color = f.Uniforms.Vec4("color")
+	})
+}
+
+// uniformRecorder records the last call that sets a uniform from a slice, or
+// a single int, before passing it on.
+type uniformRecorder struct {
+	gg.Backend
+	call   string
+	values []float32
+}
+
+func (r *uniformRecorder) Uniform1i(u *gg.Uniform, v0 int) {
+	r.call, r.values = "Uniform1i", []float32{float32(v0)}
+	r.Backend.Uniform1i(u, v0)
+}
+
+func (r *uniformRecorder) Uniform1fv(u *gg.Uniform, values []float32) {
+	r.call, r.values = "Uniform1fv", values
+	r.Backend.Uniform1fv(u, values)
+}
+
+func (r *uniformRecorder) Uniform3fv(u *gg.Uniform, values []float32) {
+	r.call, r.values = "Uniform3fv", values
+	r.Backend.Uniform3fv(u, values)
+}
+
+func (r *uniformRecorder) Uniform4fv(u *gg.Uniform, values []float32) {
+	r.call, r.values = "Uniform4fv", values
+	r.Backend.Uniform4fv(u, values)
+}
+
+func (r *uniformRecorder) UniformMatrix2fv(u *gg.Uniform, values []float32) {
+	r.call, r.values = "UniformMatrix2fv", values
+	r.Backend.UniformMatrix2fv(u, values)
+}
+
+func (r *uniformRecorder) UniformMatrix3fv(u *gg.Uniform, values []float32) {
+	r.call, r.values = "UniformMatrix3fv", values
+	r.Backend.UniformMatrix3fv(u, values)
+}
+
+func (r *uniformRecorder) UniformMatrix4fv(u *gg.Uniform, values []float32) {
+	r.call, r.values = "UniformMatrix4fv", values
+	r.Backend.UniformMatrix4fv(u, values)
+}
+
+func floats(n int) []float32 {
+	v := make([]float32, n)
+	for i := range v {
+		v[i] = float32(i + 1)
+	}
+	return v
+}
+
+func TestProgramSet(t *testing.T) {
+	tests := []struct {
+		name string
+		set  func(p *helpers.Program) error
+
+		// The error the setter returns, or if it succeeds, the call that
+		// must reach the backend.
+		err    string
+		call   string
+		values []float32
+	}{
+		{
+			name: "wrong type",
+			set:  func(p *helpers.Program) error { return p.SetVec3("color", [3]float32{1, 0, 0}) },
+			err:  "gg: uniform color is vec4, not vec3",
+		},
+		{
+			name: "no such uniform",
+			set:  func(p *helpers.Program) error { return p.SetFloat("missing", 1) },
+			err:  "gg: program has no active uniform missing",
+		},
+		{
+			name: "partial element",
+			set:  func(p *helpers.Program) error { return p.SetFloats("lights", floats(4)) },
+			err:  "gg: 4 values are not whole elements of vec3 uniform lights",
+		},
+		{
+			name: "no values",
+			set:  func(p *helpers.Program) error { return p.SetFloats("lights", nil) },
+			err:  "gg: 0 values are not whole elements of vec3 uniform lights",
+		},
+		{
+			name: "array overflow",
+			set:  func(p *helpers.Program) error { return p.SetFloats("lights", floats(9)) },
+			err:  "gg: 9 values overflow vec3[2] uniform lights",
+		},
+		{
+			name: "matrix overflow",
+			set:  func(p *helpers.Program) error { return p.SetFloats("rotation", floats(8)) },
+			err:  "gg: 8 values overflow mat2[1] uniform rotation",
+		},
+		{
+			name:   "sampler",
+			set:    func(p *helpers.Program) error { return p.SetInt("tex", 1) },
+			call:   "Uniform1i",
+			values: []float32{1},
+		},
+		{
+			name: "sampler as float",
+			set:  func(p *helpers.Program) error { return p.SetFloat("tex", 1) },
+			err:  "gg: uniform tex is sampler2D, not float",
+		},
+		{
+			name:   "float array prefix",
+			set:    func(p *helpers.Program) error { return p.SetFloats("weights", floats(2)) },
+			call:   "Uniform1fv",
+			values: floats(2),
+		},
+		{
+			name:   "vec3 array",
+			set:    func(p *helpers.Program) error { return p.SetFloats("lights", floats(6)) },
+			call:   "Uniform3fv",
+			values: floats(6),
+		},
+		{
+			name:   "vec4",
+			set:    func(p *helpers.Program) error { return p.SetFloats("color", floats(4)) },
+			call:   "Uniform4fv",
+			values: floats(4),
+		},
+		{
+			name:   "mat2",
+			set:    func(p *helpers.Program) error { return p.SetFloats("rotation", floats(4)) },
+			call:   "UniformMatrix2fv",
+			values: floats(4),
+		},
+		{
+			name:   "mat3",
+			set:    func(p *helpers.Program) error { return p.SetFloats("normalMatrix", floats(9)) },
+			call:   "UniformMatrix3fv",
+			values: floats(9),
+		},
+		{
+			name:   "mat4",
+			set:    func(p *helpers.Program) error { return p.SetFloats("mvp", floats(16)) },
+			call:   "UniformMatrix4fv",
+			values: floats(16),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := gg_soft.New(1, 1)
+			r := &uniformRecorder{Backend: s}
+			ctx := gg.NewContext(r)
+			vs, err := ctx.CreateShader([]byte(vertexSrc), gg.VERTEX_SHADER)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fs, err := ctx.CreateShader([]byte(fragmentSrc), gg.FRAGMENT_SHADER)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := helpers.LinkProgram(ctx, vs, fs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := p.Use(); err != nil {
+				t.Fatal(err)
+			}
+
+			err = tt.set(p)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Errorf("error = %v, want %s", err, tt.err)
+				}
+				if r.call != "" {
+					t.Errorf("%s reached the backend", r.call)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.call != tt.call || !reflect.DeepEqual(r.values, tt.values) {
+				t.Errorf("backend got %s(%v), want %s(%v)", r.call, r.values, tt.call, tt.values)
+			}
+			if err := s.Err(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dmac/gg"
+)
+
+// ProgramInfo describes the active uniforms and attributes of a linked
+// program, those the driver kept after optimizing away unused variables.
+type ProgramInfo struct {
+	Uniforms   []UniformInfo
+	Attributes []AttribInfo
+}
+
+// UniformInfo describes an active uniform. Each member of a uniform struct
+// is a separate uniform, named as in "light.color".
+type UniformInfo struct {
+	Name string // without the "[0]" that OpenGL appends to arrays
+	Type gg.Enum
+	Size int // array length, or 1
+	// Uniform is the location of the uniform, or of the first element of an
+	// array.
+	Uniform *gg.Uniform
+}
+
+// AttribInfo describes an active vertex attribute.
+type AttribInfo struct {
+	Name      string
+	Type      gg.Enum
+	Size      int
+	Attribute *gg.Attribute
+}
+
+// ReflectProgram lists the active uniforms and attributes of the linked
+// program p, in the order the driver reports them. Built-in variables such
+// as gl_VertexID are left out.
+func ReflectProgram(ctx *gg.Context, p *gg.Program) (*ProgramInfo, error) {
+	info := &ProgramInfo{}
+	n, err := ctx.GetProgramParameter(p, gg.ACTIVE_UNIFORMS)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < n; i++ {
+		a, err := ctx.GetActiveUniform(p, i)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(a.Name, "gl_") {
+			continue
+		}
+		u, err := ctx.GetUniformLocation(p, a.Name)
+		if err != nil {
+			return nil, err
+		}
+		info.Uniforms = append(info.Uniforms, UniformInfo{
+			Name:    strings.TrimSuffix(a.Name, "[0]"),
+			Type:    a.Type,
+			Size:    a.Size,
+			Uniform: u,
+		})
+	}
+
+	n, err = ctx.GetProgramParameter(p, gg.ACTIVE_ATTRIBUTES)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < n; i++ {
+		a, err := ctx.GetActiveAttrib(p, i)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(a.Name, "gl_") {
+			continue
+		}
+		attr, err := ctx.GetAttribLocation(p, a.Name)
+		if err != nil {
+			return nil, err
+		}
+		info.Attributes = append(info.Attributes, AttribInfo{
+			Name:      strings.TrimSuffix(a.Name, "[0]"),
+			Type:      a.Type,
+			Size:      a.Size,
+			Attribute: attr,
+		})
+	}
+	return info, nil
+}
+
+// Uniform returns the active uniform called name.
+func (info *ProgramInfo) Uniform(name string) (UniformInfo, bool) {
+	for _, u := range info.Uniforms {
+		if u.Name == name {
+			return u, true
+		}
+	}
+	return UniformInfo{}, false
+}
+
+// Attribute returns the active attribute called name.
+func (info *ProgramInfo) Attribute(name string) (AttribInfo, bool) {
+	for _, a := range info.Attributes {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return AttribInfo{}, false
+}
+
+var typeNames = map[gg.Enum]string{
+	gg.FLOAT:             "float",
+	gg.FLOAT_VEC2:        "vec2",
+	gg.FLOAT_VEC3:        "vec3",
+	gg.FLOAT_VEC4:        "vec4",
+	gg.INT:               "int",
+	gg.INT_VEC2:          "ivec2",
+	gg.INT_VEC3:          "ivec3",
+	gg.INT_VEC4:          "ivec4",
+	gg.UNSIGNED_INT:      "uint",
+	gg.UNSIGNED_INT_VEC2: "uvec2",
+	gg.UNSIGNED_INT_VEC3: "uvec3",
+	gg.UNSIGNED_INT_VEC4: "uvec4",
+	gg.BOOL:              "bool",
+	gg.BOOL_VEC2:         "bvec2",
+	gg.BOOL_VEC3:         "bvec3",
+	gg.BOOL_VEC4:         "bvec4",
+	gg.FLOAT_MAT2:        "mat2",
+	gg.FLOAT_MAT3:        "mat3",
+	gg.FLOAT_MAT4:        "mat4",
+	gg.FLOAT_MAT2x3:      "mat2x3",
+	gg.FLOAT_MAT2x4:      "mat2x4",
+	gg.FLOAT_MAT3x2:      "mat3x2",
+	gg.FLOAT_MAT3x4:      "mat3x4",
+	gg.FLOAT_MAT4x2:      "mat4x2",
+	gg.FLOAT_MAT4x3:      "mat4x3",
+	gg.SAMPLER_2D:        "sampler2D",
+	gg.SAMPLER_CUBE:      "samplerCube",
+	gg.SAMPLER_3D:        "sampler3D",
+	gg.SAMPLER_2D_ARRAY:  "sampler2DArray",
+	gg.SAMPLER_2D_SHADOW: "sampler2DShadow",
+}
+
+// TypeName returns the GLSL name of a variable type reported by
+// GetActiveUniform or GetActiveAttrib, such as "vec4" for FLOAT_VEC4.
+func TypeName(t gg.Enum) string {
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("type 0x%x", uint32(t))
+}
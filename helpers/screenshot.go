@@ -0,0 +1,39 @@
+package helpers
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/dmac/gg"
+)
+
+// ReadImage reads the width by height block of pixels whose lower left corner
+// is at (x, y) in the current framebuffer of ctx and returns it as an image
+// with its origin at the top left, flipping rows as needed.
+//
+// The pixel values are returned exactly as stored in the framebuffer, so
+// they are only correctly premultiplied if the rendering produced
+// premultiplied colors (e.g. an opaque framebuffer).
+func ReadImage(ctx *gg.Context, x, y, width, height int) (*image.RGBA, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("gg: ReadImage: invalid size %dx%d", width, height)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	if err := ctx.ReadPixels(x, y, width, height, gg.RGBA, gg.UNSIGNED_BYTE, img.Pix); err != nil {
+		return nil, err
+	}
+	flipRows(img.Pix, img.Stride, height)
+	return img, nil
+}
+
+// flipRows reverses the order of the height rows of stride bytes in pix.
+func flipRows(pix []byte, stride, height int) {
+	tmp := make([]byte, stride)
+	for top, bot := 0, height-1; top < bot; top, bot = top+1, bot-1 {
+		t := pix[top*stride : (top+1)*stride]
+		b := pix[bot*stride : (bot+1)*stride]
+		copy(tmp, t)
+		copy(t, b)
+		copy(b, tmp)
+	}
+}
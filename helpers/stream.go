@@ -0,0 +1,77 @@
+// Package helpers provides higher-level utilities built on top of the gg API.
+package helpers
+
+import (
+	"fmt"
+
+	"github.com/dmac/gg"
+)
+
+// StreamBuffer streams per-frame data, such as sprite batch vertices, through
+// a ring of buffers so that writing a frame's data does not have to wait for
+// the GPU to finish drawing from the previous frame's data.
+//
+// Each call to Upload moves to the next buffer in the ring. If the data fits
+// in that buffer's existing storage it is written with BufferSubData;
+// otherwise the buffer is orphaned and refilled with BufferData, which lets
+// the driver allocate fresh storage instead of synchronizing on the old one.
+type StreamBuffer struct {
+	ctx    *gg.Context
+	target gg.Enum
+	bufs   []*gg.Buffer
+	sizes  []int
+	cur    int
+}
+
+// NewStreamBuffer creates a StreamBuffer in ctx for target (typically
+// ARRAY_BUFFER or ELEMENT_ARRAY_BUFFER) backed by n buffers. A ring of 2 or 3
+// buffers is usually enough to avoid stalls. If n is 1, every upload orphans
+// the buffer.
+func NewStreamBuffer(ctx *gg.Context, target gg.Enum, n int) *StreamBuffer {
+	if n < 1 {
+		panic(fmt.Sprintf("gg: NewStreamBuffer with %d buffers", n))
+	}
+	s := &StreamBuffer{
+		ctx:    ctx,
+		target: target,
+		bufs:   make([]*gg.Buffer, n),
+		sizes:  make([]int, n),
+		cur:    n - 1,
+	}
+	for i := range s.bufs {
+		s.bufs[i] = ctx.CreateBuffer()
+	}
+	return s
+}
+
+// Upload writes data into the next buffer in the ring, leaving that buffer
+// bound to the stream's target, and returns it.
+func (s *StreamBuffer) Upload(data []byte) (*gg.Buffer, error) {
+	s.cur = (s.cur + 1) % len(s.bufs)
+	b := s.bufs[s.cur]
+	if err := s.ctx.BindBuffer(s.target, b); err != nil {
+		return nil, err
+	}
+	if len(s.bufs) == 1 || len(data) > s.sizes[s.cur] {
+		s.ctx.BufferData(s.target, data, gg.STREAM_DRAW)
+		s.sizes[s.cur] = len(data)
+		return b, nil
+	}
+	s.ctx.BufferSubData(s.target, 0, data)
+	return b, nil
+}
+
+// Buffer returns the buffer most recently written by Upload.
+func (s *StreamBuffer) Buffer() *gg.Buffer {
+	return s.bufs[s.cur]
+}
+
+// Delete deletes every buffer in the ring.
+func (s *StreamBuffer) Delete() error {
+	for _, b := range s.bufs {
+		if err := s.ctx.DeleteBuffer(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
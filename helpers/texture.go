@@ -0,0 +1,260 @@
+package helpers
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/dmac/gg"
+)
+
+// NPOT selects what NewTexture does with an image whose width or height is
+// not a power of two. WebGL 1 and OpenGL ES 2.0 can sample such textures
+// only with CLAMP_TO_EDGE wrapping and without mipmaps.
+type NPOT int
+
+const (
+	// NPOTKeep uploads the image at its own size.
+	NPOTKeep NPOT = iota
+	// NPOTPad places the image in the top left corner of a texture whose
+	// sides are the next powers of two, filling the rest with transparent
+	// black. The image covers texture coordinates from 0 to
+	// width/PowerOfTwo(width) and height/PowerOfTwo(height).
+	NPOTPad
+	// NPOTResize scales the image up to the next powers of two with
+	// bilinear filtering, so it still covers texture coordinates 0 to 1.
+	NPOTResize
+)
+
+// TextureOptions control how NewTexture uploads an image. The zero value
+// uploads straight-alpha colors at the image's own size with LINEAR
+// filtering and CLAMP_TO_EDGE wrapping.
+type TextureOptions struct {
+	// Premultiplied uploads colors with alpha premultiplied, for blending
+	// with ONE, ONE_MINUS_SRC_ALPHA. Otherwise colors are straight, which is
+	// what browsers upload image elements as.
+	Premultiplied bool
+
+	NPOT NPOT
+
+	// Filters and wrap modes default to LINEAR and CLAMP_TO_EDGE.
+	MinFilter, MagFilter gg.Enum
+	WrapS, WrapT         gg.Enum
+}
+
+// NewTexture creates a texture in ctx holding img and leaves it bound to
+// TEXTURE_2D on the active texture unit. The top row of img is the row at
+// t = 0.
+//
+// *image.RGBA, *image.NRGBA, *image.Gray and *image.Paletted images are
+// converted directly; other images are drawn into an *image.NRGBA first.
+// Gray images are uploaded as LUMINANCE and all others as RGBA.
+func NewTexture(ctx *gg.Context, img image.Image, opts *TextureOptions) (*gg.Texture, error) {
+	if opts == nil {
+		opts = &TextureOptions{}
+	}
+	p := imagePixels(img, opts.Premultiplied)
+	if opts.NPOT != NPOTKeep {
+		w, h := PowerOfTwo(p.width), PowerOfTwo(p.height)
+		if w != p.width || h != p.height {
+			if opts.NPOT == NPOTPad {
+				p = p.pad(w, h)
+			} else {
+				p = p.resize(w, h, opts.Premultiplied)
+			}
+		}
+	}
+
+	tex := ctx.CreateTexture()
+	if err := ctx.BindTexture(gg.TEXTURE_2D, tex); err != nil {
+		return nil, err
+	}
+	err := ctx.TexImage2D(
+		gg.TEXTURE_2D, 0, p.format,
+		p.width, p.height, 0,
+		p.format, gg.UNSIGNED_BYTE,
+		p.aligned(),
+	)
+	if err != nil {
+		ctx.DeleteTexture(tex)
+		return nil, err
+	}
+	ctx.TexParameteri(gg.TEXTURE_2D, gg.TEXTURE_MIN_FILTER, orDefault(opts.MinFilter, gg.LINEAR))
+	ctx.TexParameteri(gg.TEXTURE_2D, gg.TEXTURE_MAG_FILTER, orDefault(opts.MagFilter, gg.LINEAR))
+	ctx.TexParameteri(gg.TEXTURE_2D, gg.TEXTURE_WRAP_S, orDefault(opts.WrapS, gg.CLAMP_TO_EDGE))
+	ctx.TexParameteri(gg.TEXTURE_2D, gg.TEXTURE_WRAP_T, orDefault(opts.WrapT, gg.CLAMP_TO_EDGE))
+	return tex, nil
+}
+
+// PowerOfTwo returns the smallest power of two that is at least n.
+func PowerOfTwo(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
+
+func orDefault(e, def gg.Enum) gg.Enum {
+	if e == 0 {
+		return def
+	}
+	return e
+}
+
+// pixels is a tightly packed image of n bytes per pixel.
+type pixels struct {
+	format        gg.Enum // RGBA or LUMINANCE
+	n             int
+	width, height int
+	pix           []byte
+}
+
+// imagePixels converts img to RGBA, or LUMINANCE for gray images, with
+// premultiplied or straight alpha.
+func imagePixels(img image.Image, premultiplied bool) *pixels {
+	r := img.Bounds()
+	p := &pixels{format: gg.RGBA, n: 4, width: r.Dx(), height: r.Dy()}
+	switch m := img.(type) {
+	case *image.Gray:
+		p.format, p.n = gg.LUMINANCE, 1
+		p.pix = copyRows(m.Pix[m.PixOffset(r.Min.X, r.Min.Y):], m.Stride, p.width, p.height)
+	case *image.RGBA:
+		p.pix = copyRows(m.Pix[m.PixOffset(r.Min.X, r.Min.Y):], m.Stride, 4*p.width, p.height)
+		if !premultiplied {
+			unpremultiply(p.pix)
+		}
+	case *image.NRGBA:
+		p.pix = copyRows(m.Pix[m.PixOffset(r.Min.X, r.Min.Y):], m.Stride, 4*p.width, p.height)
+		if premultiplied {
+			premultiply(p.pix)
+		}
+	case *image.Paletted:
+		var lut [256][4]byte
+		for i, c := range m.Palette {
+			if premultiplied {
+				c := color.RGBAModel.Convert(c).(color.RGBA)
+				lut[i] = [4]byte{c.R, c.G, c.B, c.A}
+			} else {
+				c := color.NRGBAModel.Convert(c).(color.NRGBA)
+				lut[i] = [4]byte{c.R, c.G, c.B, c.A}
+			}
+		}
+		p.pix = make([]byte, 4*p.width*p.height)
+		for y := 0; y < p.height; y++ {
+			row := m.Pix[m.PixOffset(r.Min.X, r.Min.Y+y):]
+			for x := 0; x < p.width; x++ {
+				copy(p.pix[4*(y*p.width+x):], lut[row[x]][:])
+			}
+		}
+	default:
+		n := image.NewNRGBA(image.Rect(0, 0, p.width, p.height))
+		draw.Draw(n, n.Bounds(), img, r.Min, draw.Src)
+		p.pix = n.Pix
+		if premultiplied {
+			premultiply(p.pix)
+		}
+	}
+	return p
+}
+
+// copyRows copies height rows of rowLen bytes, stride bytes apart, from src
+// into a new tightly packed slice.
+func copyRows(src []byte, stride, rowLen, height int) []byte {
+	dst := make([]byte, rowLen*height)
+	for y := 0; y < height; y++ {
+		copy(dst[y*rowLen:(y+1)*rowLen], src[y*stride:])
+	}
+	return dst
+}
+
+func premultiply(pix []byte) {
+	for i := 0; i < len(pix); i += 4 {
+		a := uint32(pix[i+3])
+		pix[i+0] = byte((uint32(pix[i+0])*a + 127) / 255)
+		pix[i+1] = byte((uint32(pix[i+1])*a + 127) / 255)
+		pix[i+2] = byte((uint32(pix[i+2])*a + 127) / 255)
+	}
+}
+
+func unpremultiply(pix []byte) {
+	for i := 0; i < len(pix); i += 4 {
+		a := uint32(pix[i+3])
+		if a == 0 || a == 255 {
+			continue
+		}
+		pix[i+0] = byte((uint32(pix[i+0])*255 + a/2) / a)
+		pix[i+1] = byte((uint32(pix[i+1])*255 + a/2) / a)
+		pix[i+2] = byte((uint32(pix[i+2])*255 + a/2) / a)
+	}
+}
+
+// pad returns p in the top left corner of a width by height image.
+func (p *pixels) pad(width, height int) *pixels {
+	q := &pixels{format: p.format, n: p.n, width: width, height: height}
+	q.pix = make([]byte, p.n*width*height)
+	for y := 0; y < p.height; y++ {
+		copy(q.pix[y*p.n*width:], p.pix[y*p.n*p.width:(y+1)*p.n*p.width])
+	}
+	return q
+}
+
+// resize scales p to width by height with bilinear filtering. Straight-alpha
+// colors are premultiplied while filtering so that transparent pixels do not
+// bleed their color into their neighbors.
+func (p *pixels) resize(width, height int, premultiplied bool) *pixels {
+	straight := p.n == 4 && !premultiplied
+	if straight {
+		premultiply(p.pix)
+	}
+	q := &pixels{format: p.format, n: p.n, width: width, height: height}
+	q.pix = make([]byte, p.n*width*height)
+	if p.width > 0 && p.height > 0 {
+		for y := 0; y < height; y++ {
+			y0, y1, fy := sampleAxis(y, height, p.height)
+			for x := 0; x < width; x++ {
+				x0, x1, fx := sampleAxis(x, width, p.width)
+				for c := 0; c < p.n; c++ {
+					at := func(x, y int) float64 { return float64(p.pix[p.n*(y*p.width+x)+c]) }
+					top := at(x0, y0)*(1-fx) + at(x1, y0)*fx
+					bot := at(x0, y1)*(1-fx) + at(x1, y1)*fx
+					q.pix[p.n*(y*width+x)+c] = byte(top*(1-fy) + bot*fy + 0.5)
+				}
+			}
+		}
+	}
+	if straight {
+		unpremultiply(q.pix)
+	}
+	return q
+}
+
+// sampleAxis maps the center of destination pixel i of n onto a source axis
+// of size m, returning the two source pixels to blend and the weight of the
+// second.
+func sampleAxis(i, n, m int) (i0, i1 int, f float64) {
+	s := (float64(i)+0.5)*float64(m)/float64(n) - 0.5
+	if s < 0 {
+		s = 0
+	}
+	i0 = int(s)
+	if i0 >= m-1 {
+		return m - 1, m - 1, 0
+	}
+	return i0, i0 + 1, s - float64(i0)
+}
+
+// aligned returns the pixels with each row padded to a multiple of 4 bytes,
+// the default unpack alignment TexImage2D expects.
+func (p *pixels) aligned() []byte {
+	row := p.n * p.width
+	stride := (row + 3) &^ 3
+	if stride == row {
+		return p.pix
+	}
+	out := make([]byte, stride*p.height)
+	for y := 0; y < p.height; y++ {
+		copy(out[y*stride:], p.pix[y*row:(y+1)*row])
+	}
+	return out
+}
@@ -0,0 +1,251 @@
+package helpers_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/dmac/gg"
+	"github.com/dmac/gg/helpers"
+	"github.com/dmac/gg/soft"
+)
+
+// texImageRecorder records the last TexImage2D call before passing it on.
+type texImageRecorder struct {
+	gg.Backend
+	format gg.Enum
+	data   []byte
+}
+
+func (r *texImageRecorder) TexImage2D(
+	target gg.Enum, level int, internalFormat gg.Enum,
+	width, height, border int,
+	format, typ gg.Enum,
+	data []byte,
+) {
+	r.format, r.data = format, data
+	r.Backend.TexImage2D(target, level, internalFormat, width, height, border, format, typ, data)
+}
+
+func nrgba(w, h int, pix ...byte) *image.NRGBA {
+	return &image.NRGBA{Pix: pix, Stride: 4 * w, Rect: image.Rect(0, 0, w, h)}
+}
+
+func rgba(w, h int, pix ...byte) *image.RGBA {
+	return &image.RGBA{Pix: pix, Stride: 4 * w, Rect: image.Rect(0, 0, w, h)}
+}
+
+func gray(w, h int, pix ...byte) *image.Gray {
+	return &image.Gray{Pix: pix, Stride: w, Rect: image.Rect(0, 0, w, h)}
+}
+
+var palette = color.Palette{
+	color.NRGBA{255, 0, 0, 128},
+	color.NRGBA{0, 0, 0, 0},
+	color.Gray{200},
+}
+
+func TestNewTexture(t *testing.T) {
+	tests := []struct {
+		name string
+		img  image.Image
+		opts *helpers.TextureOptions
+
+		format        gg.Enum
+		width, height int
+		texels        []byte // RGBA, top row first
+	}{
+		{
+			name: "RGBA straight",
+			img: rgba(3, 2,
+				128, 0, 0, 128, 0, 0, 0, 0, 10, 20, 30, 255,
+				0, 64, 0, 64, 255, 255, 255, 255, 0, 0, 0, 0,
+			),
+			format: gg.RGBA, width: 3, height: 2,
+			texels: []byte{
+				255, 0, 0, 128, 0, 0, 0, 0, 10, 20, 30, 255,
+				0, 255, 0, 64, 255, 255, 255, 255, 0, 0, 0, 0,
+			},
+		},
+		{
+			name: "RGBA premultiplied",
+			img: rgba(3, 2,
+				128, 0, 0, 128, 0, 0, 0, 0, 10, 20, 30, 255,
+				0, 64, 0, 64, 255, 255, 255, 255, 0, 0, 0, 0,
+			),
+			opts:   &helpers.TextureOptions{Premultiplied: true},
+			format: gg.RGBA, width: 3, height: 2,
+			texels: []byte{
+				128, 0, 0, 128, 0, 0, 0, 0, 10, 20, 30, 255,
+				0, 64, 0, 64, 255, 255, 255, 255, 0, 0, 0, 0,
+			},
+		},
+		{
+			name: "NRGBA straight",
+			img: nrgba(3, 2,
+				200, 100, 50, 128, 1, 2, 3, 0, 10, 20, 30, 255,
+				0, 0, 0, 255, 255, 255, 255, 255, 9, 9, 9, 9,
+			),
+			format: gg.RGBA, width: 3, height: 2,
+			texels: []byte{
+				200, 100, 50, 128, 1, 2, 3, 0, 10, 20, 30, 255,
+				0, 0, 0, 255, 255, 255, 255, 255, 9, 9, 9, 9,
+			},
+		},
+		{
+			name: "NRGBA premultiplied",
+			img: nrgba(3, 2,
+				200, 100, 50, 128, 1, 2, 3, 0, 10, 20, 30, 255,
+				0, 0, 0, 255, 255, 255, 255, 255, 9, 9, 9, 9,
+			),
+			opts:   &helpers.TextureOptions{Premultiplied: true},
+			format: gg.RGBA, width: 3, height: 2,
+			texels: []byte{
+				100, 50, 25, 128, 0, 0, 0, 0, 10, 20, 30, 255,
+				0, 0, 0, 255, 255, 255, 255, 255, 0, 0, 0, 9,
+			},
+		},
+		{
+			name: "sub-image",
+			img: nrgba(4, 2,
+				1, 1, 1, 1, 10, 0, 0, 255, 20, 0, 0, 255, 30, 0, 0, 255,
+				2, 2, 2, 2, 40, 0, 0, 255, 50, 0, 0, 255, 60, 0, 0, 255,
+			).SubImage(image.Rect(1, 0, 4, 2)),
+			format: gg.RGBA, width: 3, height: 2,
+			texels: []byte{
+				10, 0, 0, 255, 20, 0, 0, 255, 30, 0, 0, 255,
+				40, 0, 0, 255, 50, 0, 0, 255, 60, 0, 0, 255,
+			},
+		},
+		{
+			name: "Paletted straight",
+			img: &image.Paletted{
+				Pix: []byte{0, 1, 2, 2, 1, 0}, Stride: 3, Rect: image.Rect(0, 0, 3, 2),
+				Palette: palette,
+			},
+			format: gg.RGBA, width: 3, height: 2,
+			texels: []byte{
+				255, 0, 0, 128, 0, 0, 0, 0, 200, 200, 200, 255,
+				200, 200, 200, 255, 0, 0, 0, 0, 255, 0, 0, 128,
+			},
+		},
+		{
+			name: "Paletted premultiplied",
+			img: &image.Paletted{
+				Pix: []byte{0, 1, 2, 2, 1, 0}, Stride: 3, Rect: image.Rect(0, 0, 3, 2),
+				Palette: palette,
+			},
+			opts:   &helpers.TextureOptions{Premultiplied: true},
+			format: gg.RGBA, width: 3, height: 2,
+			texels: []byte{
+				128, 0, 0, 128, 0, 0, 0, 0, 200, 200, 200, 255,
+				200, 200, 200, 255, 0, 0, 0, 0, 128, 0, 0, 128,
+			},
+		},
+		{
+			// Rows of 3 bytes are padded to 4 for the unpack alignment.
+			name:   "Gray",
+			img:    gray(3, 2, 10, 20, 30, 40, 50, 60),
+			format: gg.LUMINANCE, width: 3, height: 2,
+			texels: []byte{
+				10, 10, 10, 255, 20, 20, 20, 255, 30, 30, 30, 255,
+				40, 40, 40, 255, 50, 50, 50, 255, 60, 60, 60, 255,
+			},
+		},
+		{
+			name: "NPOTPad",
+			img: nrgba(3, 2,
+				10, 0, 0, 255, 20, 0, 0, 255, 30, 0, 0, 128,
+				40, 0, 0, 255, 50, 0, 0, 255, 60, 0, 0, 64,
+			),
+			opts:   &helpers.TextureOptions{NPOT: helpers.NPOTPad},
+			format: gg.RGBA, width: 4, height: 2,
+			texels: []byte{
+				10, 0, 0, 255, 20, 0, 0, 255, 30, 0, 0, 128, 0, 0, 0, 0,
+				40, 0, 0, 255, 50, 0, 0, 255, 60, 0, 0, 64, 0, 0, 0, 0,
+			},
+		},
+		{
+			name:   "NPOTPad Gray",
+			img:    gray(3, 1, 10, 20, 30),
+			opts:   &helpers.TextureOptions{NPOT: helpers.NPOTPad},
+			format: gg.LUMINANCE, width: 4, height: 1,
+			texels: []byte{
+				10, 10, 10, 255, 20, 20, 20, 255, 30, 30, 30, 255, 0, 0, 0, 255,
+			},
+		},
+		{
+			// Destination pixels 1 and 2 fall 0.625 and 1.375 source pixels
+			// along.
+			name:   "NPOTResize Gray",
+			img:    gray(3, 1, 0, 80, 160),
+			opts:   &helpers.TextureOptions{NPOT: helpers.NPOTResize},
+			format: gg.LUMINANCE, width: 4, height: 1,
+			texels: []byte{
+				0, 0, 0, 255, 50, 50, 50, 255, 110, 110, 110, 255, 160, 160, 160, 255,
+			},
+		},
+		{
+			// The transparent green pixel in the middle must not bleed into
+			// its neighbors.
+			name: "NPOTResize straight",
+			img: nrgba(3, 1,
+				255, 0, 0, 255, 0, 255, 0, 0, 0, 0, 255, 255,
+			),
+			opts:   &helpers.TextureOptions{NPOT: helpers.NPOTResize},
+			format: gg.RGBA, width: 4, height: 1,
+			texels: []byte{
+				255, 0, 0, 255, 255, 0, 0, 96, 0, 0, 255, 96, 0, 0, 255, 255,
+			},
+		},
+		{
+			name: "NPOTResize premultiplied",
+			img: nrgba(3, 1,
+				255, 0, 0, 255, 0, 255, 0, 0, 0, 0, 255, 255,
+			),
+			opts:   &helpers.TextureOptions{NPOT: helpers.NPOTResize, Premultiplied: true},
+			format: gg.RGBA, width: 4, height: 1,
+			texels: []byte{
+				255, 0, 0, 255, 96, 0, 0, 96, 0, 0, 96, 96, 0, 0, 255, 255,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := gg_soft.New(1, 1)
+			r := &texImageRecorder{Backend: s}
+			ctx := gg.NewContext(r)
+			tex, err := helpers.NewTexture(ctx, tt.img, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if r.format != tt.format {
+				t.Errorf("uploaded format 0x%x, want 0x%x", int(r.format), int(tt.format))
+			}
+			n := 4
+			if tt.format == gg.LUMINANCE {
+				n = 1
+			}
+			if want := (n*tt.width + 3) &^ 3 * tt.height; len(r.data) != want {
+				t.Errorf("uploaded %d bytes, want %d", len(r.data), want)
+			}
+
+			fb := ctx.CreateFramebuffer()
+			ctx.BindFramebuffer(gg.FRAMEBUFFER, fb)
+			ctx.FramebufferTexture2D(gg.FRAMEBUFFER, gg.COLOR_ATTACHMENT0, gg.TEXTURE_2D, tex, 0)
+			if err := ctx.CheckFramebufferStatus(gg.FRAMEBUFFER); err != nil {
+				t.Fatal(err)
+			}
+			got := make([]byte, 4*tt.width*tt.height)
+			ctx.ReadPixels(0, 0, tt.width, tt.height, gg.RGBA, gg.UNSIGNED_BYTE, got)
+			if err := s.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.texels) {
+				t.Errorf("texels =\n%v\nwant\n%v", got, tt.texels)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package gg
+
+import (
+	"image"
+	"image/draw"
+)
+
+// TexImage2DFromImage specifies level of the texture bound to target from
+// img, stored as RGBA with UNSIGNED_BYTE components. Colors are uploaded
+// with straight, not premultiplied, alpha, which is what browsers upload
+// image elements as. The top row of img is the row at t = 0.
+func (c *Context) TexImage2DFromImage(target Enum, level int, img image.Image) error {
+	pix, w, h := nrgbaPixels(img)
+	return c.TexImage2D(target, level, RGBA, w, h, 0, RGBA, UNSIGNED_BYTE, pix)
+}
+
+// nrgbaPixels returns the pixels of img as tightly packed, non-premultiplied
+// RGBA rows, reusing the pixels of an *image.NRGBA where possible.
+func nrgbaPixels(img image.Image) (pix []byte, width, height int) {
+	r := img.Bounds()
+	width, height = r.Dx(), r.Dy()
+	if m, ok := img.(*image.NRGBA); ok && m.Stride == 4*width {
+		i := m.PixOffset(r.Min.X, r.Min.Y)
+		return m.Pix[i : i+4*width*height], width, height
+	}
+	m := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(m, m.Bounds(), img, r.Min, draw.Src)
+	return m.Pix, width, height
+}
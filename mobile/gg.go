@@ -0,0 +1,461 @@
+// Package gg_mobile implements gg.Backend on OpenGL ES 2.0 through
+// golang.org/x/mobile/gl, for Android and iOS.
+//
+// A gl.Context does not make GL calls itself. It queues them for a
+// gl.Worker, which executes them on the thread the GL context is current on,
+// and calls that return a value block until the worker has run them. Within
+// golang.org/x/mobile/app the app package services the worker and hands out
+// the gl.Context in lifecycle events:
+//
+//	case lifecycle.Event:
+//		if e.Crosses(lifecycle.StageVisible) == lifecycle.CrossOn {
+//			ctx = gg_mobile.NewContext(e.DrawContext.(gl.Context))
+//		}
+//
+// Programs that create their own GL context use NewWorkerContext and Serve
+// instead.
+package gg_mobile
+
+import (
+	"fmt"
+
+	"github.com/dmac/gg"
+	"golang.org/x/mobile/gl"
+)
+
+type backend struct {
+	gl   gl.Context
+	objs gg.Table
+}
+
+var _ gg.Backend = (*backend)(nil)
+
+// NewContext returns a gg.Context that issues calls to glctx.
+func NewContext(glctx gl.Context) *gg.Context {
+	return gg.NewContext(&backend{gl: glctx})
+}
+
+// NewWorkerContext creates a gl.Context with gl.NewContext and returns a
+// gg.Context for it, along with the worker that executes its calls. The
+// worker must be serviced, for example with Serve, on the thread the GL
+// context is current on.
+func NewWorkerContext() (*gg.Context, gl.Worker) {
+	glctx, worker := gl.NewContext()
+	return NewContext(glctx), worker
+}
+
+// Serve executes the GL calls queued for w until done is closed. It must be
+// called from the thread the GL context is current on, which should be
+// locked with runtime.LockOSThread.
+func Serve(w gl.Worker, done <-chan struct{}) {
+	for {
+		select {
+		case <-w.WorkAvailable():
+			w.DoWork()
+		case <-done:
+			return
+		}
+	}
+}
+
+// The lookups below return the zero object, which GL ignores, for an ID
+// that is not in the table.
+
+func (b *backend) buffer(id gg.ID) gl.Buffer {
+	v, _ := b.objs.Get(id).(gl.Buffer)
+	return v
+}
+
+func (b *backend) shader(id gg.ID) gl.Shader {
+	v, _ := b.objs.Get(id).(gl.Shader)
+	return v
+}
+
+func (b *backend) program(id gg.ID) gl.Program {
+	v, _ := b.objs.Get(id).(gl.Program)
+	return v
+}
+
+func (b *backend) uniform(id gg.ID) gl.Uniform {
+	if v, ok := b.objs.Get(id).(gl.Uniform); ok {
+		return v
+	}
+	return gl.Uniform{Value: -1}
+}
+
+func (b *backend) texture(id gg.ID) gl.Texture {
+	v, _ := b.objs.Get(id).(gl.Texture)
+	return v
+}
+
+func (b *backend) framebuffer(id gg.ID) gl.Framebuffer {
+	v, _ := b.objs.Get(id).(gl.Framebuffer)
+	return v
+}
+
+func (b *backend) renderbuffer(id gg.ID) gl.Renderbuffer {
+	v, _ := b.objs.Get(id).(gl.Renderbuffer)
+	return v
+}
+
+func (b *backend) Enable(c gg.Enum) {
+	b.gl.Enable(gl.Enum(c))
+}
+
+func (b *backend) Disable(c gg.Enum) {
+	b.gl.Disable(gl.Enum(c))
+}
+
+func (b *backend) DepthFunc(f gg.Enum) {
+	b.gl.DepthFunc(gl.Enum(f))
+}
+
+func (b *backend) BlendFunc(sfactor, dfactor gg.Enum) {
+	b.gl.BlendFunc(gl.Enum(sfactor), gl.Enum(dfactor))
+}
+
+func (b *backend) Clear(mask gg.Enum) {
+	b.gl.Clear(gl.Enum(mask))
+}
+
+func (be *backend) ClearColor(r, g, b, a float32) {
+	be.gl.ClearColor(r, g, b, a)
+}
+
+func (b *backend) Viewport(x, y, width, height int) {
+	b.gl.Viewport(x, y, width, height)
+}
+
+func (b *backend) Scissor(x, y, width, height int) {
+	b.gl.Scissor(int32(x), int32(y), int32(width), int32(height))
+}
+
+func (b *backend) CreateBuffer() *gg.Buffer {
+	return &gg.Buffer{ID: b.objs.Add(b.gl.CreateBuffer())}
+}
+
+func (b *backend) BindBuffer(typ gg.Enum, buf *gg.Buffer) {
+	var v gl.Buffer
+	if buf != nil {
+		v = b.buffer(buf.ID)
+	}
+	b.gl.BindBuffer(gl.Enum(typ), v)
+}
+
+func (b *backend) BufferData(typ gg.Enum, src []byte, usage gg.Enum) {
+	if len(src) == 0 {
+		b.gl.BufferInit(gl.Enum(typ), 0, gl.Enum(usage))
+		return
+	}
+	b.gl.BufferData(gl.Enum(typ), src, gl.Enum(usage))
+}
+
+func (b *backend) BufferSubData(typ gg.Enum, offset int, src []byte) {
+	if len(src) == 0 {
+		return
+	}
+	b.gl.BufferSubData(gl.Enum(typ), offset, src)
+}
+
+func (b *backend) DeleteBuffer(buf *gg.Buffer) {
+	b.gl.DeleteBuffer(b.buffer(buf.ID))
+	b.objs.Delete(buf.ID)
+}
+
+func (*backend) ShaderDialect() gg.Dialect {
+	return gg.GLSLES100
+}
+
+func (b *backend) CreateShader(src []byte, typ gg.Enum) (*gg.Shader, error) {
+	shader := b.gl.CreateShader(gl.Enum(typ))
+	b.gl.ShaderSource(shader, string(src))
+	b.gl.CompileShader(shader)
+	if b.gl.GetShaderi(shader, gl.COMPILE_STATUS) == 0 {
+		log := b.gl.GetShaderInfoLog(shader)
+		b.gl.DeleteShader(shader)
+		return nil, gg.NewShaderError(typ, src, log)
+	}
+	return &gg.Shader{ID: b.objs.Add(shader)}, nil
+}
+
+func (b *backend) DeleteShader(s *gg.Shader) {
+	b.gl.DeleteShader(b.shader(s.ID))
+	b.objs.Delete(s.ID)
+}
+
+func (b *backend) CreateProgram() *gg.Program {
+	return &gg.Program{ID: b.objs.Add(b.gl.CreateProgram())}
+}
+
+func (b *backend) DeleteProgram(p *gg.Program) {
+	b.gl.DeleteProgram(b.program(p.ID))
+	b.objs.Delete(p.ID)
+}
+
+func (b *backend) AttachShader(p *gg.Program, s *gg.Shader) {
+	b.gl.AttachShader(b.program(p.ID), b.shader(s.ID))
+	b.objs.Attach(p.ID, s.ID)
+}
+
+func (b *backend) DetachShader(p *gg.Program, s *gg.Shader) {
+	b.gl.DetachShader(b.program(p.ID), b.shader(s.ID))
+	b.objs.Detach(p.ID, s.ID)
+}
+
+func (b *backend) LinkProgram(p *gg.Program) error {
+	pv := b.program(p.ID)
+	b.gl.LinkProgram(pv)
+	if b.gl.GetProgrami(pv, gl.LINK_STATUS) == 0 {
+		return gg.NewLinkError(b.gl.GetProgramInfoLog(pv))
+	}
+	return nil
+}
+
+func (b *backend) GetProgramParameter(p *gg.Program, pname gg.Enum) int {
+	return b.gl.GetProgrami(b.program(p.ID), gl.Enum(pname))
+}
+
+func (b *backend) GetActiveUniform(p *gg.Program, index int) gg.ActiveInfo {
+	name, size, typ := b.gl.GetActiveUniform(b.program(p.ID), uint32(index))
+	return gg.ActiveInfo{Name: name, Size: size, Type: gg.Enum(typ)}
+}
+
+func (b *backend) GetActiveAttrib(p *gg.Program, index int) gg.ActiveInfo {
+	name, size, typ := b.gl.GetActiveAttrib(b.program(p.ID), uint32(index))
+	return gg.ActiveInfo{Name: name, Size: size, Type: gg.Enum(typ)}
+}
+
+func (b *backend) UseProgram(p *gg.Program) {
+	var v gl.Program
+	if p != nil {
+		v = b.program(p.ID)
+	}
+	b.gl.UseProgram(v)
+}
+
+func (b *backend) GetUniformLocation(p *gg.Program, name string) (*gg.Uniform, error) {
+	u := b.gl.GetUniformLocation(b.program(p.ID), name)
+	if u.Value < 0 {
+		return nil, fmt.Errorf("gg: no uniform named %s", name)
+	}
+	return &gg.Uniform{ID: b.objs.Named(p.ID, name, u)}, nil
+}
+
+func (b *backend) Uniform1f(u *gg.Uniform, v0 float32) {
+	b.gl.Uniform1f(b.uniform(u.ID), v0)
+}
+
+func (b *backend) Uniform2f(u *gg.Uniform, v0, v1 float32) {
+	b.gl.Uniform2f(b.uniform(u.ID), v0, v1)
+}
+
+func (b *backend) Uniform3f(u *gg.Uniform, v0, v1, v2 float32) {
+	b.gl.Uniform3f(b.uniform(u.ID), v0, v1, v2)
+}
+
+func (b *backend) Uniform4f(u *gg.Uniform, v0, v1, v2, v3 float32) {
+	b.gl.Uniform4f(b.uniform(u.ID), v0, v1, v2, v3)
+}
+
+func (b *backend) Uniform1i(u *gg.Uniform, v0 int) {
+	b.gl.Uniform1i(b.uniform(u.ID), v0)
+}
+
+func (b *backend) Uniform2i(u *gg.Uniform, v0, v1 int) {
+	b.gl.Uniform2i(b.uniform(u.ID), v0, v1)
+}
+
+func (b *backend) Uniform3i(u *gg.Uniform, v0, v1, v2 int) {
+	b.gl.Uniform3i(b.uniform(u.ID), int32(v0), int32(v1), int32(v2))
+}
+
+func (b *backend) Uniform4i(u *gg.Uniform, v0, v1, v2, v3 int) {
+	b.gl.Uniform4i(b.uniform(u.ID), int32(v0), int32(v1), int32(v2), int32(v3))
+}
+
+func (b *backend) Uniform1fv(u *gg.Uniform, values []float32) {
+	b.gl.Uniform1fv(b.uniform(u.ID), values)
+}
+
+func (b *backend) Uniform2fv(u *gg.Uniform, values []float32) {
+	b.gl.Uniform2fv(b.uniform(u.ID), values)
+}
+
+func (b *backend) Uniform3fv(u *gg.Uniform, values []float32) {
+	b.gl.Uniform3fv(b.uniform(u.ID), values)
+}
+
+func (b *backend) Uniform4fv(u *gg.Uniform, values []float32) {
+	b.gl.Uniform4fv(b.uniform(u.ID), values)
+}
+
+func (b *backend) Uniform1iv(u *gg.Uniform, values []int32) {
+	b.gl.Uniform1iv(b.uniform(u.ID), values)
+}
+
+func (b *backend) Uniform2iv(u *gg.Uniform, values []int32) {
+	b.gl.Uniform2iv(b.uniform(u.ID), values)
+}
+
+func (b *backend) Uniform3iv(u *gg.Uniform, values []int32) {
+	b.gl.Uniform3iv(b.uniform(u.ID), values)
+}
+
+func (b *backend) Uniform4iv(u *gg.Uniform, values []int32) {
+	b.gl.Uniform4iv(b.uniform(u.ID), values)
+}
+
+func (b *backend) UniformMatrix2fv(u *gg.Uniform, values []float32) {
+	b.gl.UniformMatrix2fv(b.uniform(u.ID), values)
+}
+
+func (b *backend) UniformMatrix3fv(u *gg.Uniform, values []float32) {
+	b.gl.UniformMatrix3fv(b.uniform(u.ID), values)
+}
+
+func (b *backend) UniformMatrix4fv(u *gg.Uniform, values []float32) {
+	b.gl.UniformMatrix4fv(b.uniform(u.ID), values)
+}
+
+func (b *backend) GetAttribLocation(p *gg.Program, name string) (*gg.Attribute, error) {
+	a := b.gl.GetAttribLocation(b.program(p.ID), name)
+	// The location is -1, converted to uint, if there is no such attribute.
+	if int32(a.Value) < 0 {
+		return nil, fmt.Errorf("gg: no attribute named %s", name)
+	}
+	return &gg.Attribute{Location: int(a.Value)}, nil
+}
+
+func (b *backend) EnableVertexAttribArray(a *gg.Attribute) {
+	b.gl.EnableVertexAttribArray(gl.Attrib{Value: uint(a.Location)})
+}
+
+func (b *backend) VertexAttribPointer(a *gg.Attribute, size int, typ gg.Enum, normalized bool, stride, offset int) {
+	b.gl.VertexAttribPointer(gl.Attrib{Value: uint(a.Location)}, size, gl.Enum(typ), normalized, stride, offset)
+}
+
+func (b *backend) CreateTexture() *gg.Texture {
+	return &gg.Texture{ID: b.objs.Add(b.gl.CreateTexture())}
+}
+
+func (b *backend) ActiveTexture(tex gg.Enum) {
+	b.gl.ActiveTexture(gl.Enum(tex))
+}
+
+func (b *backend) BindTexture(target gg.Enum, texture *gg.Texture) {
+	var v gl.Texture
+	if texture != nil {
+		v = b.texture(texture.ID)
+	}
+	b.gl.BindTexture(gl.Enum(target), v)
+}
+
+func (b *backend) DeleteTexture(t *gg.Texture) {
+	b.gl.DeleteTexture(b.texture(t.ID))
+	b.objs.Delete(t.ID)
+}
+
+func (b *backend) TexImage2D(
+	target gg.Enum, level int, internalFormat gg.Enum,
+	width, height, border int,
+	format, typ gg.Enum,
+	data []byte,
+) {
+	// OpenGL ES has no border; it must be 0, and is not passed on.
+	b.gl.TexImage2D(
+		gl.Enum(target), level, int(internalFormat),
+		width, height,
+		gl.Enum(format), gl.Enum(typ),
+		data,
+	)
+}
+
+func (b *backend) TexParameteri(target gg.Enum, pname gg.Enum, param gg.Enum) {
+	b.gl.TexParameteri(gl.Enum(target), gl.Enum(pname), int(param))
+}
+
+func (b *backend) DrawArrays(mode gg.Enum, first, count int) {
+	b.gl.DrawArrays(gl.Enum(mode), first, count)
+}
+
+func (b *backend) DrawElements(mode gg.Enum, count int, typ gg.Enum, offset int) error {
+	// OpenGL ES 2.0 only has UNSIGNED_INT indices through
+	// OES_element_index_uint, which x/mobile/gl does not expose.
+	switch typ {
+	case gg.UNSIGNED_BYTE, gg.UNSIGNED_SHORT:
+	case gg.UNSIGNED_INT:
+		return fmt.Errorf("gg: UNSIGNED_INT indices are not supported on OpenGL ES 2.0")
+	default:
+		return fmt.Errorf("gg: invalid index type 0x%x", uint32(typ))
+	}
+	b.gl.DrawElements(gl.Enum(mode), count, gl.Enum(typ), offset)
+	return nil
+}
+
+func (b *backend) CreateFramebuffer() *gg.Framebuffer {
+	return &gg.Framebuffer{ID: b.objs.Add(b.gl.CreateFramebuffer())}
+}
+
+func (b *backend) BindFramebuffer(target gg.Enum, fb *gg.Framebuffer) {
+	var v gl.Framebuffer
+	if fb != nil {
+		v = b.framebuffer(fb.ID)
+	}
+	b.gl.BindFramebuffer(gl.Enum(target), v)
+}
+
+func (b *backend) DeleteFramebuffer(fb *gg.Framebuffer) {
+	b.gl.DeleteFramebuffer(b.framebuffer(fb.ID))
+	b.objs.Delete(fb.ID)
+}
+
+func (b *backend) FramebufferTexture2D(
+	target, attachment, textarget gg.Enum,
+	texture *gg.Texture, level int,
+) {
+	var v gl.Texture
+	if texture != nil {
+		v = b.texture(texture.ID)
+	}
+	b.gl.FramebufferTexture2D(gl.Enum(target), gl.Enum(attachment), gl.Enum(textarget), v, level)
+}
+
+func (b *backend) FramebufferRenderbuffer(
+	target, attachment, renderbuffertarget gg.Enum,
+	rb *gg.Renderbuffer,
+) {
+	var v gl.Renderbuffer
+	if rb != nil {
+		v = b.renderbuffer(rb.ID)
+	}
+	b.gl.FramebufferRenderbuffer(gl.Enum(target), gl.Enum(attachment), gl.Enum(renderbuffertarget), v)
+}
+
+func (b *backend) CheckFramebufferStatus(target gg.Enum) gg.Enum {
+	return gg.Enum(b.gl.CheckFramebufferStatus(gl.Enum(target)))
+}
+
+func (b *backend) CreateRenderbuffer() *gg.Renderbuffer {
+	return &gg.Renderbuffer{ID: b.objs.Add(b.gl.CreateRenderbuffer())}
+}
+
+func (b *backend) BindRenderbuffer(target gg.Enum, rb *gg.Renderbuffer) {
+	var v gl.Renderbuffer
+	if rb != nil {
+		v = b.renderbuffer(rb.ID)
+	}
+	b.gl.BindRenderbuffer(gl.Enum(target), v)
+}
+
+func (b *backend) DeleteRenderbuffer(rb *gg.Renderbuffer) {
+	b.gl.DeleteRenderbuffer(b.renderbuffer(rb.ID))
+	b.objs.Delete(rb.ID)
+}
+
+func (b *backend) RenderbufferStorage(target, internalFormat gg.Enum, width, height int) {
+	b.gl.RenderbufferStorage(gl.Enum(target), gl.Enum(internalFormat), width, height)
+}
+
+func (b *backend) ReadPixels(x, y, width, height int, format, typ gg.Enum, dst []byte) {
+	b.gl.ReadPixels(dst, x, y, width, height, gl.Enum(format), gl.Enum(typ))
+}
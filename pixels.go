@@ -0,0 +1,49 @@
+package gg
+
+// pixelSize returns the size in bytes of a single pixel with the given
+// format and type, or 0 if the combination is not recognized.
+func pixelSize(format, typ Enum) int {
+	switch typ {
+	case UNSIGNED_SHORT_5_6_5, UNSIGNED_SHORT_4_4_4_4, UNSIGNED_SHORT_5_5_5_1:
+		return 2
+	case UNSIGNED_INT_24_8:
+		return 4
+	}
+	var components int
+	switch format {
+	case ALPHA, LUMINANCE, RED, RED_INTEGER, DEPTH_COMPONENT:
+		components = 1
+	case LUMINANCE_ALPHA, RG, RG_INTEGER:
+		components = 2
+	case RGB, RGB_INTEGER:
+		components = 3
+	case RGBA, RGBA_INTEGER:
+		components = 4
+	default:
+		return 0
+	}
+	switch typ {
+	case BYTE, UNSIGNED_BYTE:
+		return components
+	case SHORT, UNSIGNED_SHORT, HALF_FLOAT:
+		return 2 * components
+	case INT, UNSIGNED_INT, FLOAT:
+		return 4 * components
+	}
+	return 0
+}
+
+// imageSize returns the number of bytes needed to hold a width by height
+// image with the given format and type, assuming the default row alignment
+// of 4 bytes. It returns -1 if the format and type are not recognized.
+func imageSize(width, height int, format, typ Enum) int {
+	size := pixelSize(format, typ)
+	if size == 0 {
+		return -1
+	}
+	if width <= 0 || height <= 0 {
+		return 0
+	}
+	row := (width*size + 3) &^ 3
+	return row*(height-1) + width*size
+}
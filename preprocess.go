@@ -0,0 +1,144 @@
+package gg
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// ShaderSource is shader source assembled by PreprocessShader from several
+// files, along with where each of its lines came from.
+type ShaderSource struct {
+	Text []byte
+	// Lines[i] is the origin of line i+1 of Text. Lines the preprocessor
+	// added, such as injected defines, have a zero SourceLine.
+	Lines []SourceLine
+}
+
+// SourceLine is a line of a shader file.
+type SourceLine struct {
+	File string
+	Line int
+}
+
+func (l SourceLine) String() string {
+	if l.File == "" {
+		return strconv.Itoa(l.Line)
+	}
+	return fmt.Sprintf("%s:%d", l.File, l.Line)
+}
+
+var includeRE = regexp.MustCompile(`^[ \t]*#[ \t]*include[ \t]*(?:"([^"]+)"|<([^>]+)>)[ \t]*(?://.*)?$`)
+
+// PreprocessShader reads the shader file name from fsys and replaces each
+// #include directive in it with the contents of the named file.
+//
+// #include "file" names a file relative to the including file and
+// #include <file> one relative to the root of fsys. Each file is included at
+// most once per shader, so included files need no include guards.
+//
+// Each entry of defines becomes a #define directive placed before the
+// source, after its #version directive if it has one.
+//
+// Compile errors from Context.CreateShaderFrom report the file and line of
+// the original source. CreateShaderFrom translates a source without a
+// #version directive for the backend, which defines macros such as
+// GG_GLSL_ES that select per-backend variants; see TranslateShader.
+func PreprocessShader(fsys fs.FS, name string, defines map[string]string) (*ShaderSource, error) {
+	p := &preprocessor{fsys: fsys, seen: make(map[string]bool)}
+	if err := p.include(name, ""); err != nil {
+		return nil, err
+	}
+	if len(defines) > 0 {
+		p.define(defines)
+	}
+	return &ShaderSource{Text: p.buf.Bytes(), Lines: p.lines}, nil
+}
+
+type preprocessor struct {
+	fsys  fs.FS
+	seen  map[string]bool
+	buf   bytes.Buffer
+	lines []SourceLine
+}
+
+func (p *preprocessor) include(name string, from string) error {
+	if !fs.ValidPath(name) {
+		return fmt.Errorf("gg: %s: invalid include path %q", from, name)
+	}
+	if p.seen[name] {
+		return nil
+	}
+	p.seen[name] = true
+	src, err := fs.ReadFile(p.fsys, name)
+	if err != nil {
+		if from != "" {
+			return fmt.Errorf("gg: %s: %v", from, err)
+		}
+		return fmt.Errorf("gg: %v", err)
+	}
+	for i, line := range bytes.SplitAfter(src, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		at := SourceLine{name, i + 1}
+		m := includeRE.FindSubmatch(bytes.TrimRight(line, "\r\n"))
+		if m == nil {
+			if from != "" && versionRE.Match(line) {
+				return fmt.Errorf("gg: %s: #version in included file", at)
+			}
+			p.buf.Write(line)
+			if line[len(line)-1] != '\n' {
+				p.buf.WriteByte('\n')
+			}
+			p.lines = append(p.lines, at)
+			continue
+		}
+		inc := string(m[2])
+		if len(m[1]) > 0 {
+			inc = path.Join(path.Dir(name), string(m[1]))
+		}
+		if err := p.include(inc, at.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// define inserts a #define directive for each of defines, in sorted order,
+// after the #version directive or at the start of the source.
+func (p *preprocessor) define(defines map[string]string) {
+	names := make([]string, 0, len(defines))
+	for name := range defines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&buf, "#define %s %s\n", name, defines[name])
+	}
+
+	src := p.buf.Bytes()
+	at, line := 0, 0
+	if loc := versionRE.FindIndex(src); loc != nil {
+		if end := bytes.IndexByte(src[loc[0]:], '\n'); end >= 0 {
+			at = loc[0] + end + 1
+			line = bytes.Count(src[:at], []byte("\n"))
+		}
+	}
+	text := make([]byte, 0, len(src)+buf.Len())
+	text = append(text, src[:at]...)
+	text = append(text, buf.Bytes()...)
+	text = append(text, src[at:]...)
+	lines := make([]SourceLine, 0, len(p.lines)+len(names))
+	lines = append(lines, p.lines[:line]...)
+	lines = append(lines, make([]SourceLine, len(names))...)
+	lines = append(lines, p.lines[line:]...)
+	p.buf.Reset()
+	p.buf.Write(text)
+	p.lines = lines
+}
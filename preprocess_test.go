@@ -0,0 +1,222 @@
+package gg_test
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dmac/gg"
+)
+
+var shaderFiles = fstest.MapFS{
+	"shaders/main.frag":  {Data: []byte("#version 100\n#include \"light.glsl\"\nvoid main() {}\n")},
+	"shaders/light.glsl": {Data: []byte("#include <common/math.glsl>\nvec3 light() { return vec3(PI); }\n")},
+	"shaders/twice.frag": {Data: []byte("#include \"light.glsl\"\n#include \"light.glsl\" // again\nvoid main() {}\n")},
+	"common/math.glsl":   {Data: []byte("float sq(float x) { return x * x; }")},
+	"bad/version.frag":   {Data: []byte("#include \"v.glsl\"\n")},
+	"bad/v.glsl":         {Data: []byte("#version 100\n")},
+	"bad/missing.frag":   {Data: []byte("void f();\n#include \"nope.glsl\"\n")},
+	"bad/escape.frag":    {Data: []byte("#include \"../../etc/passwd\"\n")},
+}
+
+func TestPreprocessShader(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		defines   map[string]string
+		wantText  string
+		wantLines []gg.SourceLine
+	}{
+		{
+			name: "nested includes",
+			file: "shaders/main.frag",
+			wantText: "#version 100\n" +
+				"float sq(float x) { return x * x; }\n" +
+				"vec3 light() { return vec3(PI); }\n" +
+				"void main() {}\n",
+			wantLines: []gg.SourceLine{
+				{File: "shaders/main.frag", Line: 1},
+				{File: "common/math.glsl", Line: 1},
+				{File: "shaders/light.glsl", Line: 2},
+				{File: "shaders/main.frag", Line: 3},
+			},
+		},
+		{
+			name: "defines follow version",
+			file: "shaders/main.frag",
+			defines: map[string]string{
+				"PI":    "3.14159",
+				"LIGHT": "1",
+			},
+			wantText: "#version 100\n" +
+				"#define LIGHT 1\n" +
+				"#define PI 3.14159\n" +
+				"float sq(float x) { return x * x; }\n" +
+				"vec3 light() { return vec3(PI); }\n" +
+				"void main() {}\n",
+			wantLines: []gg.SourceLine{
+				{File: "shaders/main.frag", Line: 1},
+				{},
+				{},
+				{File: "common/math.glsl", Line: 1},
+				{File: "shaders/light.glsl", Line: 2},
+				{File: "shaders/main.frag", Line: 3},
+			},
+		},
+		{
+			name:    "defines without version come first",
+			file:    "common/math.glsl",
+			defines: map[string]string{"N": "4"},
+			wantText: "#define N 4\n" +
+				"float sq(float x) { return x * x; }\n",
+			wantLines: []gg.SourceLine{
+				{},
+				{File: "common/math.glsl", Line: 1},
+			},
+		},
+		{
+			name: "files are included once",
+			file: "shaders/twice.frag",
+			wantText: "float sq(float x) { return x * x; }\n" +
+				"vec3 light() { return vec3(PI); }\n" +
+				"void main() {}\n",
+			wantLines: []gg.SourceLine{
+				{File: "common/math.glsl", Line: 1},
+				{File: "shaders/light.glsl", Line: 2},
+				{File: "shaders/twice.frag", Line: 3},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := gg.PreprocessShader(shaderFiles, tt.file, tt.defines)
+			if err != nil {
+				t.Fatalf("PreprocessShader: %v", err)
+			}
+			if string(src.Text) != tt.wantText {
+				t.Errorf("Text =\n%s\nwant\n%s", src.Text, tt.wantText)
+			}
+			if !reflect.DeepEqual(src.Lines, tt.wantLines) {
+				t.Errorf("Lines = %v, want %v", src.Lines, tt.wantLines)
+			}
+		})
+	}
+}
+
+func TestPreprocessShaderErrors(t *testing.T) {
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"bad/version.frag", "bad/v.glsl:1: #version in included file"},
+		{"bad/missing.frag", "bad/missing.frag:2:"},
+		{"bad/escape.frag", "invalid include path"},
+		{"nope.frag", "nope.frag"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			_, err := gg.PreprocessShader(shaderFiles, tt.file, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("PreprocessShader error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// failingBackend compiles every shader for GLSL ES 1.00 and fails it with a
+// Mesa-style error on the line that contains "BAD".
+type failingBackend struct {
+	gg.Backend
+}
+
+func (failingBackend) ShaderDialect() gg.Dialect { return gg.GLSLES100 }
+
+func (failingBackend) CreateShader(src []byte, typ gg.Enum) (*gg.Shader, error) {
+	for i, line := range bytes.Split(src, []byte("\n")) {
+		if bytes.Contains(line, []byte("BAD")) {
+			log := fmt.Sprintf("0:%d(3): error: syntax error\n", i+1)
+			return nil, gg.NewShaderError(typ, src, log)
+		}
+	}
+	return &gg.Shader{ID: 1}, nil
+}
+
+func TestCreateShaderFromRemapsErrors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.frag": {Data: []byte("#include \"lib.glsl\"\nvoid main() {\n\tgl_FragColor = f();\n}\n")},
+		"lib.glsl":  {Data: []byte("vec4 f() {\n\treturn BAD;\n}\n")},
+	}
+	src, err := gg.PreprocessShader(fsys, "main.frag", map[string]string{"X": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := gg.NewContext(failingBackend{})
+	_, err = ctx.CreateShaderFrom(src, gg.FRAGMENT_SHADER)
+	se, ok := err.(*gg.ShaderError)
+	if !ok {
+		t.Fatalf("CreateShaderFrom error = %v, want a *gg.ShaderError", err)
+	}
+	if len(se.Diagnostics) != 1 {
+		t.Fatalf("Diagnostics = %v, want 1", se.Diagnostics)
+	}
+	d := se.Diagnostics[0]
+	if d.File != "lib.glsl" || d.Line != 2 || d.Column != 3 {
+		t.Errorf("diagnostic at %s:%d:%d, want lib.glsl:2:3", d.File, d.Line, d.Column)
+	}
+	if want := "gg: compile fragment shader: lib.glsl:2:3: error: syntax error"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
+
+// sourceBackend records the source of the last shader it compiled.
+type sourceBackend struct {
+	gg.Backend
+	dialect gg.Dialect
+	src     []byte
+}
+
+func (b *sourceBackend) ShaderDialect() gg.Dialect { return b.dialect }
+
+func (b *sourceBackend) CreateShader(src []byte, typ gg.Enum) (*gg.Shader, error) {
+	b.src = src
+	return &gg.Shader{ID: 1}, nil
+}
+
+func TestCreateShaderFromDefinesDialect(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.frag": {Data: []byte("#ifdef GG_GLSL_ES\nprecision highp float;\n#endif\nvoid main() {}\n")},
+	}
+	src, err := gg.PreprocessShader(fsys, "main.frag", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		d    gg.Dialect
+		want []string
+	}{
+		{gg.GLSL120, []string{"GG_GLSL120"}},
+		{gg.GLSL330, []string{"GG_GLSL330"}},
+		{gg.GLSLES100, []string{"GG_GLSLES100", "GG_GLSL_ES"}},
+		{gg.GLSLES300, []string{"GG_GLSLES300", "GG_GLSL_ES"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.d.String(), func(t *testing.T) {
+			b := &sourceBackend{dialect: tt.d}
+			if _, err := gg.NewContext(b).CreateShaderFrom(src, gg.FRAGMENT_SHADER); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, line := range strings.Split(string(b.src), "\n") {
+				if strings.HasPrefix(line, "#define ") {
+					got = append(got, strings.TrimSuffix(line[len("#define "):], " 1"))
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("defines = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
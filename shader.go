@@ -0,0 +1,199 @@
+package gg
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+)
+
+// Dialect identifies the version of GLSL a backend compiles.
+type Dialect int
+
+const (
+	// GLSL120 is desktop GLSL 1.20, compiled by OpenGL 2.1.
+	GLSL120 Dialect = iota + 1
+	// GLSL330 is desktop GLSL 3.30 core, compiled by OpenGL 3.3 core
+	// profile.
+	GLSL330
+	// GLSLES100 is GLSL ES 1.00, compiled by WebGL 1 and OpenGL ES 2.0.
+	GLSLES100
+	// GLSLES300 is GLSL ES 3.00, compiled by WebGL 2 and OpenGL ES 3.0.
+	GLSLES300
+)
+
+func (d Dialect) String() string {
+	switch d {
+	case GLSL120:
+		return "GLSL 1.20"
+	case GLSL330:
+		return "GLSL 3.30"
+	case GLSLES100:
+		return "GLSL ES 1.00"
+	case GLSLES300:
+		return "GLSL ES 3.00"
+	}
+	return fmt.Sprintf("Dialect(%d)", int(d))
+}
+
+// defines returns the macros TranslateShader defines for d.
+func (d Dialect) defines() []string {
+	switch d {
+	case GLSL120:
+		return []string{"GG_GLSL120"}
+	case GLSL330:
+		return []string{"GG_GLSL330"}
+	case GLSLES100:
+		return []string{"GG_GLSLES100", "GG_GLSL_ES"}
+	case GLSLES300:
+		return []string{"GG_GLSLES300", "GG_GLSL_ES"}
+	}
+	return nil
+}
+
+// ShaderDialectBackend is implemented by backends that compile GLSL source.
+// Context.CreateShader translates portable shader sources into the dialect
+// such a backend reports.
+type ShaderDialectBackend interface {
+	ShaderDialect() Dialect
+}
+
+// ShaderDialect returns the GLSL dialect the backend of c compiles, or 0 if
+// it takes shader sources verbatim, as the software backend does.
+func (c *Context) ShaderDialect() Dialect {
+	if b, ok := c.backend.(ShaderDialectBackend); ok {
+		return b.ShaderDialect()
+	}
+	return 0
+}
+
+var (
+	versionRE      = regexp.MustCompile(`(?m)^[ \t]*#[ \t]*version\b`)
+	precisionRE    = regexp.MustCompile(`\bprecision[ \t]+(highp|mediump|lowp)[ \t]+\w+[ \t]*;`)
+	floatDefaultRE = regexp.MustCompile(`\bprecision[ \t]+(highp|mediump|lowp)[ \t]+float[ \t]*;`)
+	qualifierRE    = regexp.MustCompile(`\b(highp|mediump|lowp)\b[ \t]*`)
+	attributeRE    = regexp.MustCompile(`\battribute\b`)
+	varyingRE      = regexp.MustCompile(`\bvarying\b`)
+	fragColorRE    = regexp.MustCompile(`\bgl_FragColor\b`)
+	fragDataRE     = regexp.MustCompile(`\bgl_FragData\b`)
+	textureFuncRE  = regexp.MustCompile(`\b(texture2D|textureCube)(Lod|Proj|ProjLod)?\b`)
+	extensionRE    = regexp.MustCompile(`^[ \t]*#[ \t]*extension\b`)
+)
+
+// IsPortableShader reports whether src is a portable shader source, one with
+// no #version directive.
+func IsPortableShader(src []byte) bool {
+	return !versionRE.Match(src)
+}
+
+// TranslateShader rewrites the portable shader source src, of type
+// VERTEX_SHADER or FRAGMENT_SHADER, for dialect d.
+//
+// A portable source is written in the common subset of GLSL 1.20 and GLSL ES
+// 1.00 and has no #version directive. It may use precision qualifiers and
+// precision statements, which are removed for GLSL 1.20. A fragment shader
+// without a default float precision gets mediump for GLSL ES. For GLSL 3.30
+// and GLSL ES 3.00, attribute and varying become in and out, texture2D and
+// textureCube become texture, and gl_FragColor is written to a declared
+// output instead; gl_FragData is not translated.
+//
+// Translation adds a #version line before the source and any declarations it
+// needs after the source's #extension directives. CreateShader maps line
+// numbers in compile errors back to the lines of src.
+//
+// After the #version line, the translated source defines a macro named for
+// the dialect, GG_GLSL120, GG_GLSL330, GG_GLSLES100 or GG_GLSLES300, and
+// GG_GLSL_ES for the GLSL ES dialects, so that one source can hold variants
+// for different backends:
+//
+//	#ifdef GG_GLSL_ES
+//	precision highp float;
+//	#endif
+func TranslateShader(src []byte, typ Enum, d Dialect) ([]byte, error) {
+	out, _, err := translateShader(src, typ, d)
+	return out, err
+}
+
+// translateShader is TranslateShader, also returning for each line of the
+// result the 1-based line of src it came from, or 0 for added lines.
+func translateShader(src []byte, typ Enum, d Dialect) ([]byte, []int, error) {
+	if !IsPortableShader(src) {
+		return nil, nil, fmt.Errorf("gg: TranslateShader: source has a #version directive")
+	}
+	if typ != VERTEX_SHADER && typ != FRAGMENT_SHADER {
+		return nil, nil, fmt.Errorf("gg: TranslateShader: invalid shader type 0x%x", uint32(typ))
+	}
+	body := src
+	var version string
+	var decls []string
+	switch d {
+	case GLSL120:
+		version = "#version 120"
+		body = precisionRE.ReplaceAll(body, nil)
+		body = qualifierRE.ReplaceAll(body, nil)
+	case GLSLES100:
+		version = "#version 100"
+	case GLSL330, GLSLES300:
+		version = "#version 330 core"
+		if d == GLSLES300 {
+			version = "#version 300 es"
+		}
+		if fragDataRE.Match(body) {
+			return nil, nil, fmt.Errorf("gg: TranslateShader: gl_FragData cannot be translated for %s", d)
+		}
+		if typ == VERTEX_SHADER {
+			body = attributeRE.ReplaceAll(body, []byte("in"))
+			body = varyingRE.ReplaceAll(body, []byte("out"))
+		} else {
+			body = varyingRE.ReplaceAll(body, []byte("in"))
+			body = fragColorRE.ReplaceAll(body, []byte("gg_FragColor"))
+		}
+		body = textureFuncRE.ReplaceAll(body, []byte("texture$2"))
+	default:
+		return nil, nil, fmt.Errorf("gg: TranslateShader: unknown dialect %s", d)
+	}
+	if typ == FRAGMENT_SHADER {
+		if (d == GLSLES100 || d == GLSLES300) && !floatDefaultRE.Match(src) {
+			decls = append(decls, "precision mediump float;")
+		}
+		if (d == GLSL330 || d == GLSLES300) && fragColorRE.Match(src) {
+			decls = append(decls, "out mediump vec4 gg_FragColor;")
+		}
+	}
+
+	// Declarations must follow #extension directives, which have to come
+	// before any other tokens.
+	lines := bytes.SplitAfter(body, []byte("\n"))
+	at := 0
+	for i, line := range lines {
+		if extensionRE.Match(line) {
+			at = i + 1
+		}
+	}
+	var buf bytes.Buffer
+	origin := []int{0}
+	buf.WriteString(version + "\n")
+	for _, def := range d.defines() {
+		buf.WriteString("#define " + def + " 1\n")
+		origin = append(origin, 0)
+	}
+	for i, line := range lines {
+		if i == at {
+			for _, decl := range decls {
+				buf.WriteString(decl + "\n")
+				origin = append(origin, 0)
+			}
+		}
+		buf.Write(line)
+		origin = append(origin, i+1)
+	}
+	if at == len(lines) && len(decls) > 0 {
+		if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+			buf.WriteString("\n")
+		}
+		for _, decl := range decls {
+			buf.WriteString(decl + "\n")
+			origin = append(origin, 0)
+		}
+	}
+	return buf.Bytes(), origin, nil
+}
@@ -0,0 +1,133 @@
+package gg_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dmac/gg"
+)
+
+func TestTranslateShader(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		typ  gg.Enum
+		d    gg.Dialect
+		want string
+	}{
+		{
+			name: "GLSL 1.20 strips precision",
+			src:  "attribute highp vec3 pos;\nvarying lowp vec2 uv;\nvoid main() {}\n",
+			typ:  gg.VERTEX_SHADER,
+			d:    gg.GLSL120,
+			want: "#version 120\n#define GG_GLSL120 1\nattribute vec3 pos;\nvarying vec2 uv;\nvoid main() {}\n",
+		},
+		{
+			name: "GLSL 1.20 removes precision statements",
+			src:  "precision mediump float;\nvoid main() { gl_FragColor = vec4(1.0); }\n",
+			typ:  gg.FRAGMENT_SHADER,
+			d:    gg.GLSL120,
+			want: "#version 120\n#define GG_GLSL120 1\n\nvoid main() { gl_FragColor = vec4(1.0); }\n",
+		},
+		{
+			name: "GLSL ES 1.00 adds default precision",
+			src:  "void main() { gl_FragColor = vec4(1.0); }\n",
+			typ:  gg.FRAGMENT_SHADER,
+			d:    gg.GLSLES100,
+			want: "#version 100\n#define GG_GLSLES100 1\n#define GG_GLSL_ES 1\nprecision mediump float;\nvoid main() { gl_FragColor = vec4(1.0); }\n",
+		},
+		{
+			name: "GLSL ES 1.00 keeps a default precision",
+			src:  "precision highp float;\nvoid main() { gl_FragColor = vec4(1.0); }\n",
+			typ:  gg.FRAGMENT_SHADER,
+			d:    gg.GLSLES100,
+			want: "#version 100\n#define GG_GLSLES100 1\n#define GG_GLSL_ES 1\nprecision highp float;\nvoid main() { gl_FragColor = vec4(1.0); }\n",
+		},
+		{
+			name: "GLSL ES 1.00 vertex shader is unchanged",
+			src:  "attribute vec3 pos;\nvoid main() { gl_Position = vec4(pos, 1.0); }\n",
+			typ:  gg.VERTEX_SHADER,
+			d:    gg.GLSLES100,
+			want: "#version 100\n#define GG_GLSLES100 1\n#define GG_GLSL_ES 1\nattribute vec3 pos;\nvoid main() { gl_Position = vec4(pos, 1.0); }\n",
+		},
+		{
+			name: "GLSL 3.30 vertex shader",
+			src:  "attribute vec3 pos;\nvarying vec2 uv;\nvarying vec2 my_varying;\nuniform sampler2D tex;\nvoid main() { uv = texture2DLod(tex, pos.xy, 0.0).xy; }\n",
+			typ:  gg.VERTEX_SHADER,
+			d:    gg.GLSL330,
+			want: "#version 330 core\n#define GG_GLSL330 1\nin vec3 pos;\nout vec2 uv;\nout vec2 my_varying;\nuniform sampler2D tex;\nvoid main() { uv = textureLod(tex, pos.xy, 0.0).xy; }\n",
+		},
+		{
+			name: "GLSL ES 3.00 fragment shader",
+			src:  "varying vec2 uv;\nuniform sampler2D tex;\nvoid main() { gl_FragColor = texture2D(tex, uv); }\n",
+			typ:  gg.FRAGMENT_SHADER,
+			d:    gg.GLSLES300,
+			want: "#version 300 es\n#define GG_GLSLES300 1\n#define GG_GLSL_ES 1\nprecision mediump float;\nout mediump vec4 gg_FragColor;\nin vec2 uv;\nuniform sampler2D tex;\nvoid main() { gg_FragColor = texture(tex, uv); }\n",
+		},
+		{
+			name: "declarations follow extensions",
+			src:  "#extension GL_OES_standard_derivatives : enable\nvoid main() { gl_FragColor = vec4(1.0); }\n",
+			typ:  gg.FRAGMENT_SHADER,
+			d:    gg.GLSL330,
+			want: "#version 330 core\n#define GG_GLSL330 1\n#extension GL_OES_standard_derivatives : enable\nout mediump vec4 gg_FragColor;\nvoid main() { gg_FragColor = vec4(1.0); }\n",
+		},
+		{
+			name: "source without a final newline",
+			src:  "void main() { gl_FragColor = vec4(1.0); }",
+			typ:  gg.FRAGMENT_SHADER,
+			d:    gg.GLSLES100,
+			want: "#version 100\n#define GG_GLSLES100 1\n#define GG_GLSL_ES 1\nprecision mediump float;\nvoid main() { gl_FragColor = vec4(1.0); }",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := gg.TranslateShader([]byte(tt.src), tt.typ, tt.d)
+			if err != nil {
+				t.Fatalf("TranslateShader: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("TranslateShader =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranslateShaderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		typ  gg.Enum
+		d    gg.Dialect
+		want string
+	}{
+		{"version", "#version 100\nvoid main() {}\n", gg.VERTEX_SHADER, gg.GLSLES100, "#version directive"},
+		{"shader type", "void main() {}\n", gg.TEXTURE_2D, gg.GLSLES100, "invalid shader type"},
+		{"dialect", "void main() {}\n", gg.VERTEX_SHADER, 0, "unknown dialect"},
+		{"gl_FragData", "void main() { gl_FragData[0] = vec4(1.0); }\n", gg.FRAGMENT_SHADER, gg.GLSLES300, "gl_FragData"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := gg.TranslateShader([]byte(tt.src), tt.typ, tt.d)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("TranslateShader error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsPortableShader(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{"void main() {}\n", true},
+		{"#version 100\nvoid main() {}\n", false},
+		{"  # version 330 core\nvoid main() {}\n", false},
+		{"// #version 100\nvoid main() {}\n", true},
+	}
+	for _, tt := range tests {
+		if got := gg.IsPortableShader([]byte(tt.src)); got != tt.want {
+			t.Errorf("IsPortableShader(%q) = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package gg
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Severity is the severity of a Diagnostic.
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// Diagnostic is one message from a shader compiler or linker info log.
+type Diagnostic struct {
+	// File and Line locate the message in the original source: File is set
+	// for shaders created with CreateShaderFrom, and Line is 0 for messages
+	// that do not refer to a line, as most link errors do not.
+	File     string
+	Line     int
+	Column   int // 0 if the driver does not report one
+	Severity Severity
+	Message  string
+
+	// line is the line of the compiled source the message refers to.
+	line int
+}
+
+func (d Diagnostic) String() string {
+	var b strings.Builder
+	if d.File != "" {
+		b.WriteString(d.File + ":")
+	}
+	if d.Line > 0 {
+		b.WriteString(strconv.Itoa(d.Line) + ":")
+		if d.Column > 0 {
+			b.WriteString(strconv.Itoa(d.Column) + ":")
+		}
+	}
+	if b.Len() > 0 {
+		b.WriteString(" ")
+	}
+	fmt.Fprintf(&b, "%s: %s", d.Severity, d.Message)
+	return b.String()
+}
+
+// ShaderError is returned by CreateShader when a shader fails to compile.
+type ShaderError struct {
+	Stage  Enum   // VERTEX_SHADER or FRAGMENT_SHADER
+	Source []byte // the source as compiled, after translation
+	Log    string // the driver's info log
+	// Diagnostics are the messages parsed from Log.
+	Diagnostics []Diagnostic
+
+	// lines are the origins of the lines of Source, if it was translated or
+	// preprocessed.
+	lines []SourceLine
+}
+
+// NewShaderError returns the error for a shader of type stage whose source
+// src failed to compile with the given info log. Backends use it to report
+// compile errors.
+func NewShaderError(stage Enum, src []byte, log string) *ShaderError {
+	log = strings.TrimRight(log, "\x00 \t\r\n")
+	return &ShaderError{
+		Stage:       stage,
+		Source:      src,
+		Log:         log,
+		Diagnostics: parseLog(log),
+	}
+}
+
+func (e *ShaderError) Error() string {
+	stage := "shader"
+	switch e.Stage {
+	case VERTEX_SHADER:
+		stage = "vertex shader"
+	case FRAGMENT_SHADER:
+		stage = "fragment shader"
+	}
+	return "gg: compile " + stage + ":" + formatLog(e.Log, e.Diagnostics)
+}
+
+// Excerpt returns the source lines that the error's diagnostics refer to,
+// each with up to context lines before and after it, numbered with their
+// positions in the original source. Offending lines are marked with > and
+// followed by their messages.
+func (e *ShaderError) Excerpt(context int) string {
+	src := strings.Split(strings.TrimSuffix(string(e.Source), "\n"), "\n")
+	msgs := make(map[int][]string)
+	var marked []int
+	for _, d := range e.Diagnostics {
+		if d.line < 1 || d.line > len(src) {
+			continue
+		}
+		if msgs[d.line] == nil {
+			marked = append(marked, d.line)
+		}
+		msgs[d.line] = append(msgs[d.line], d.String())
+	}
+	sort.Ints(marked)
+
+	width := 0
+	for n := range src {
+		if w := len(e.origin(n + 1)); w > width {
+			width = w
+		}
+	}
+	var b strings.Builder
+	end := 0
+	for _, line := range marked {
+		from, to := line-context, line+context
+		if from <= end {
+			from = end + 1
+		}
+		if from < 1 {
+			from = 1
+		}
+		if to > len(src) {
+			to = len(src)
+		}
+		if end > 0 && from > end+1 {
+			b.WriteString("...\n")
+		}
+		for n := from; n <= to; n++ {
+			mark := " "
+			if msgs[n] != nil {
+				mark = ">"
+			}
+			fmt.Fprintf(&b, "%s %*s | %s\n", mark, width, e.origin(n), src[n-1])
+			for _, m := range msgs[n] {
+				fmt.Fprintf(&b, "  %*s | %s\n", width, "", m)
+			}
+		}
+		if to > end {
+			end = to
+		}
+	}
+	return b.String()
+}
+
+// origin returns the label for line n of Source.
+func (e *ShaderError) origin(n int) string {
+	if e.lines == nil {
+		return strconv.Itoa(n)
+	}
+	if n > len(e.lines) || e.lines[n-1].Line == 0 {
+		return ""
+	}
+	return e.lines[n-1].String()
+}
+
+// remap sets the positions of e's diagnostics to the original source lines
+// that the lines of e.Source came from.
+func (e *ShaderError) remap(lines []SourceLine) {
+	e.lines = lines
+	for i := range e.Diagnostics {
+		d := &e.Diagnostics[i]
+		if d.line < 1 || d.line > len(lines) {
+			continue
+		}
+		d.File, d.Line = lines[d.line-1].File, lines[d.line-1].Line
+	}
+}
+
+// LinkError is returned by LinkProgram when a program fails to link.
+type LinkError struct {
+	Log         string // the driver's info log
+	Diagnostics []Diagnostic
+}
+
+// NewLinkError returns the error for a program that failed to link with the
+// given info log. Backends use it to report link errors.
+func NewLinkError(log string) *LinkError {
+	log = strings.TrimRight(log, "\x00 \t\r\n")
+	return &LinkError{Log: log, Diagnostics: parseLog(log)}
+}
+
+func (e *LinkError) Error() string {
+	return "gg: link program:" + formatLog(e.Log, e.Diagnostics)
+}
+
+// formatLog formats a single diagnostic on the same line and several on
+// lines of their own, or falls back to the raw log if no diagnostics could be
+// parsed from it.
+func formatLog(log string, diags []Diagnostic) string {
+	if len(diags) == 0 {
+		if log == "" {
+			return " no info log"
+		}
+		return " " + log
+	}
+	if len(diags) == 1 {
+		return " " + diags[0].String()
+	}
+	var b strings.Builder
+	for _, d := range diags {
+		b.WriteString("\n\t" + d.String())
+	}
+	return b.String()
+}
+
+// Info logs are not standardized; these match the forms used by the common
+// drivers. Line numbers are preceded by the source string number, which is
+// always 0 since gg passes a single string.
+var (
+	// Mesa: 0:12(5): error: syntax error, unexpected '}'
+	mesaRE = regexp.MustCompile(`^\d+:(\d+)\((\d+)\):\s*(\w+):\s*(.*)$`)
+	// ANGLE, Apple and Intel: ERROR: 0:12: 'x' : undeclared identifier
+	angleRE = regexp.MustCompile(`^(\w+):\s*\d+:(\d+):\s*(.*)$`)
+	// NVIDIA: 0(12) : error C1008: undefined variable "x"
+	nvidiaRE = regexp.MustCompile(`^\d+\((\d+)\)\s*:\s*(\w+)(?:\s+\w+)*\s*:\s*(.*)$`)
+	// Link errors and other messages without a position: error: ...
+	plainRE = regexp.MustCompile(`^(\w+):\s*(.*)$`)
+	// ANGLE's summary line: ERROR: 2 compilation errors.  No code generated.
+	summaryRE = regexp.MustCompile(`^\d+ compilation errors?\.`)
+)
+
+func parseLog(log string) []Diagnostic {
+	var diags []Diagnostic
+	for _, line := range strings.Split(log, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var d Diagnostic
+		var sev string
+		if m := mesaRE.FindStringSubmatch(line); m != nil {
+			d.line, _ = strconv.Atoi(m[1])
+			d.Column, _ = strconv.Atoi(m[2])
+			sev, d.Message = m[3], m[4]
+		} else if m := angleRE.FindStringSubmatch(line); m != nil {
+			d.line, _ = strconv.Atoi(m[2])
+			sev, d.Message = m[1], m[3]
+		} else if m := nvidiaRE.FindStringSubmatch(line); m != nil {
+			d.line, _ = strconv.Atoi(m[1])
+			sev, d.Message = m[2], m[3]
+		} else if m := plainRE.FindStringSubmatch(line); m != nil && !summaryRE.MatchString(m[2]) {
+			sev, d.Message = m[1], m[2]
+		} else {
+			continue
+		}
+		switch strings.ToLower(sev) {
+		case "error", "fatal":
+			d.Severity = SeverityError
+		case "warning":
+			d.Severity = SeverityWarning
+		case "info", "note":
+			d.Severity = SeverityInfo
+		default:
+			continue
+		}
+		d.Line = d.line
+		diags = append(diags, d)
+	}
+	return diags
+}
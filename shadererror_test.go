@@ -0,0 +1,155 @@
+package gg_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dmac/gg"
+)
+
+func TestShaderErrorDiagnostics(t *testing.T) {
+	type diag struct {
+		Line, Column int
+		Severity     gg.Severity
+		Message      string
+	}
+	tests := []struct {
+		name string
+		log  string
+		want []diag
+	}{
+		{
+			name: "Mesa",
+			log:  "0:12(5): error: syntax error, unexpected '}'\n0:3(1): warning: unused variable\n",
+			want: []diag{
+				{12, 5, gg.SeverityError, "syntax error, unexpected '}'"},
+				{3, 1, gg.SeverityWarning, "unused variable"},
+			},
+		},
+		{
+			name: "ANGLE",
+			log:  "ERROR: 0:7: 'x' : undeclared identifier\nERROR: 1 compilation errors.  No code generated.\n\x00",
+			want: []diag{
+				{7, 0, gg.SeverityError, "'x' : undeclared identifier"},
+			},
+		},
+		{
+			name: "NVIDIA",
+			log:  "0(4) : error C1008: undefined variable \"x\"\n0(9) : warning C7050: \"y\" might be used before being initialized",
+			want: []diag{
+				{4, 0, gg.SeverityError, "undefined variable \"x\""},
+				{9, 0, gg.SeverityWarning, "\"y\" might be used before being initialized"},
+			},
+		},
+		{
+			name: "no position",
+			log:  "error: vertex shader lacks `main'\n",
+			want: []diag{
+				{0, 0, gg.SeverityError, "vertex shader lacks `main'"},
+			},
+		},
+		{
+			name: "unrecognized",
+			log:  "Link failed for some reason\n",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := gg.NewShaderError(gg.FRAGMENT_SHADER, nil, tt.log)
+			var got []diag
+			for _, d := range e.Diagnostics {
+				got = append(got, diag{d.Line, d.Column, d.Severity, d.Message})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diagnostics = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShaderErrorString(t *testing.T) {
+	tests := []struct {
+		stage gg.Enum
+		log   string
+		want  string
+	}{
+		{gg.VERTEX_SHADER, "0:2(1): error: bad\n", "gg: compile vertex shader: 2:1: error: bad"},
+		{gg.FRAGMENT_SHADER, "0:2(1): error: bad\n0:3(4): error: worse\n", "gg: compile fragment shader:\n\t2:1: error: bad\n\t3:4: error: worse"},
+		{gg.FRAGMENT_SHADER, "something broke\n", "gg: compile fragment shader: something broke"},
+		{gg.FRAGMENT_SHADER, "\x00", "gg: compile fragment shader: no info log"},
+	}
+	for _, tt := range tests {
+		if got := gg.NewShaderError(tt.stage, nil, tt.log).Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestLinkError(t *testing.T) {
+	err := gg.NewLinkError("error: varying uv is not written by the vertex shader\n")
+	if want := "gg: link program: error: varying uv is not written by the vertex shader"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+	if len(err.Diagnostics) != 1 || err.Diagnostics[0].Line != 0 {
+		t.Errorf("Diagnostics = %v, want one without a line", err.Diagnostics)
+	}
+}
+
+func TestShaderErrorExcerpt(t *testing.T) {
+	src := []byte("one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n")
+	tests := []struct {
+		name    string
+		log     string
+		context int
+		want    string
+	}{
+		{
+			name:    "one line",
+			log:     "0:3(2): error: bad\n",
+			context: 1,
+			want: "   2 | two\n" +
+				">  3 | three\n" +
+				"     | 3:2: error: bad\n" +
+				"   4 | four\n",
+		},
+		{
+			name:    "separate lines",
+			log:     "0:1(1): error: a\n0:9(1): warning: b\n",
+			context: 1,
+			want: ">  1 | one\n" +
+				"     | 1:1: error: a\n" +
+				"   2 | two\n" +
+				"...\n" +
+				"   8 | eight\n" +
+				">  9 | nine\n" +
+				"     | 9:1: warning: b\n" +
+				"  10 | ten\n",
+		},
+		{
+			name:    "overlapping context",
+			log:     "0:4(1): error: a\n0:5(1): error: b\n",
+			context: 1,
+			want: "   3 | three\n" +
+				">  4 | four\n" +
+				"     | 4:1: error: a\n" +
+				">  5 | five\n" +
+				"     | 5:1: error: b\n" +
+				"   6 | six\n",
+		},
+		{
+			name:    "no positions",
+			log:     "error: bad\n",
+			context: 2,
+			want:    "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := gg.NewShaderError(gg.FRAGMENT_SHADER, src, tt.log).Excerpt(tt.context)
+			if got != tt.want {
+				t.Errorf("Excerpt =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package gg_soft
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dmac/gg"
+)
+
+const maxVertexAttribs = 16
+
+type program struct {
+	shaders []*shader
+	linked  bool
+
+	vfn      VertexFunc
+	ffn      FragmentFunc
+	uniforms *Uniforms
+	active   []*uniformVar // uniforms in order of declaration
+	attribs  []decl        // indexed by location
+	layout   map[string]varyingSlot
+	nvary    int
+}
+
+func (p *program) link(b *Backend) error {
+	var vs, fs *shader
+	for _, s := range p.shaders {
+		switch s.typ {
+		case gg.VERTEX_SHADER:
+			vs = s
+		case gg.FRAGMENT_SHADER:
+			fs = s
+		}
+	}
+	if vs == nil || fs == nil {
+		return gg.NewLinkError("error: need a vertex and a fragment shader")
+	}
+
+	uniforms := &Uniforms{b: b, vars: make(map[string]*uniformVar)}
+	var active []*uniformVar
+	var attribs []decl
+	layout := make(map[string]varyingSlot)
+	nvary := 0
+	for _, s := range []*shader{vs, fs} {
+		for _, d := range s.decls {
+			switch d.qualifier {
+			case "uniform":
+				if v, ok := uniforms.vars[d.name]; ok {
+					if v.typ != d.typ || v.size != d.size {
+						return gg.NewLinkError(fmt.Sprintf("error: uniform %s declared with different types", d.name))
+					}
+					continue
+				}
+				v := &uniformVar{
+					decl: d,
+					data: make([]float32, d.typ.components*d.size),
+				}
+				uniforms.vars[d.name] = v
+				active = append(active, v)
+			case "attribute":
+				if s == vs {
+					attribs = append(attribs, d)
+				}
+			case "varying":
+				if s == vs {
+					n := d.typ.components * d.size
+					layout[d.name] = varyingSlot{offset: nvary, n: n}
+					nvary += n
+				} else if _, ok := layout[d.name]; !ok {
+					return gg.NewLinkError(fmt.Sprintf("error: varying %s is not written by the vertex shader", d.name))
+				}
+			}
+		}
+	}
+	if len(attribs) > maxVertexAttribs {
+		return gg.NewLinkError(fmt.Sprintf("error: too many attributes (%d > %d)", len(attribs), maxVertexAttribs))
+	}
+
+	p.vfn = vs.vfn
+	p.ffn = fs.ffn
+	p.uniforms = uniforms
+	p.active = active
+	p.attribs = attribs
+	p.layout = layout
+	p.nvary = nvary
+	p.linked = true
+	return nil
+}
+
+// uniformLocation resolves name, which may index into an array uniform as
+// in "bones[3]".
+func (p *program) uniformLocation(name string) (*uniformLoc, bool) {
+	base, elem := name, 0
+	if i := strings.IndexByte(name, '['); i >= 0 && strings.HasSuffix(name, "]") {
+		n, err := strconv.Atoi(name[i+1 : len(name)-1])
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		base, elem = name[:i], n
+	}
+	v, ok := p.uniforms.vars[base]
+	if !ok || elem >= v.size {
+		return nil, false
+	}
+	return &uniformLoc{v: v, elem: elem}, true
+}
+
+func (p *program) attribLocation(name string) (int, bool) {
+	for i, a := range p.attribs {
+		if a.name == name {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// set stores values into consecutive elements of the uniform at loc, each
+// element holding n components. Values beyond the end of the uniform are
+// ignored, as in OpenGL.
+func (loc *uniformLoc) set(n int, values []float32) error {
+	v := loc.v
+	if v.typ.components != n {
+		return fmt.Errorf("gg: uniform %s has %d components, not %d", v.name, v.typ.components, n)
+	}
+	copy(v.data[loc.elem*n:], values)
+	return nil
+}
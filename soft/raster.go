@@ -0,0 +1,452 @@
+package gg_soft
+
+import (
+	"math"
+
+	"github.com/dmac/gg"
+)
+
+// clipVert is a shaded vertex in clip coordinates.
+type clipVert struct {
+	pos   [4]float32
+	psize float32
+	vary  []float32
+}
+
+// winVert is a vertex in window coordinates. Varyings are kept in clip space
+// and interpolated using invW for perspective correction.
+type winVert struct {
+	x, y, z float32
+	invW    float32
+	vary    []float32
+}
+
+type rasterizer struct {
+	b    *Backend
+	t    *target
+	p    *program
+	rect [4]int // x0, y0, x1, y1 of the drawable area
+	frag *Fragment
+	vary []float32
+}
+
+func (b *Backend) draw(mode gg.Enum, indices []int) {
+	p := b.program
+	if p == nil {
+		b.setErr("draw: no program in use")
+		return
+	}
+	t, status := b.drawTarget()
+	if status != gg.FRAMEBUFFER_COMPLETE {
+		b.setErr("draw: framebuffer incomplete")
+		return
+	}
+
+	for loc, a := range p.attribs {
+		if st := b.attribs[loc]; st.enabled && st.buf == nil {
+			// OpenGL reports INVALID_OPERATION for an enabled array with no
+			// buffer bound by VertexAttribPointer.
+			b.setErr("draw: attribute %s is enabled with no buffer", a.name)
+			return
+		}
+	}
+
+	attribs := make(map[string][4]float32, len(p.attribs))
+	vert := &Vertex{Uniforms: p.uniforms, attribs: attribs, layout: p.layout}
+	cache := make(map[int]*clipVert)
+	shade := func(i int) *clipVert {
+		if cv, ok := cache[i]; ok {
+			return cv
+		}
+		for loc, a := range p.attribs {
+			v, ok := b.attribs[loc].fetch(i)
+			if !ok {
+				b.setErr("draw: vertex %d is out of range of attribute %s", i, a.name)
+				return nil
+			}
+			attribs[a.name] = v
+		}
+		vert.Position = [4]float32{0, 0, 0, 1}
+		vert.PointSize = 1
+		vert.varyings = make([]float32, p.nvary)
+		p.vfn(vert)
+		cv := &clipVert{pos: vert.Position, psize: vert.PointSize, vary: vert.varyings}
+		cache[i] = cv
+		return cv
+	}
+	verts := make([]*clipVert, len(indices))
+	for k, i := range indices {
+		if verts[k] = shade(i); verts[k] == nil {
+			return
+		}
+	}
+
+	x0, y0, x1, y1 := intersect(0, 0, t.width, t.height, b.viewport)
+	if b.enabled[gg.SCISSOR_TEST] {
+		x0, y0, x1, y1 = intersect(x0, y0, x1, y1, b.scissor)
+	}
+	r := &rasterizer{
+		b:    b,
+		t:    t,
+		p:    p,
+		rect: [4]int{x0, y0, x1, y1},
+		frag: &Fragment{Uniforms: p.uniforms, layout: p.layout},
+		vary: make([]float32, p.nvary),
+	}
+
+	n := len(verts)
+	switch mode {
+	case gg.TRIANGLES:
+		for k := 0; k+2 < n; k += 3 {
+			r.triangle(verts[k], verts[k+1], verts[k+2])
+		}
+	case gg.TRIANGLE_STRIP:
+		for k := 0; k+2 < n; k++ {
+			if k%2 == 0 {
+				r.triangle(verts[k], verts[k+1], verts[k+2])
+			} else {
+				r.triangle(verts[k+1], verts[k], verts[k+2])
+			}
+		}
+	case gg.TRIANGLE_FAN:
+		for k := 1; k+1 < n; k++ {
+			r.triangle(verts[0], verts[k], verts[k+1])
+		}
+	case gg.LINES:
+		for k := 0; k+1 < n; k += 2 {
+			r.line(verts[k], verts[k+1])
+		}
+	case gg.LINE_STRIP, gg.LINE_LOOP:
+		for k := 0; k+1 < n; k++ {
+			r.line(verts[k], verts[k+1])
+		}
+		if mode == gg.LINE_LOOP && n > 2 {
+			r.line(verts[n-1], verts[0])
+		}
+	case gg.POINTS:
+		for _, v := range verts {
+			r.point(v)
+		}
+	default:
+		b.setErr("draw: invalid mode 0x%x", uint32(mode))
+	}
+}
+
+// nearPlanes are the clip planes applied before rasterization, as functions
+// of a clip-space position that must be non-negative. Clipping against
+// z >= -w handles the near plane; w >= epsilon keeps the perspective divide
+// well defined. The other planes are handled by limiting rasterization to
+// the viewport and discarding fragments with out-of-range depth.
+var nearPlanes = []func(p [4]float32) float32{
+	func(p [4]float32) float32 { return p[2] + p[3] },
+	func(p [4]float32) float32 { return p[3] - 1e-5 },
+}
+
+func lerpVert(a, b *clipVert, t float32) *clipVert {
+	v := &clipVert{
+		psize: a.psize + (b.psize-a.psize)*t,
+		vary:  make([]float32, len(a.vary)),
+	}
+	for i := range v.pos {
+		v.pos[i] = a.pos[i] + (b.pos[i]-a.pos[i])*t
+	}
+	for i := range v.vary {
+		v.vary[i] = a.vary[i] + (b.vary[i]-a.vary[i])*t
+	}
+	return v
+}
+
+// clipPolygon clips a convex polygon against nearPlanes.
+func clipPolygon(poly []*clipVert) []*clipVert {
+	for _, plane := range nearPlanes {
+		var out []*clipVert
+		for i, cur := range poly {
+			next := poly[(i+1)%len(poly)]
+			dc, dn := plane(cur.pos), plane(next.pos)
+			if dc >= 0 {
+				out = append(out, cur)
+			}
+			if (dc >= 0) != (dn >= 0) {
+				out = append(out, lerpVert(cur, next, dc/(dc-dn)))
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		poly = out
+	}
+	return poly
+}
+
+func (r *rasterizer) toWindow(v *clipVert) winVert {
+	vp := r.b.viewport
+	invW := 1 / v.pos[3]
+	return winVert{
+		x:    (v.pos[0]*invW+1)*float32(vp[2])/2 + float32(vp[0]),
+		y:    (v.pos[1]*invW+1)*float32(vp[3])/2 + float32(vp[1]),
+		z:    (v.pos[2]*invW + 1) / 2,
+		invW: invW,
+		vary: v.vary,
+	}
+}
+
+func (r *rasterizer) triangle(a, b, c *clipVert) {
+	poly := clipPolygon([]*clipVert{a, b, c})
+	for i := 1; i+1 < len(poly); i++ {
+		r.rasterTriangle(r.toWindow(poly[0]), r.toWindow(poly[i]), r.toWindow(poly[i+1]))
+	}
+}
+
+func edgeFunc(a, b *winVert, px, py float32) float32 {
+	return (b.x-a.x)*(py-a.y) - (b.y-a.y)*(px-a.x)
+}
+
+// topLeft reports whether the edge from a to b of a counter-clockwise
+// triangle is a top or left edge. Pixels centered exactly on such edges are
+// drawn, and those on other edges are not, so that triangles sharing an edge
+// never both cover a pixel.
+func topLeft(a, b *winVert) bool {
+	dx, dy := b.x-a.x, b.y-a.y
+	return dy < 0 || (dy == 0 && dx < 0)
+}
+
+func (r *rasterizer) rasterTriangle(v0, v1, v2 winVert) {
+	area := edgeFunc(&v0, &v1, v2.x, v2.y)
+	if area == 0 {
+		return
+	}
+	front := area > 0
+	if !front && r.b.enabled[gg.CULL_FACE] {
+		return
+	}
+	if !front {
+		v1, v2 = v2, v1
+		area = -area
+	}
+	minX := int(math.Floor(float64(min3(v0.x, v1.x, v2.x))))
+	maxX := int(math.Ceil(float64(max3(v0.x, v1.x, v2.x))))
+	minY := int(math.Floor(float64(min3(v0.y, v1.y, v2.y))))
+	maxY := int(math.Ceil(float64(max3(v0.y, v1.y, v2.y))))
+	minX, minY, maxX, maxY = intersect(minX, minY, maxX, maxY, [4]int{
+		r.rect[0], r.rect[1], r.rect[2] - r.rect[0], r.rect[3] - r.rect[1],
+	})
+	tl0, tl1, tl2 := topLeft(&v1, &v2), topLeft(&v2, &v0), topLeft(&v0, &v1)
+	for py := minY; py < maxY; py++ {
+		cy := float32(py) + 0.5
+		for px := minX; px < maxX; px++ {
+			cx := float32(px) + 0.5
+			e0 := edgeFunc(&v1, &v2, cx, cy)
+			e1 := edgeFunc(&v2, &v0, cx, cy)
+			e2 := edgeFunc(&v0, &v1, cx, cy)
+			if !inside(e0, tl0) || !inside(e1, tl1) || !inside(e2, tl2) {
+				continue
+			}
+			b0, b1, b2 := e0/area, e1/area, e2/area
+			z := b0*v0.z + b1*v1.z + b2*v2.z
+			q0, q1, q2 := b0*v0.invW, b1*v1.invW, b2*v2.invW
+			w := q0 + q1 + q2
+			for k := range r.vary {
+				r.vary[k] = (q0*v0.vary[k] + q1*v1.vary[k] + q2*v2.vary[k]) / w
+			}
+			r.fragment(px, py, z, w, front, [2]float32{})
+		}
+	}
+}
+
+func inside(e float32, topLeft bool) bool {
+	return e > 0 || (e == 0 && topLeft)
+}
+
+func (r *rasterizer) line(a, b *clipVert) {
+	for _, plane := range nearPlanes {
+		da, db := plane(a.pos), plane(b.pos)
+		switch {
+		case da < 0 && db < 0:
+			return
+		case da < 0:
+			a = lerpVert(a, b, da/(da-db))
+		case db < 0:
+			b = lerpVert(a, b, da/(da-db))
+		}
+	}
+	v0, v1 := r.toWindow(a), r.toWindow(b)
+	dx, dy := v1.x-v0.x, v1.y-v0.y
+	n := int(math.Ceil(math.Max(math.Abs(float64(dx)), math.Abs(float64(dy)))))
+	if n == 0 {
+		n = 1
+	}
+	for i := 0; i < n; i++ {
+		t := (float32(i) + 0.5) / float32(n)
+		px := int(math.Floor(float64(v0.x + dx*t)))
+		py := int(math.Floor(float64(v0.y + dy*t)))
+		if !r.contains(px, py) {
+			continue
+		}
+		q0, q1 := (1-t)*v0.invW, t*v1.invW
+		w := q0 + q1
+		for k := range r.vary {
+			r.vary[k] = (q0*v0.vary[k] + q1*v1.vary[k]) / w
+		}
+		r.fragment(px, py, v0.z+(v1.z-v0.z)*t, w, true, [2]float32{})
+	}
+}
+
+func (r *rasterizer) point(v *clipVert) {
+	p := v.pos
+	if p[3] <= 0 {
+		return
+	}
+	for i := 0; i < 3; i++ {
+		if p[i] < -p[3] || p[i] > p[3] {
+			return
+		}
+	}
+	wv := r.toWindow(v)
+	size := v.psize
+	if size < 1 {
+		size = 1
+	}
+	left, bottom := wv.x-size/2, wv.y-size/2
+	copy(r.vary, v.vary)
+	for py := int(math.Floor(float64(bottom + 0.5))); float32(py)+0.5 < bottom+size; py++ {
+		for px := int(math.Floor(float64(left + 0.5))); float32(px)+0.5 < left+size; px++ {
+			if !r.contains(px, py) {
+				continue
+			}
+			pc := [2]float32{
+				(float32(px) + 0.5 - left) / size,
+				1 - (float32(py)+0.5-bottom)/size,
+			}
+			r.fragment(px, py, wv.z, wv.invW, true, pc)
+		}
+	}
+}
+
+func (r *rasterizer) contains(px, py int) bool {
+	return px >= r.rect[0] && py >= r.rect[1] && px < r.rect[2] && py < r.rect[3]
+}
+
+// fragment shades the fragment at pixel (px, py) using the interpolated
+// varyings in r.vary, then applies the depth test and blending.
+func (r *rasterizer) fragment(px, py int, z, w float32, front bool, pointCoord [2]float32) {
+	if z < 0 || z > 1 {
+		return
+	}
+	f := r.frag
+	f.FragCoord = [4]float32{float32(px) + 0.5, float32(py) + 0.5, z, w}
+	f.FrontFacing = front
+	f.PointCoord = pointCoord
+	f.Color = [4]float32{}
+	f.Discard = false
+	f.varyings = r.vary
+	r.p.ffn(f)
+	if f.Discard {
+		return
+	}
+
+	b, t := r.b, r.t
+	i := py*t.width + px
+	if b.enabled[gg.DEPTH_TEST] && t.depth != nil {
+		if !depthPass(b.depthFunc, z, t.depth[i]) {
+			return
+		}
+		t.depth[i] = z
+	}
+	if t.color == nil {
+		return
+	}
+	dst := t.color[4*i : 4*i+4]
+	src := f.Color
+	for k := range src {
+		src[k] = clamp01(src[k])
+	}
+	if b.enabled[gg.BLEND] {
+		d := [4]float32{
+			float32(dst[0]) / 255,
+			float32(dst[1]) / 255,
+			float32(dst[2]) / 255,
+			float32(dst[3]) / 255,
+		}
+		sf := blendFactor(b.sfactor, src, d)
+		df := blendFactor(b.dfactor, src, d)
+		for k := range src {
+			src[k] = src[k]*sf[k] + d[k]*df[k]
+		}
+	}
+	for k := range src {
+		dst[k] = toByte(src[k])
+	}
+}
+
+func depthPass(f gg.Enum, z, stored float32) bool {
+	switch f {
+	case gg.NEVER:
+		return false
+	case gg.LESS:
+		return z < stored
+	case gg.EQUAL:
+		return z == stored
+	case gg.LEQUAL:
+		return z <= stored
+	case gg.GREATER:
+		return z > stored
+	case gg.NOTEQUAL:
+		return z != stored
+	case gg.GEQUAL:
+		return z >= stored
+	}
+	return true
+}
+
+func blendFactor(factor gg.Enum, src, dst [4]float32) [4]float32 {
+	splat := func(f float32) [4]float32 { return [4]float32{f, f, f, f} }
+	switch factor {
+	case gg.ZERO:
+		return splat(0)
+	case gg.ONE:
+		return splat(1)
+	case gg.SRC_COLOR:
+		return src
+	case gg.ONE_MINUS_SRC_COLOR:
+		return [4]float32{1 - src[0], 1 - src[1], 1 - src[2], 1 - src[3]}
+	case gg.DST_COLOR:
+		return dst
+	case gg.ONE_MINUS_DST_COLOR:
+		return [4]float32{1 - dst[0], 1 - dst[1], 1 - dst[2], 1 - dst[3]}
+	case gg.SRC_ALPHA:
+		return splat(src[3])
+	case gg.ONE_MINUS_SRC_ALPHA:
+		return splat(1 - src[3])
+	case gg.DST_ALPHA:
+		return splat(dst[3])
+	case gg.ONE_MINUS_DST_ALPHA:
+		return splat(1 - dst[3])
+	case gg.SRC_ALPHA_SATURATE:
+		f := src[3]
+		if 1-dst[3] < f {
+			f = 1 - dst[3]
+		}
+		return [4]float32{f, f, f, 1}
+	}
+	return splat(1)
+}
+
+func min3(a, b, c float32) float32 {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+func max3(a, b, c float32) float32 {
+	if b > a {
+		a = b
+	}
+	if c > a {
+		a = c
+	}
+	return a
+}
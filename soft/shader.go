@@ -0,0 +1,302 @@
+package gg_soft
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dmac/gg"
+)
+
+// A VertexFunc implements a vertex shader. It reads v's uniforms and
+// attributes and must set v.Position. It may set varyings with SetVarying
+// and v.PointSize.
+type VertexFunc func(v *Vertex)
+
+// A FragmentFunc implements a fragment shader. It reads f's uniforms and
+// interpolated varyings and sets f.Color, or sets f.Discard to drop the
+// fragment.
+type FragmentFunc func(f *Fragment)
+
+var registry = struct {
+	sync.Mutex
+	vertex   map[string]VertexFunc
+	fragment map[string]FragmentFunc
+}{
+	vertex:   make(map[string]VertexFunc),
+	fragment: make(map[string]FragmentFunc),
+}
+
+// RegisterVertexShader registers fn as the implementation of the vertex
+// shader with the given GLSL source. CreateShader looks up shaders by their
+// source, ignoring leading and trailing whitespace.
+//
+// The GLSL source is still parsed for its uniform, attribute and varying
+// declarations, which determine the names and types visible to fn.
+func RegisterVertexShader(src string, fn VertexFunc) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.vertex[strings.TrimSpace(src)] = fn
+}
+
+// RegisterFragmentShader registers fn as the implementation of the fragment
+// shader with the given GLSL source. See RegisterVertexShader.
+func RegisterFragmentShader(src string, fn FragmentFunc) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.fragment[strings.TrimSpace(src)] = fn
+}
+
+type shader struct {
+	typ   gg.Enum
+	vfn   VertexFunc
+	ffn   FragmentFunc
+	decls []decl
+}
+
+func newShader(src string, typ gg.Enum) (*shader, error) {
+	s := &shader{typ: typ}
+	key := strings.TrimSpace(src)
+	registry.Lock()
+	switch typ {
+	case gg.VERTEX_SHADER:
+		s.vfn = registry.vertex[key]
+	case gg.FRAGMENT_SHADER:
+		s.ffn = registry.fragment[key]
+	default:
+		registry.Unlock()
+		return nil, fmt.Errorf("gg: compile shader: invalid shader type 0x%x", uint32(typ))
+	}
+	registry.Unlock()
+	if s.vfn == nil && s.ffn == nil {
+		return nil, gg.NewShaderError(typ, []byte(src), "error: no Go function registered for this source")
+	}
+	decls, err := parseDecls(src)
+	if err != nil {
+		return nil, gg.NewShaderError(typ, []byte(src), "error: "+err.Error())
+	}
+	s.decls = decls
+	return s, nil
+}
+
+// glslType describes a GLSL variable type.
+type glslType struct {
+	enum       gg.Enum
+	components int
+	sampler    bool
+}
+
+var glslTypes = map[string]glslType{
+	"float":       {gg.FLOAT, 1, false},
+	"vec2":        {gg.FLOAT_VEC2, 2, false},
+	"vec3":        {gg.FLOAT_VEC3, 3, false},
+	"vec4":        {gg.FLOAT_VEC4, 4, false},
+	"int":         {gg.INT, 1, false},
+	"ivec2":       {gg.INT_VEC2, 2, false},
+	"ivec3":       {gg.INT_VEC3, 3, false},
+	"ivec4":       {gg.INT_VEC4, 4, false},
+	"bool":        {gg.BOOL, 1, false},
+	"bvec2":       {gg.BOOL_VEC2, 2, false},
+	"bvec3":       {gg.BOOL_VEC3, 3, false},
+	"bvec4":       {gg.BOOL_VEC4, 4, false},
+	"mat2":        {gg.FLOAT_MAT2, 4, false},
+	"mat3":        {gg.FLOAT_MAT3, 9, false},
+	"mat4":        {gg.FLOAT_MAT4, 16, false},
+	"sampler2D":   {gg.SAMPLER_2D, 1, true},
+	"samplerCube": {gg.SAMPLER_CUBE, 1, true},
+}
+
+// A decl is a uniform, attribute or varying declaration in GLSL source.
+type decl struct {
+	qualifier string
+	name      string
+	typ       glslType
+	size      int // array length, 1 for non-arrays
+	array     bool
+}
+
+var (
+	commentRE = regexp.MustCompile(`(?s)/\*.*?\*/|//[^\n]*`)
+	declRE    = regexp.MustCompile(`(?m)^\s*(uniform|attribute|varying)\s+(?:(?:lowp|mediump|highp)\s+)?(\w+)\s+([^;]+);`)
+	nameRE    = regexp.MustCompile(`^\s*(\w+)\s*(?:\[\s*(\d+)\s*\])?\s*$`)
+)
+
+func parseDecls(src string) ([]decl, error) {
+	src = commentRE.ReplaceAllString(src, "")
+	var decls []decl
+	for _, m := range declRE.FindAllStringSubmatch(src, -1) {
+		typ, ok := glslTypes[m[2]]
+		if !ok {
+			return nil, fmt.Errorf("unsupported %s type %s", m[1], m[2])
+		}
+		for _, n := range strings.Split(m[3], ",") {
+			nm := nameRE.FindStringSubmatch(n)
+			if nm == nil {
+				return nil, fmt.Errorf("cannot parse %s declaration %q", m[1], strings.TrimSpace(n))
+			}
+			d := decl{qualifier: m[1], name: nm[1], typ: typ, size: 1}
+			if nm[2] != "" {
+				d.size, _ = strconv.Atoi(nm[2])
+				d.array = true
+			}
+			decls = append(decls, d)
+		}
+	}
+	return decls, nil
+}
+
+// uniformVar holds the current value of a uniform in a linked program.
+// Integer and boolean values are stored as float32.
+type uniformVar struct {
+	decl
+	data []float32
+}
+
+type uniformLoc struct {
+	v    *uniformVar
+	elem int
+}
+
+// Uniforms gives shader functions access to the uniforms of the current
+// program. Accessing a uniform that the shader source does not declare
+// returns zero values.
+type Uniforms struct {
+	b    *Backend
+	vars map[string]*uniformVar
+}
+
+func (u *Uniforms) get(name string, n int) []float32 {
+	v, ok := u.vars[name]
+	if !ok || len(v.data) < n {
+		return make([]float32, n)
+	}
+	return v.data
+}
+
+// Floats returns all components of the named uniform, including every
+// element of an array uniform.
+func (u *Uniforms) Floats(name string) []float32 {
+	v, ok := u.vars[name]
+	if !ok {
+		return nil
+	}
+	return v.data
+}
+
+func (u *Uniforms) Float(name string) float32 { return u.get(name, 1)[0] }
+
+func (u *Uniforms) Int(name string) int { return int(u.get(name, 1)[0]) }
+
+func (u *Uniforms) Vec2(name string) (v [2]float32) {
+	copy(v[:], u.get(name, 2))
+	return v
+}
+
+func (u *Uniforms) Vec3(name string) (v [3]float32) {
+	copy(v[:], u.get(name, 3))
+	return v
+}
+
+func (u *Uniforms) Vec4(name string) (v [4]float32) {
+	copy(v[:], u.get(name, 4))
+	return v
+}
+
+// Mat4 returns the named mat4 uniform in column-major order.
+func (u *Uniforms) Mat4(name string) (m [16]float32) {
+	copy(m[:], u.get(name, 16))
+	return m
+}
+
+// Texture2D samples the texture bound to the unit selected by the named
+// sampler2D uniform at texture coordinates (s, t).
+func (u *Uniforms) Texture2D(name string, s, t float32) [4]float32 {
+	unit := u.Int(name)
+	if unit < 0 || unit >= len(u.b.units) {
+		return [4]float32{0, 0, 0, 1}
+	}
+	tex := u.b.units[unit]
+	if tex == nil {
+		return [4]float32{0, 0, 0, 1}
+	}
+	return tex.sample(s, t)
+}
+
+// Vertex is the input and output of a VertexFunc invocation.
+type Vertex struct {
+	Uniforms *Uniforms
+
+	// Position is the clip-space position output, gl_Position.
+	Position [4]float32
+	// PointSize is the point size output, gl_PointSize. It defaults to 1.
+	PointSize float32
+
+	attribs  map[string][4]float32
+	varyings []float32
+	layout   map[string]varyingSlot
+}
+
+// Attrib returns the value of the named attribute. Components not supplied
+// by the attribute array are filled from (0, 0, 0, 1).
+func (v *Vertex) Attrib(name string) [4]float32 {
+	a, ok := v.attribs[name]
+	if !ok {
+		return [4]float32{0, 0, 0, 1}
+	}
+	return a
+}
+
+// SetVarying sets the named varying output, which must be declared in the
+// vertex shader source.
+func (v *Vertex) SetVarying(name string, values ...float32) {
+	slot, ok := v.layout[name]
+	if !ok {
+		return
+	}
+	copy(v.varyings[slot.offset:slot.offset+slot.n], values)
+}
+
+// Fragment is the input and output of a FragmentFunc invocation.
+type Fragment struct {
+	Uniforms *Uniforms
+
+	// FragCoord is the window-relative fragment coordinate, gl_FragCoord.
+	FragCoord [4]float32
+	// FrontFacing reports whether the fragment belongs to a front-facing
+	// primitive, gl_FrontFacing.
+	FrontFacing bool
+	// PointCoord is the fragment's position within a point, gl_PointCoord.
+	PointCoord [2]float32
+
+	// Color is the fragment color output, gl_FragColor.
+	Color [4]float32
+	// Discard drops the fragment when set.
+	Discard bool
+
+	varyings []float32
+	layout   map[string]varyingSlot
+}
+
+// Varying returns the interpolated value of the named varying.
+func (f *Fragment) Varying(name string) []float32 {
+	slot, ok := f.layout[name]
+	if !ok {
+		return nil
+	}
+	return f.varyings[slot.offset : slot.offset+slot.n]
+}
+
+type varyingSlot struct {
+	offset, n int
+}
+
+// MulMat4 returns the product of the column-major matrix m and v.
+func MulMat4(m [16]float32, v [4]float32) [4]float32 {
+	var r [4]float32
+	for row := 0; row < 4; row++ {
+		r[row] = m[row]*v[0] + m[4+row]*v[1] + m[8+row]*v[2] + m[12+row]*v[3]
+	}
+	return r
+}
@@ -0,0 +1,957 @@
+// Package gg_soft implements gg.Backend entirely in Go, rasterizing into
+// memory. It needs no GPU or display, which makes it suitable for headless
+// rendering and for testing gg programs.
+//
+// Shaders cannot be compiled from GLSL. Instead, Go functions are registered
+// against shader sources with RegisterVertexShader and
+// RegisterFragmentShader, so programs that create shaders from GLSL source
+// run unmodified once the matching functions are registered. The GLSL
+// source is only parsed for its uniform, attribute and varying declarations.
+//
+// Supported state includes buffers, 2D textures, framebuffers with texture
+// and renderbuffer attachments, blending, depth testing, back-face culling
+// and scissoring. Primitives are rasterized for TRIANGLES, TRIANGLE_STRIP,
+// TRIANGLE_FAN, LINES, LINE_STRIP, LINE_LOOP and POINTS.
+package gg_soft
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/dmac/gg"
+)
+
+// Backend is a software implementation of gg.Backend that renders into an
+// in-memory default framebuffer.
+type Backend struct {
+	width, height int
+	color         []byte    // RGBA8, rows bottom-up
+	depth         []float32 // rows bottom-up
+
+	err error
+
+	enabled    map[gg.Enum]bool
+	depthFunc  gg.Enum
+	sfactor    gg.Enum
+	dfactor    gg.Enum
+	clearColor [4]float32
+	viewport   [4]int
+	scissor    [4]int
+
+	arrayBuffer   *buffer
+	elementBuffer *buffer
+	attribs       [maxVertexAttribs]attribState
+	program       *program
+
+	activeUnit int
+	units      [32]*texture
+
+	framebuffer  *framebuffer
+	renderbuffer *renderbuffer
+
+	objs gg.Table
+}
+
+var _ gg.Backend = (*Backend)(nil)
+
+// New returns a Backend with a default framebuffer of the given size.
+func New(width, height int) *Backend {
+	return &Backend{
+		width:     width,
+		height:    height,
+		color:     make([]byte, 4*width*height),
+		depth:     newDepth(width * height),
+		enabled:   make(map[gg.Enum]bool),
+		depthFunc: gg.LESS,
+		sfactor:   gg.ONE,
+		dfactor:   gg.ZERO,
+		viewport:  [4]int{0, 0, width, height},
+		scissor:   [4]int{0, 0, width, height},
+	}
+}
+
+// NewContext returns a gg.Context that renders with a new Backend with a
+// default framebuffer of the given size. The Backend is returned as well, for
+// reading back the image and recorded errors.
+func NewContext(width, height int) (*gg.Context, *Backend) {
+	b := New(width, height)
+	return gg.NewContext(b), b
+}
+
+func newDepth(n int) []float32 {
+	d := make([]float32, n)
+	for i := range d {
+		d[i] = 1
+	}
+	return d
+}
+
+// Image returns a copy of the default framebuffer with its origin at the top
+// left.
+func (b *Backend) Image() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, b.width, b.height))
+	stride := 4 * b.width
+	for y := 0; y < b.height; y++ {
+		copy(img.Pix[y*img.Stride:], b.color[(b.height-1-y)*stride:(b.height-y)*stride])
+	}
+	return img
+}
+
+// Err returns the first error recorded since the last call to Err, and
+// clears it. Errors are recorded for calls that OpenGL would reject with
+// an error code, such as invalid enums or drawing without a program.
+func (b *Backend) Err() error {
+	err := b.err
+	b.err = nil
+	return err
+}
+
+func (b *Backend) setErr(format string, args ...interface{}) {
+	if b.err == nil {
+		b.err = fmt.Errorf("gg: "+format, args...)
+	}
+}
+
+type buffer struct {
+	data []byte
+}
+
+type attribState struct {
+	enabled    bool
+	buf        *buffer
+	size       int
+	typ        gg.Enum
+	normalized bool
+	stride     int
+	offset     int
+}
+
+type renderbuffer struct {
+	format        gg.Enum
+	width, height int
+	color         []byte
+	depth         []float32
+}
+
+type framebuffer struct {
+	colorTex *texture
+	colorRB  *renderbuffer
+	depthRB  *renderbuffer
+}
+
+// target is a surface that primitives are rasterized into. Either color or
+// depth may be nil.
+type target struct {
+	width, height int
+	color         []byte
+	depth         []float32
+}
+
+// drawTarget returns the surface of the current framebuffer, or an
+// incomplete framebuffer status.
+func (b *Backend) drawTarget() (*target, gg.Enum) {
+	fb := b.framebuffer
+	if fb == nil {
+		return &target{b.width, b.height, b.color, b.depth}, gg.FRAMEBUFFER_COMPLETE
+	}
+	var t target
+	sized := false
+	size := func(w, h int) bool {
+		if !sized {
+			t.width, t.height, sized = w, h, true
+			return true
+		}
+		return t.width == w && t.height == h
+	}
+	switch {
+	case fb.colorTex != nil:
+		if !size(fb.colorTex.width, fb.colorTex.height) {
+			return nil, gg.FRAMEBUFFER_INCOMPLETE_DIMENSIONS
+		}
+		t.color = fb.colorTex.pix
+	case fb.colorRB != nil:
+		if fb.colorRB.color == nil {
+			return nil, gg.FRAMEBUFFER_INCOMPLETE_ATTACHMENT
+		}
+		size(fb.colorRB.width, fb.colorRB.height)
+		t.color = fb.colorRB.color
+	}
+	if fb.depthRB != nil {
+		if fb.depthRB.depth == nil {
+			return nil, gg.FRAMEBUFFER_INCOMPLETE_ATTACHMENT
+		}
+		if !size(fb.depthRB.width, fb.depthRB.height) {
+			return nil, gg.FRAMEBUFFER_INCOMPLETE_DIMENSIONS
+		}
+		t.depth = fb.depthRB.depth
+	}
+	if !sized {
+		return nil, gg.FRAMEBUFFER_INCOMPLETE_MISSING_ATTACHMENT
+	}
+	if t.width == 0 || t.height == 0 {
+		return nil, gg.FRAMEBUFFER_INCOMPLETE_ATTACHMENT
+	}
+	return &t, gg.FRAMEBUFFER_COMPLETE
+}
+
+func (b *Backend) Enable(c gg.Enum) {
+	b.enabled[c] = true
+}
+
+func (b *Backend) Disable(c gg.Enum) {
+	delete(b.enabled, c)
+}
+
+func (b *Backend) DepthFunc(f gg.Enum) {
+	switch f {
+	case gg.NEVER, gg.LESS, gg.EQUAL, gg.LEQUAL, gg.GREATER, gg.NOTEQUAL, gg.GEQUAL, gg.ALWAYS:
+		b.depthFunc = f
+	default:
+		b.setErr("DepthFunc: invalid function 0x%x", uint32(f))
+	}
+}
+
+func (b *Backend) BlendFunc(sfactor, dfactor gg.Enum) {
+	b.sfactor = sfactor
+	b.dfactor = dfactor
+}
+
+func (b *Backend) Clear(mask gg.Enum) {
+	t, status := b.drawTarget()
+	if status != gg.FRAMEBUFFER_COMPLETE {
+		b.setErr("Clear: framebuffer incomplete")
+		return
+	}
+	x0, y0, x1, y1 := 0, 0, t.width, t.height
+	if b.enabled[gg.SCISSOR_TEST] {
+		x0, y0, x1, y1 = intersect(x0, y0, x1, y1, b.scissor)
+	}
+	var c [4]byte
+	for i, v := range b.clearColor {
+		c[i] = toByte(v)
+	}
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			i := y*t.width + x
+			if mask&gg.COLOR_BUFFER_BIT != 0 && t.color != nil {
+				copy(t.color[4*i:4*i+4], c[:])
+			}
+			if mask&gg.DEPTH_BUFFER_BIT != 0 && t.depth != nil {
+				t.depth[i] = 1
+			}
+		}
+	}
+}
+
+func (b *Backend) ClearColor(r, g, bl, a float32) {
+	b.clearColor = [4]float32{clamp01(r), clamp01(g), clamp01(bl), clamp01(a)}
+}
+
+func (b *Backend) Viewport(x, y, width, height int) {
+	b.viewport = [4]int{x, y, width, height}
+}
+
+func (b *Backend) Scissor(x, y, width, height int) {
+	b.scissor = [4]int{x, y, width, height}
+}
+
+func (b *Backend) CreateBuffer() *gg.Buffer {
+	return &gg.Buffer{ID: b.objs.Add(&buffer{})}
+}
+
+func (b *Backend) BindBuffer(typ gg.Enum, buf *gg.Buffer) {
+	var v *buffer
+	if buf != nil {
+		v, _ = b.objs.Get(buf.ID).(*buffer)
+	}
+	switch typ {
+	case gg.ARRAY_BUFFER:
+		b.arrayBuffer = v
+	case gg.ELEMENT_ARRAY_BUFFER:
+		b.elementBuffer = v
+	default:
+		b.setErr("BindBuffer: invalid target 0x%x", uint32(typ))
+	}
+}
+
+func (b *Backend) boundBuffer(typ gg.Enum) *buffer {
+	switch typ {
+	case gg.ARRAY_BUFFER:
+		return b.arrayBuffer
+	case gg.ELEMENT_ARRAY_BUFFER:
+		return b.elementBuffer
+	}
+	return nil
+}
+
+func (b *Backend) BufferData(typ gg.Enum, src []byte, usage gg.Enum) {
+	buf := b.boundBuffer(typ)
+	if buf == nil {
+		b.setErr("BufferData: no buffer bound to 0x%x", uint32(typ))
+		return
+	}
+	buf.data = append([]byte(nil), src...)
+}
+
+func (b *Backend) BufferSubData(typ gg.Enum, offset int, src []byte) {
+	buf := b.boundBuffer(typ)
+	if buf == nil {
+		b.setErr("BufferSubData: no buffer bound to 0x%x", uint32(typ))
+		return
+	}
+	if offset < 0 || offset+len(src) > len(buf.data) {
+		b.setErr("BufferSubData: range [%d, %d) exceeds buffer size %d", offset, offset+len(src), len(buf.data))
+		return
+	}
+	copy(buf.data[offset:], src)
+}
+
+func (b *Backend) DeleteBuffer(buf *gg.Buffer) {
+	v, _ := b.objs.Get(buf.ID).(*buffer)
+	b.objs.Delete(buf.ID)
+	if b.arrayBuffer == v {
+		b.arrayBuffer = nil
+	}
+	if b.elementBuffer == v {
+		b.elementBuffer = nil
+	}
+}
+
+func (b *Backend) CreateShader(src []byte, typ gg.Enum) (*gg.Shader, error) {
+	s, err := newShader(string(src), typ)
+	if err != nil {
+		return nil, err
+	}
+	return &gg.Shader{ID: b.objs.Add(s)}, nil
+}
+
+// DeleteShader removes s once it is detached from every program.
+func (b *Backend) DeleteShader(s *gg.Shader) {
+	b.objs.Delete(s.ID)
+}
+
+func (b *Backend) AttachShader(p *gg.Program, s *gg.Shader) {
+	pv := b.programOf("AttachShader", p)
+	sv, _ := b.objs.Get(s.ID).(*shader)
+	if pv == nil || sv == nil {
+		return
+	}
+	for _, as := range pv.shaders {
+		if as == sv {
+			b.setErr("AttachShader: shader is already attached")
+			return
+		}
+	}
+	pv.shaders = append(pv.shaders, sv)
+	b.objs.Attach(p.ID, s.ID)
+}
+
+func (b *Backend) DetachShader(p *gg.Program, s *gg.Shader) {
+	pv := b.programOf("DetachShader", p)
+	if pv == nil {
+		return
+	}
+	sv, _ := b.objs.Get(s.ID).(*shader)
+	for i, as := range pv.shaders {
+		if as == sv {
+			pv.shaders = append(pv.shaders[:i], pv.shaders[i+1:]...)
+			b.objs.Detach(p.ID, s.ID)
+			return
+		}
+	}
+	b.setErr("DetachShader: shader is not attached")
+}
+
+// programOf returns the program p refers to, recording an error for call if
+// there is none.
+func (b *Backend) programOf(call string, p *gg.Program) *program {
+	pv, _ := b.objs.Get(p.ID).(*program)
+	if pv == nil {
+		b.setErr("%s: program is not valid on this backend", call)
+	}
+	return pv
+}
+
+func (b *Backend) CreateProgram() *gg.Program {
+	return &gg.Program{ID: b.objs.Add(&program{})}
+}
+
+func (b *Backend) DeleteProgram(p *gg.Program) {
+	if pv, _ := b.objs.Get(p.ID).(*program); pv != nil && b.program == pv {
+		b.program = nil
+	}
+	b.objs.Delete(p.ID)
+}
+
+func (b *Backend) LinkProgram(p *gg.Program) error {
+	pv := b.programOf("LinkProgram", p)
+	if pv == nil {
+		return fmt.Errorf("gg: LinkProgram: unknown program")
+	}
+	return pv.link(b)
+}
+
+func (b *Backend) GetProgramParameter(p *gg.Program, pname gg.Enum) int {
+	pv := b.programOf("GetProgramParameter", p)
+	if pv == nil {
+		return 0
+	}
+	switch pname {
+	case gg.LINK_STATUS:
+		if pv.linked {
+			return 1
+		}
+		return 0
+	case gg.ATTACHED_SHADERS:
+		return len(pv.shaders)
+	case gg.ACTIVE_UNIFORMS:
+		return len(pv.active)
+	case gg.ACTIVE_ATTRIBUTES:
+		return len(pv.attribs)
+	}
+	b.setErr("GetProgramParameter: unsupported parameter 0x%x", uint32(pname))
+	return 0
+}
+
+func (b *Backend) GetActiveUniform(p *gg.Program, index int) gg.ActiveInfo {
+	pv := b.programOf("GetActiveUniform", p)
+	if pv == nil || index < 0 || index >= len(pv.active) {
+		return gg.ActiveInfo{}
+	}
+	return activeInfo(pv.active[index].decl)
+}
+
+func (b *Backend) GetActiveAttrib(p *gg.Program, index int) gg.ActiveInfo {
+	pv := b.programOf("GetActiveAttrib", p)
+	if pv == nil || index < 0 || index >= len(pv.attribs) {
+		return gg.ActiveInfo{}
+	}
+	return activeInfo(pv.attribs[index])
+}
+
+// activeInfo describes d the way OpenGL does, with "[0]" after array names.
+func activeInfo(d decl) gg.ActiveInfo {
+	name := d.name
+	if d.array {
+		name += "[0]"
+	}
+	return gg.ActiveInfo{Name: name, Size: d.size, Type: d.typ.enum}
+}
+
+func (b *Backend) UseProgram(p *gg.Program) {
+	if p == nil {
+		b.program = nil
+		return
+	}
+	pv := b.programOf("UseProgram", p)
+	if pv == nil {
+		return
+	}
+	if !pv.linked {
+		b.setErr("UseProgram: program is not linked")
+		return
+	}
+	b.program = pv
+}
+
+func (b *Backend) GetUniformLocation(p *gg.Program, name string) (*gg.Uniform, error) {
+	pv := b.programOf("GetUniformLocation", p)
+	if pv == nil {
+		return nil, fmt.Errorf("gg: no uniform named %s", name)
+	}
+	loc, ok := pv.uniformLocation(name)
+	if !ok {
+		return nil, fmt.Errorf("gg: no uniform named %s", name)
+	}
+	return &gg.Uniform{ID: b.objs.Named(p.ID, name, loc)}, nil
+}
+
+func (b *Backend) uniform(u *gg.Uniform, n int, values ...float32) {
+	loc, _ := b.objs.Get(u.ID).(*uniformLoc)
+	if loc == nil {
+		b.setErr("uniform location is not valid on this backend")
+		return
+	}
+	if b.program == nil || b.program.uniforms.vars[loc.v.name] != loc.v {
+		b.setErr("uniform %s does not belong to the current program", loc.v.name)
+		return
+	}
+	if err := loc.set(n, values); err != nil {
+		b.setErr("%v", err)
+	}
+}
+
+func ints(values []int32) []float32 {
+	f := make([]float32, len(values))
+	for i, v := range values {
+		f[i] = float32(v)
+	}
+	return f
+}
+
+func (b *Backend) Uniform1f(u *gg.Uniform, v0 float32) { b.uniform(u, 1, v0) }
+
+func (b *Backend) Uniform2f(u *gg.Uniform, v0, v1 float32) { b.uniform(u, 2, v0, v1) }
+
+func (b *Backend) Uniform3f(u *gg.Uniform, v0, v1, v2 float32) { b.uniform(u, 3, v0, v1, v2) }
+
+func (b *Backend) Uniform4f(u *gg.Uniform, v0, v1, v2, v3 float32) {
+	b.uniform(u, 4, v0, v1, v2, v3)
+}
+
+func (b *Backend) Uniform1i(u *gg.Uniform, v0 int) { b.uniform(u, 1, float32(v0)) }
+
+func (b *Backend) Uniform2i(u *gg.Uniform, v0, v1 int) {
+	b.uniform(u, 2, float32(v0), float32(v1))
+}
+
+func (b *Backend) Uniform3i(u *gg.Uniform, v0, v1, v2 int) {
+	b.uniform(u, 3, float32(v0), float32(v1), float32(v2))
+}
+
+func (b *Backend) Uniform4i(u *gg.Uniform, v0, v1, v2, v3 int) {
+	b.uniform(u, 4, float32(v0), float32(v1), float32(v2), float32(v3))
+}
+
+func (b *Backend) Uniform1fv(u *gg.Uniform, values []float32) { b.uniform(u, 1, values...) }
+
+func (b *Backend) Uniform2fv(u *gg.Uniform, values []float32) { b.uniform(u, 2, values...) }
+
+func (b *Backend) Uniform3fv(u *gg.Uniform, values []float32) { b.uniform(u, 3, values...) }
+
+func (b *Backend) Uniform4fv(u *gg.Uniform, values []float32) { b.uniform(u, 4, values...) }
+
+func (b *Backend) Uniform1iv(u *gg.Uniform, values []int32) { b.uniform(u, 1, ints(values)...) }
+
+func (b *Backend) Uniform2iv(u *gg.Uniform, values []int32) { b.uniform(u, 2, ints(values)...) }
+
+func (b *Backend) Uniform3iv(u *gg.Uniform, values []int32) { b.uniform(u, 3, ints(values)...) }
+
+func (b *Backend) Uniform4iv(u *gg.Uniform, values []int32) { b.uniform(u, 4, ints(values)...) }
+
+func (b *Backend) UniformMatrix2fv(u *gg.Uniform, values []float32) { b.uniform(u, 4, values...) }
+
+func (b *Backend) UniformMatrix3fv(u *gg.Uniform, values []float32) { b.uniform(u, 9, values...) }
+
+func (b *Backend) UniformMatrix4fv(u *gg.Uniform, values []float32) { b.uniform(u, 16, values...) }
+
+func (b *Backend) GetAttribLocation(p *gg.Program, name string) (*gg.Attribute, error) {
+	pv := b.programOf("GetAttribLocation", p)
+	if pv == nil {
+		return nil, fmt.Errorf("gg: no attribute named %s", name)
+	}
+	loc, ok := pv.attribLocation(name)
+	if !ok {
+		return nil, fmt.Errorf("gg: no attribute named %s", name)
+	}
+	return &gg.Attribute{Location: loc}, nil
+}
+
+func (b *Backend) EnableVertexAttribArray(a *gg.Attribute) {
+	if a.Location < 0 || a.Location >= len(b.attribs) {
+		b.setErr("EnableVertexAttribArray: invalid location %d", a.Location)
+		return
+	}
+	b.attribs[a.Location].enabled = true
+}
+
+func (b *Backend) VertexAttribPointer(a *gg.Attribute, size int, typ gg.Enum, normalized bool, stride, offset int) {
+	if a.Location < 0 || a.Location >= len(b.attribs) {
+		b.setErr("VertexAttribPointer: invalid location %d", a.Location)
+		return
+	}
+	if size < 1 || size > 4 || stride < 0 || offset < 0 {
+		b.setErr("VertexAttribPointer: invalid size, stride or offset")
+		return
+	}
+	if typeSize(typ) == 0 {
+		b.setErr("VertexAttribPointer: invalid type 0x%x", uint32(typ))
+		return
+	}
+	if b.arrayBuffer == nil {
+		b.setErr("VertexAttribPointer: no buffer bound to ARRAY_BUFFER")
+		return
+	}
+	st := &b.attribs[a.Location]
+	st.buf = b.arrayBuffer
+	st.size = size
+	st.typ = typ
+	st.normalized = normalized
+	st.stride = stride
+	st.offset = offset
+}
+
+func typeSize(typ gg.Enum) int {
+	switch typ {
+	case gg.BYTE, gg.UNSIGNED_BYTE:
+		return 1
+	case gg.SHORT, gg.UNSIGNED_SHORT:
+		return 2
+	case gg.INT, gg.UNSIGNED_INT, gg.FLOAT:
+		return 4
+	}
+	return 0
+}
+
+// fetch returns the value of attribute a for vertex i.
+func (a *attribState) fetch(i int) ([4]float32, bool) {
+	v := [4]float32{0, 0, 0, 1}
+	if !a.enabled {
+		return v, true
+	}
+	n := typeSize(a.typ)
+	stride := a.stride
+	if stride == 0 {
+		stride = n * a.size
+	}
+	off := a.offset + i*stride
+	if a.buf == nil || off < 0 || off+n*a.size > len(a.buf.data) {
+		return v, false
+	}
+	d := a.buf.data[off:]
+	for c := 0; c < a.size; c++ {
+		e := d[c*n:]
+		var f float32
+		switch a.typ {
+		case gg.FLOAT:
+			f = math.Float32frombits(binary.LittleEndian.Uint32(e))
+		case gg.BYTE:
+			f = float32(int8(e[0]))
+			if a.normalized {
+				f = float32(math.Max(float64(f)/127, -1))
+			}
+		case gg.UNSIGNED_BYTE:
+			f = float32(e[0])
+			if a.normalized {
+				f /= 255
+			}
+		case gg.SHORT:
+			f = float32(int16(binary.LittleEndian.Uint16(e)))
+			if a.normalized {
+				f = float32(math.Max(float64(f)/32767, -1))
+			}
+		case gg.UNSIGNED_SHORT:
+			f = float32(binary.LittleEndian.Uint16(e))
+			if a.normalized {
+				f /= 65535
+			}
+		case gg.INT:
+			f = float32(int32(binary.LittleEndian.Uint32(e)))
+			if a.normalized {
+				f = float32(math.Max(float64(f)/math.MaxInt32, -1))
+			}
+		case gg.UNSIGNED_INT:
+			f = float32(binary.LittleEndian.Uint32(e))
+			if a.normalized {
+				f = float32(float64(f) / math.MaxUint32)
+			}
+		}
+		v[c] = f
+	}
+	return v, true
+}
+
+func (b *Backend) CreateTexture() *gg.Texture {
+	return &gg.Texture{ID: b.objs.Add(newTexture())}
+}
+
+func (b *Backend) ActiveTexture(tex gg.Enum) {
+	unit := int(tex) - gg.TEXTURE0
+	if unit < 0 || unit >= len(b.units) {
+		b.setErr("ActiveTexture: invalid texture unit 0x%x", uint32(tex))
+		return
+	}
+	b.activeUnit = unit
+}
+
+func (b *Backend) BindTexture(target gg.Enum, tex *gg.Texture) {
+	if target != gg.TEXTURE_2D {
+		b.setErr("BindTexture: unsupported target 0x%x", uint32(target))
+		return
+	}
+	var v *texture
+	if tex != nil {
+		v, _ = b.objs.Get(tex.ID).(*texture)
+	}
+	b.units[b.activeUnit] = v
+}
+
+// DeleteTexture unbinds tex from every texture unit and, as in OpenGL,
+// detaches it from the bound framebuffer.
+func (b *Backend) DeleteTexture(tex *gg.Texture) {
+	v, _ := b.objs.Get(tex.ID).(*texture)
+	b.objs.Delete(tex.ID)
+	if v == nil {
+		return
+	}
+	for i, t := range b.units {
+		if t == v {
+			b.units[i] = nil
+		}
+	}
+	if fb := b.framebuffer; fb != nil && fb.colorTex == v {
+		fb.colorTex = nil
+	}
+}
+
+func (b *Backend) TexImage2D(
+	target gg.Enum, level int, internalFormat gg.Enum,
+	width, height, border int,
+	format, typ gg.Enum,
+	data []byte,
+) {
+	if target != gg.TEXTURE_2D {
+		b.setErr("TexImage2D: unsupported target 0x%x", uint32(target))
+		return
+	}
+	if level != 0 {
+		// Mipmaps are never sampled.
+		return
+	}
+	t := b.units[b.activeUnit]
+	if t == nil {
+		b.setErr("TexImage2D: no texture bound")
+		return
+	}
+	if err := t.upload(width, height, internalFormat, format, typ, data); err != nil {
+		b.setErr("%v", err)
+	}
+}
+
+func (b *Backend) TexParameteri(target gg.Enum, pname gg.Enum, param gg.Enum) {
+	t := b.units[b.activeUnit]
+	if target != gg.TEXTURE_2D || t == nil {
+		b.setErr("TexParameteri: no texture bound to 0x%x", uint32(target))
+		return
+	}
+	if err := t.setParameter(pname, param); err != nil {
+		b.setErr("%v", err)
+	}
+}
+
+func (b *Backend) CreateFramebuffer() *gg.Framebuffer {
+	return &gg.Framebuffer{ID: b.objs.Add(&framebuffer{})}
+}
+
+func (b *Backend) BindFramebuffer(target gg.Enum, fb *gg.Framebuffer) {
+	if target != gg.FRAMEBUFFER {
+		b.setErr("BindFramebuffer: invalid target 0x%x", uint32(target))
+		return
+	}
+	b.framebuffer = nil
+	if fb != nil {
+		b.framebuffer, _ = b.objs.Get(fb.ID).(*framebuffer)
+	}
+}
+
+func (b *Backend) DeleteFramebuffer(fb *gg.Framebuffer) {
+	if v, _ := b.objs.Get(fb.ID).(*framebuffer); v != nil && b.framebuffer == v {
+		b.framebuffer = nil
+	}
+	b.objs.Delete(fb.ID)
+}
+
+func (b *Backend) FramebufferTexture2D(
+	target, attachment, textarget gg.Enum,
+	tex *gg.Texture, level int,
+) {
+	fb := b.framebuffer
+	if target != gg.FRAMEBUFFER || fb == nil {
+		b.setErr("FramebufferTexture2D: no framebuffer bound")
+		return
+	}
+	if attachment != gg.COLOR_ATTACHMENT0 || textarget != gg.TEXTURE_2D || level != 0 {
+		b.setErr("FramebufferTexture2D: only level 0 of a 2D texture can be attached to COLOR_ATTACHMENT0")
+		return
+	}
+	fb.colorRB = nil
+	fb.colorTex = nil
+	if tex != nil {
+		fb.colorTex, _ = b.objs.Get(tex.ID).(*texture)
+	}
+}
+
+func (b *Backend) FramebufferRenderbuffer(
+	target, attachment, renderbuffertarget gg.Enum,
+	rb *gg.Renderbuffer,
+) {
+	fb := b.framebuffer
+	if target != gg.FRAMEBUFFER || fb == nil {
+		b.setErr("FramebufferRenderbuffer: no framebuffer bound")
+		return
+	}
+	var v *renderbuffer
+	if rb != nil {
+		v, _ = b.objs.Get(rb.ID).(*renderbuffer)
+	}
+	switch attachment {
+	case gg.COLOR_ATTACHMENT0:
+		fb.colorTex = nil
+		fb.colorRB = v
+	case gg.DEPTH_ATTACHMENT, gg.DEPTH_STENCIL_ATTACHMENT:
+		fb.depthRB = v
+	case gg.STENCIL_ATTACHMENT:
+		// There is no stencil buffer.
+	default:
+		b.setErr("FramebufferRenderbuffer: invalid attachment 0x%x", uint32(attachment))
+	}
+}
+
+func (b *Backend) CheckFramebufferStatus(target gg.Enum) gg.Enum {
+	_, status := b.drawTarget()
+	return status
+}
+
+func (b *Backend) CreateRenderbuffer() *gg.Renderbuffer {
+	return &gg.Renderbuffer{ID: b.objs.Add(&renderbuffer{})}
+}
+
+func (b *Backend) BindRenderbuffer(target gg.Enum, rb *gg.Renderbuffer) {
+	b.renderbuffer = nil
+	if rb != nil {
+		b.renderbuffer, _ = b.objs.Get(rb.ID).(*renderbuffer)
+	}
+}
+
+// DeleteRenderbuffer unbinds rb and, like DeleteTexture, detaches it from
+// the bound framebuffer.
+func (b *Backend) DeleteRenderbuffer(rb *gg.Renderbuffer) {
+	v, _ := b.objs.Get(rb.ID).(*renderbuffer)
+	b.objs.Delete(rb.ID)
+	if v == nil {
+		return
+	}
+	if b.renderbuffer == v {
+		b.renderbuffer = nil
+	}
+	if fb := b.framebuffer; fb != nil {
+		if fb.colorRB == v {
+			fb.colorRB = nil
+		}
+		if fb.depthRB == v {
+			fb.depthRB = nil
+		}
+	}
+}
+
+func (b *Backend) RenderbufferStorage(target, internalFormat gg.Enum, width, height int) {
+	rb := b.renderbuffer
+	if target != gg.RENDERBUFFER || rb == nil {
+		b.setErr("RenderbufferStorage: no renderbuffer bound")
+		return
+	}
+	rb.format, rb.width, rb.height = internalFormat, width, height
+	rb.color, rb.depth = nil, nil
+	switch internalFormat {
+	case gg.RGBA4, gg.RGB565, gg.RGB5_A1, gg.RGBA8, gg.RGBA:
+		rb.color = make([]byte, 4*width*height)
+	case gg.DEPTH_COMPONENT16, gg.DEPTH_COMPONENT24, gg.DEPTH_COMPONENT32F,
+		gg.DEPTH_STENCIL, gg.DEPTH24_STENCIL8:
+		rb.depth = newDepth(width * height)
+	case gg.STENCIL_INDEX8:
+	default:
+		b.setErr("RenderbufferStorage: unsupported format 0x%x", uint32(internalFormat))
+	}
+}
+
+func (b *Backend) DrawArrays(mode gg.Enum, first, count int) {
+	if first < 0 || count < 0 {
+		b.setErr("DrawArrays: negative first or count")
+		return
+	}
+	indices := make([]int, count)
+	for i := range indices {
+		indices[i] = first + i
+	}
+	b.draw(mode, indices)
+}
+
+func (b *Backend) DrawElements(mode gg.Enum, count int, typ gg.Enum, offset int) error {
+	n := typeSize(typ)
+	if n == 0 || typ == gg.BYTE || typ == gg.SHORT || typ == gg.INT || typ == gg.FLOAT {
+		return fmt.Errorf("gg: invalid index type 0x%x", uint32(typ))
+	}
+	if b.elementBuffer == nil {
+		return fmt.Errorf("gg: DrawElements: no buffer bound to ELEMENT_ARRAY_BUFFER")
+	}
+	data := b.elementBuffer.data
+	if offset < 0 || count < 0 || offset+count*n > len(data) {
+		return fmt.Errorf("gg: DrawElements: %d indices at offset %d exceed buffer size %d", count, offset, len(data))
+	}
+	indices := make([]int, count)
+	for i := range indices {
+		e := data[offset+i*n:]
+		switch typ {
+		case gg.UNSIGNED_BYTE:
+			indices[i] = int(e[0])
+		case gg.UNSIGNED_SHORT:
+			indices[i] = int(binary.LittleEndian.Uint16(e))
+		case gg.UNSIGNED_INT:
+			indices[i] = int(binary.LittleEndian.Uint32(e))
+		}
+	}
+	b.draw(mode, indices)
+	return nil
+}
+
+func (b *Backend) ReadPixels(x, y, width, height int, format, typ gg.Enum, dst []byte) {
+	if format != gg.RGBA || typ != gg.UNSIGNED_BYTE {
+		b.setErr("ReadPixels: only RGBA and UNSIGNED_BYTE are supported")
+		return
+	}
+	t, status := b.drawTarget()
+	if status != gg.FRAMEBUFFER_COMPLETE || t.color == nil {
+		b.setErr("ReadPixels: framebuffer has no color attachment")
+		return
+	}
+	for row := 0; row < height; row++ {
+		for col := 0; col < width; col++ {
+			sx, sy := x+col, y+row
+			if sx < 0 || sy < 0 || sx >= t.width || sy >= t.height {
+				continue
+			}
+			i := 4 * (sy*t.width + sx)
+			copy(dst[4*(row*width+col):], t.color[i:i+4])
+		}
+	}
+}
+
+// intersect clips the rectangle [x0, x1) x [y0, y1) to r, which is given as
+// x, y, width, height.
+func intersect(x0, y0, x1, y1 int, r [4]int) (int, int, int, int) {
+	if x0 < r[0] {
+		x0 = r[0]
+	}
+	if y0 < r[1] {
+		y0 = r[1]
+	}
+	if x1 > r[0]+r[2] {
+		x1 = r[0] + r[2]
+	}
+	if y1 > r[1]+r[3] {
+		y1 = r[1] + r[3]
+	}
+	if x1 < x0 {
+		x1 = x0
+	}
+	if y1 < y0 {
+		y1 = y0
+	}
+	return x0, y0, x1, y1
+}
+
+func clamp01(f float32) float32 {
+	if f < 0 {
+		return 0
+	}
+	if f > 1 {
+		return 1
+	}
+	return f
+}
+
+func toByte(f float32) byte {
+	return byte(clamp01(f)*255 + 0.5)
+}
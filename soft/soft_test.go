@@ -0,0 +1,394 @@
+package gg_soft_test
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/dmac/gg"
+	"github.com/dmac/gg/soft"
+)
+
+const (
+	vertexSrc = `
+attribute vec3 position;
+void main() { gl_Position = vec4(position, 1.0); }
+`
+	fragmentSrc = `
+uniform vec4 color;
+void main() { gl_FragColor = color; }
+`
+)
+
+func init() {
+	gg_soft.RegisterVertexShader(vertexSrc, func(v *gg_soft.Vertex) {
+		p := v.Attrib("position")
+		v.Position = [4]float32{p[0], p[1], p[2], 1}
+	})
+	gg_soft.RegisterFragmentShader(fragmentSrc, func(f *gg_soft.Fragment) {
+		f.Color = f.Uniforms.Vec4("color")
+	})
+}
+
+// scene is a linked program that fills primitives with the color uniform.
+type scene struct {
+	b        *gg_soft.Backend
+	program  *gg.Program
+	position *gg.Attribute
+	color    *gg.Uniform
+}
+
+func newScene(t *testing.T, width, height int) *scene {
+	t.Helper()
+	b := gg_soft.New(width, height)
+	vs, err := b.CreateShader([]byte(vertexSrc), gg.VERTEX_SHADER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs, err := b.CreateShader([]byte(fragmentSrc), gg.FRAGMENT_SHADER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := b.CreateProgram()
+	b.AttachShader(p, vs)
+	b.AttachShader(p, fs)
+	if err := b.LinkProgram(p); err != nil {
+		t.Fatal(err)
+	}
+	b.UseProgram(p)
+	s := &scene{b: b, program: p}
+	if s.position, err = b.GetAttribLocation(p, "position"); err != nil {
+		t.Fatal(err)
+	}
+	if s.color, err = b.GetUniformLocation(p, "color"); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// quad draws a rectangle from (x0, y0) to (x1, y1) in normalized device
+// coordinates at depth z, filled with c.
+func (s *scene) quad(x0, y0, x1, y1, z float32, c [4]float32) {
+	b := s.b
+	buf := b.CreateBuffer()
+	b.BindBuffer(gg.ARRAY_BUFFER, buf)
+	b.BufferData(gg.ARRAY_BUFFER, floatBytes(
+		x0, y0, z, x1, y0, z, x1, y1, z,
+		x0, y0, z, x1, y1, z, x0, y1, z,
+	), gg.STATIC_DRAW)
+	b.EnableVertexAttribArray(s.position)
+	b.VertexAttribPointer(s.position, 3, gg.FLOAT, false, 0, 0)
+	b.Uniform4f(s.color, c[0], c[1], c[2], c[3])
+	b.DrawArrays(gg.TRIANGLES, 0, 6)
+}
+
+func floatBytes(values ...float32) []byte {
+	data := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(v))
+	}
+	return data
+}
+
+var (
+	red   = [4]float32{1, 0, 0, 1}
+	green = [4]float32{0, 1, 0, 1}
+)
+
+func TestDraw(t *testing.T) {
+	tests := []struct {
+		name string
+		draw func(s *scene)
+		want map[image.Point]color.RGBA // pixels of Image, origin at top left
+	}{
+		{
+			name: "clear",
+			draw: func(s *scene) {
+				s.b.ClearColor(0, 0, 1, 1)
+				s.b.Clear(gg.COLOR_BUFFER_BIT)
+			},
+			want: map[image.Point]color.RGBA{
+				{0, 0}: {0, 0, 255, 255},
+				{3, 3}: {0, 0, 255, 255},
+			},
+		},
+		{
+			name: "full screen",
+			draw: func(s *scene) { s.quad(-1, -1, 1, 1, 0, red) },
+			want: map[image.Point]color.RGBA{
+				{0, 0}: {255, 0, 0, 255},
+				{3, 3}: {255, 0, 0, 255},
+			},
+		},
+		{
+			name: "bottom left quarter",
+			draw: func(s *scene) { s.quad(-1, -1, 0, 0, 0, red) },
+			want: map[image.Point]color.RGBA{
+				{0, 3}: {255, 0, 0, 255},
+				{1, 2}: {255, 0, 0, 255},
+				{2, 2}: {},
+				{0, 0}: {},
+			},
+		},
+		{
+			name: "viewport",
+			draw: func(s *scene) {
+				s.b.Viewport(2, 2, 2, 2)
+				s.quad(-1, -1, 1, 1, 0, red)
+			},
+			want: map[image.Point]color.RGBA{
+				{3, 0}: {255, 0, 0, 255},
+				{2, 1}: {255, 0, 0, 255},
+				{1, 1}: {},
+				{3, 3}: {},
+			},
+		},
+		{
+			name: "scissor",
+			draw: func(s *scene) {
+				s.b.Enable(gg.SCISSOR_TEST)
+				s.b.Scissor(0, 0, 2, 4)
+				s.b.ClearColor(0, 1, 0, 1)
+				s.b.Clear(gg.COLOR_BUFFER_BIT)
+			},
+			want: map[image.Point]color.RGBA{
+				{1, 0}: {0, 255, 0, 255},
+				{2, 0}: {},
+			},
+		},
+		{
+			name: "depth test keeps the nearer quad",
+			draw: func(s *scene) {
+				s.b.Enable(gg.DEPTH_TEST)
+				s.quad(-1, -1, 1, 1, 0, red)
+				s.quad(-1, -1, 1, 1, 0.5, green)
+			},
+			want: map[image.Point]color.RGBA{
+				{0, 0}: {255, 0, 0, 255},
+			},
+		},
+		{
+			name: "without depth test the last quad wins",
+			draw: func(s *scene) {
+				s.quad(-1, -1, 1, 1, 0, red)
+				s.quad(-1, -1, 1, 1, 0.5, green)
+			},
+			want: map[image.Point]color.RGBA{
+				{0, 0}: {0, 255, 0, 255},
+			},
+		},
+		{
+			name: "blending",
+			draw: func(s *scene) {
+				s.b.ClearColor(0, 0, 1, 1)
+				s.b.Clear(gg.COLOR_BUFFER_BIT)
+				s.b.Enable(gg.BLEND)
+				s.b.BlendFunc(gg.SRC_ALPHA, gg.ONE_MINUS_SRC_ALPHA)
+				s.quad(-1, -1, 1, 1, 0, [4]float32{1, 0, 0, 0.5})
+			},
+			want: map[image.Point]color.RGBA{
+				{0, 0}: {128, 0, 128, 191},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newScene(t, 4, 4)
+			tt.draw(s)
+			if err := s.b.Err(); err != nil {
+				t.Fatal(err)
+			}
+			img := s.b.Image()
+			for p, want := range tt.want {
+				if got := img.RGBAAt(p.X, p.Y); got != want {
+					t.Errorf("pixel %v = %v, want %v", p, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReadPixels(t *testing.T) {
+	s := newScene(t, 4, 4)
+	s.quad(-1, -1, 0, 0, 0, red)
+	dst := make([]byte, 4*2)
+	s.b.ReadPixels(1, 0, 2, 1, gg.RGBA, gg.UNSIGNED_BYTE, dst)
+	if err := s.b.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{255, 0, 0, 255, 0, 0, 0, 0}
+	if string(dst) != string(want) {
+		t.Errorf("ReadPixels = %v, want %v", dst, want)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		call func(s *scene)
+		want string
+	}{
+		{
+			name: "no program",
+			call: func(s *scene) {
+				s.b.UseProgram(nil)
+				s.b.DrawArrays(gg.TRIANGLES, 0, 3)
+			},
+			want: "no program in use",
+		},
+		{
+			name: "enabled attribute without a buffer",
+			call: func(s *scene) {
+				s.b.EnableVertexAttribArray(s.position)
+				s.b.DrawArrays(gg.TRIANGLES, 0, 3)
+			},
+			want: "attribute position is enabled with no buffer",
+		},
+		{
+			name: "vertex out of range",
+			call: func(s *scene) {
+				s.quad(-1, -1, 1, 1, 0, red)
+				s.b.DrawArrays(gg.TRIANGLES, 3, 6)
+			},
+			want: "vertex 6 is out of range",
+		},
+		{
+			name: "VertexAttribPointer without a buffer",
+			call: func(s *scene) {
+				s.b.VertexAttribPointer(s.position, 3, gg.FLOAT, false, 0, 0)
+			},
+			want: "no buffer bound to ARRAY_BUFFER",
+		},
+		{
+			name: "invalid depth function",
+			call: func(s *scene) { s.b.DepthFunc(gg.TEXTURE_2D) },
+			want: "DepthFunc: invalid function",
+		},
+		{
+			name: "shader attached twice",
+			call: func(s *scene) {
+				vs, _ := s.b.CreateShader([]byte(vertexSrc), gg.VERTEX_SHADER)
+				s.b.AttachShader(s.program, vs)
+				s.b.AttachShader(s.program, vs)
+			},
+			want: "already attached",
+		},
+		{
+			name: "detach unattached shader",
+			call: func(s *scene) {
+				vs, _ := s.b.CreateShader([]byte(vertexSrc), gg.VERTEX_SHADER)
+				s.b.DetachShader(s.program, vs)
+			},
+			want: "not attached",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newScene(t, 4, 4)
+			tt.call(s)
+			if err := s.b.Err(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Err() = %v, want an error containing %q", err, tt.want)
+			}
+			if err := s.b.Err(); err != nil {
+				t.Errorf("second Err() = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestCreateShaderUnregistered(t *testing.T) {
+	b := gg_soft.New(1, 1)
+	_, err := b.CreateShader([]byte("void main() {}"), gg.FRAGMENT_SHADER)
+	if _, ok := err.(*gg.ShaderError); !ok {
+		t.Errorf("CreateShader error = %v, want a *gg.ShaderError", err)
+	}
+}
+
+func TestNewContext(t *testing.T) {
+	// Contexts are independent, so any number can be made in one binary.
+	tests := []struct {
+		clear [4]float32
+		want  color.RGBA
+	}{
+		{red, color.RGBA{255, 0, 0, 255}},
+		{green, color.RGBA{0, 255, 0, 255}},
+	}
+	for _, tt := range tests {
+		ctx, b := gg_soft.NewContext(2, 2)
+		ctx.ClearColor(tt.clear[0], tt.clear[1], tt.clear[2], tt.clear[3])
+		ctx.Clear(gg.COLOR_BUFFER_BIT)
+		if got := b.Image().RGBAAt(1, 1); got != tt.want {
+			t.Errorf("pixel = %v, want %v", got, tt.want)
+		}
+	}
+}
+
+func TestDeleteDetachesFromFramebuffer(t *testing.T) {
+	tests := []struct {
+		name   string
+		delete func(b *gg_soft.Backend, tex *gg.Texture, colorRB, depthRB *gg.Renderbuffer)
+		attach func(b *gg_soft.Backend, tex *gg.Texture, colorRB, depthRB *gg.Renderbuffer)
+		want   gg.Enum
+	}{
+		{
+			name: "texture",
+			attach: func(b *gg_soft.Backend, tex *gg.Texture, colorRB, depthRB *gg.Renderbuffer) {
+				b.FramebufferTexture2D(gg.FRAMEBUFFER, gg.COLOR_ATTACHMENT0, gg.TEXTURE_2D, tex, 0)
+			},
+			delete: func(b *gg_soft.Backend, tex *gg.Texture, colorRB, depthRB *gg.Renderbuffer) {
+				b.DeleteTexture(tex)
+			},
+			want: gg.FRAMEBUFFER_INCOMPLETE_MISSING_ATTACHMENT,
+		},
+		{
+			name: "colorRB renderbuffer",
+			attach: func(b *gg_soft.Backend, tex *gg.Texture, colorRB, depthRB *gg.Renderbuffer) {
+				b.FramebufferRenderbuffer(gg.FRAMEBUFFER, gg.COLOR_ATTACHMENT0, gg.RENDERBUFFER, colorRB)
+			},
+			delete: func(b *gg_soft.Backend, tex *gg.Texture, colorRB, depthRB *gg.Renderbuffer) {
+				b.DeleteRenderbuffer(colorRB)
+			},
+			want: gg.FRAMEBUFFER_INCOMPLETE_MISSING_ATTACHMENT,
+		},
+		{
+			name: "depthRB renderbuffer",
+			attach: func(b *gg_soft.Backend, tex *gg.Texture, colorRB, depthRB *gg.Renderbuffer) {
+				b.FramebufferTexture2D(gg.FRAMEBUFFER, gg.COLOR_ATTACHMENT0, gg.TEXTURE_2D, tex, 0)
+				b.FramebufferRenderbuffer(gg.FRAMEBUFFER, gg.DEPTH_ATTACHMENT, gg.RENDERBUFFER, depthRB)
+			},
+			delete: func(b *gg_soft.Backend, tex *gg.Texture, colorRB, depthRB *gg.Renderbuffer) {
+				b.DeleteRenderbuffer(depthRB)
+			},
+			want: gg.FRAMEBUFFER_COMPLETE,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := gg_soft.New(1, 1)
+			tex := b.CreateTexture()
+			b.BindTexture(gg.TEXTURE_2D, tex)
+			b.TexImage2D(gg.TEXTURE_2D, 0, gg.RGBA, 2, 2, 0, gg.RGBA, gg.UNSIGNED_BYTE, make([]byte, 16))
+			colorRB := b.CreateRenderbuffer()
+			b.BindRenderbuffer(gg.RENDERBUFFER, colorRB)
+			b.RenderbufferStorage(gg.RENDERBUFFER, gg.RGBA4, 2, 2)
+			depthRB := b.CreateRenderbuffer()
+			b.BindRenderbuffer(gg.RENDERBUFFER, depthRB)
+			b.RenderbufferStorage(gg.RENDERBUFFER, gg.DEPTH_COMPONENT16, 2, 2)
+			b.BindFramebuffer(gg.FRAMEBUFFER, b.CreateFramebuffer())
+			tt.attach(b, tex, colorRB, depthRB)
+			if got := b.CheckFramebufferStatus(gg.FRAMEBUFFER); got != gg.FRAMEBUFFER_COMPLETE {
+				t.Fatalf("status before delete = 0x%x, want FRAMEBUFFER_COMPLETE", uint32(got))
+			}
+			tt.delete(b, tex, colorRB, depthRB)
+			if got := b.CheckFramebufferStatus(gg.FRAMEBUFFER); got != tt.want {
+				t.Errorf("status after delete = 0x%x, want 0x%x", uint32(got), uint32(tt.want))
+			}
+			if err := b.Err(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
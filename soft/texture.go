@@ -0,0 +1,189 @@
+package gg_soft
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/dmac/gg"
+)
+
+// texture stores level 0 of a 2D texture as RGBA8 with rows ordered
+// bottom-up, matching OpenGL's texture coordinate space.
+type texture struct {
+	width, height int
+	pix           []byte
+
+	wrapS, wrapT gg.Enum
+	magFilter    gg.Enum
+	minFilter    gg.Enum
+}
+
+func newTexture() *texture {
+	return &texture{
+		wrapS:     gg.REPEAT,
+		wrapT:     gg.REPEAT,
+		magFilter: gg.LINEAR,
+		minFilter: gg.NEAREST_MIPMAP_LINEAR,
+	}
+}
+
+// upload replaces the texture contents with width by height pixels from
+// src, converting them to RGBA8 and then keeping only the components of
+// internalFormat. A nil src allocates transparent black storage.
+func (t *texture) upload(width, height int, internalFormat, format, typ gg.Enum, src []byte) error {
+	if typ != gg.UNSIGNED_BYTE {
+		return fmt.Errorf("gg: TexImage2D: unsupported type 0x%x", uint32(typ))
+	}
+	var n int
+	switch format {
+	case gg.ALPHA, gg.LUMINANCE:
+		n = 1
+	case gg.LUMINANCE_ALPHA:
+		n = 2
+	case gg.RGB:
+		n = 3
+	case gg.RGBA:
+		n = 4
+	default:
+		return fmt.Errorf("gg: TexImage2D: unsupported format 0x%x", uint32(format))
+	}
+	if width < 0 || height < 0 {
+		return fmt.Errorf("gg: TexImage2D: invalid size %dx%d", width, height)
+	}
+	switch internalFormat {
+	case gg.ALPHA, gg.LUMINANCE, gg.LUMINANCE_ALPHA, gg.RGB, gg.RGBA:
+	default:
+		return fmt.Errorf("gg: TexImage2D: unsupported internal format 0x%x", uint32(internalFormat))
+	}
+	pix := make([]byte, 4*width*height)
+	if src != nil {
+		stride := (width*n + 3) &^ 3
+		if height > 0 && len(src) < stride*(height-1)+width*n {
+			return fmt.Errorf("gg: TexImage2D: need %d bytes, have %d", stride*(height-1)+width*n, len(src))
+		}
+		for y := 0; y < height; y++ {
+			row := src[y*stride:]
+			for x := 0; x < width; x++ {
+				p := pix[4*(y*width+x):]
+				s := row[x*n:]
+				switch format {
+				case gg.ALPHA:
+					p[0], p[1], p[2], p[3] = 0, 0, 0, s[0]
+				case gg.LUMINANCE:
+					p[0], p[1], p[2], p[3] = s[0], s[0], s[0], 255
+				case gg.LUMINANCE_ALPHA:
+					p[0], p[1], p[2], p[3] = s[0], s[0], s[0], s[1]
+				case gg.RGB:
+					p[0], p[1], p[2], p[3] = s[0], s[1], s[2], 255
+				case gg.RGBA:
+					copy(p[:4], s[:4])
+				}
+				switch internalFormat {
+				case gg.ALPHA:
+					p[0], p[1], p[2] = 0, 0, 0
+				case gg.LUMINANCE:
+					p[1], p[2], p[3] = p[0], p[0], 255
+				case gg.LUMINANCE_ALPHA:
+					p[1], p[2] = p[0], p[0]
+				case gg.RGB:
+					p[3] = 255
+				}
+			}
+		}
+	}
+	t.width, t.height, t.pix = width, height, pix
+	return nil
+}
+
+func (t *texture) setParameter(pname, param gg.Enum) error {
+	switch pname {
+	case gg.TEXTURE_WRAP_S, gg.TEXTURE_WRAP_T:
+		switch param {
+		case gg.REPEAT, gg.CLAMP_TO_EDGE, gg.MIRRORED_REPEAT:
+		default:
+			return fmt.Errorf("gg: TexParameteri: invalid wrap mode 0x%x", uint32(param))
+		}
+		if pname == gg.TEXTURE_WRAP_S {
+			t.wrapS = param
+		} else {
+			t.wrapT = param
+		}
+	case gg.TEXTURE_MAG_FILTER:
+		if param != gg.NEAREST && param != gg.LINEAR {
+			return fmt.Errorf("gg: TexParameteri: invalid mag filter 0x%x", uint32(param))
+		}
+		t.magFilter = param
+	case gg.TEXTURE_MIN_FILTER:
+		t.minFilter = param
+	default:
+		return fmt.Errorf("gg: TexParameteri: unsupported parameter 0x%x", uint32(pname))
+	}
+	return nil
+}
+
+// sample returns the color at texture coordinates (s, t). There are no
+// mipmaps and no screen-space derivatives, so the magnification filter is
+// used for every lookup.
+func (t *texture) sample(s, tc float32) [4]float32 {
+	if t.width == 0 || t.height == 0 {
+		return [4]float32{0, 0, 0, 1}
+	}
+	u := float64(s)*float64(t.width) - 0.5
+	v := float64(tc)*float64(t.height) - 0.5
+	if t.magFilter == gg.NEAREST {
+		return t.texel(int(math.Floor(u+0.5)), int(math.Floor(v+0.5)))
+	}
+	x0, y0 := math.Floor(u), math.Floor(v)
+	fx, fy := float32(u-x0), float32(v-y0)
+	i, j := int(x0), int(y0)
+	c00, c10 := t.texel(i, j), t.texel(i+1, j)
+	c01, c11 := t.texel(i, j+1), t.texel(i+1, j+1)
+	var c [4]float32
+	for k := range c {
+		top := c00[k]*(1-fx) + c10[k]*fx
+		bot := c01[k]*(1-fx) + c11[k]*fx
+		c[k] = top*(1-fy) + bot*fy
+	}
+	return c
+}
+
+func (t *texture) texel(x, y int) [4]float32 {
+	x = wrap(x, t.width, t.wrapS)
+	y = wrap(y, t.height, t.wrapT)
+	p := t.pix[4*(y*t.width+x):]
+	return [4]float32{
+		float32(p[0]) / 255,
+		float32(p[1]) / 255,
+		float32(p[2]) / 255,
+		float32(p[3]) / 255,
+	}
+}
+
+func wrap(i, n int, mode gg.Enum) int {
+	switch mode {
+	case gg.CLAMP_TO_EDGE:
+		if i < 0 {
+			return 0
+		}
+		if i >= n {
+			return n - 1
+		}
+		return i
+	case gg.MIRRORED_REPEAT:
+		i = mod(i, 2*n)
+		if i >= n {
+			return 2*n - 1 - i
+		}
+		return i
+	default:
+		return mod(i, n)
+	}
+}
+
+func mod(a, n int) int {
+	a %= n
+	if a < 0 {
+		a += n
+	}
+	return a
+}
@@ -0,0 +1,436 @@
+package gg_trace
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/dmac/gg"
+)
+
+// rawCall is a trace line with its arguments left undecoded.
+type rawCall struct {
+	Seq  int               `json:"seq"`
+	Call string            `json:"call"`
+	Args []json.RawMessage `json:"args"`
+	Ret  json.RawMessage   `json:"ret"`
+	Err  string            `json:"err"`
+}
+
+// Replay reads a trace written by a Recorder from r and issues its calls to
+// b, recreating every object the trace created. It stops at the first call
+// that cannot be replayed, such as an upload recorded by hash only, or a
+// call that fails on b but succeeded when it was recorded.
+func Replay(r io.Reader, b gg.Backend) error {
+	p := &replayer{b: b, handles: make(map[int]interface{})}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<30)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var c rawCall
+		if err := json.Unmarshal(sc.Bytes(), &c); err != nil {
+			return fmt.Errorf("gg: replay: %v", err)
+		}
+		if err := p.replay(&c); err != nil {
+			return fmt.Errorf("gg: replay: call %d (%s): %v", c.Seq, c.Call, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("gg: replay: %v", err)
+	}
+	return nil
+}
+
+type replayer struct {
+	b       gg.Backend
+	handles map[int]interface{}
+}
+
+// args decodes the arguments of a call in order. The first decoding error
+// is kept in err and later reads return zero values.
+type args struct {
+	p   *replayer
+	raw []json.RawMessage
+	i   int
+	err error
+}
+
+func (a *args) next(v interface{}) {
+	if a.err != nil {
+		return
+	}
+	if a.i >= len(a.raw) {
+		a.err = fmt.Errorf("missing argument %d", a.i)
+		return
+	}
+	if err := json.Unmarshal(a.raw[a.i], v); err != nil {
+		a.err = fmt.Errorf("argument %d: %v", a.i, err)
+	}
+	a.i++
+}
+
+func (a *args) enum() gg.Enum {
+	var v uint32
+	a.next(&v)
+	return gg.Enum(v)
+}
+
+func (a *args) int() int {
+	var v int
+	a.next(&v)
+	return v
+}
+
+func (a *args) float() float32 {
+	var v float32
+	a.next(&v)
+	return v
+}
+
+func (a *args) bool() bool {
+	var v bool
+	a.next(&v)
+	return v
+}
+
+func (a *args) string() string {
+	var v string
+	a.next(&v)
+	return v
+}
+
+func (a *args) floats() []float32 {
+	var v []float32
+	a.next(&v)
+	return v
+}
+
+func (a *args) ints() []int32 {
+	var v []int32
+	a.next(&v)
+	return v
+}
+
+// data decodes an uploaded []byte. A null argument decodes to nil.
+func (a *args) data() []byte {
+	var v *dataRef
+	a.next(&v)
+	if a.err != nil || v == nil {
+		return nil
+	}
+	if v.Data == nil && v.Len > 0 {
+		a.err = fmt.Errorf("data was recorded by hash only")
+		return nil
+	}
+	return v.Data
+}
+
+// handle decodes a handle reference and returns the object replayed for it,
+// or nil for a null reference.
+func (a *args) handle(kind string) interface{} {
+	var ref *handleRef
+	a.next(&ref)
+	if a.err != nil || ref == nil {
+		return nil
+	}
+	if ref.Kind != kind {
+		a.err = fmt.Errorf("argument %d: got %s handle, want %s", a.i-1, ref.Kind, kind)
+		return nil
+	}
+	h, ok := a.p.handles[ref.ID]
+	if !ok {
+		a.err = fmt.Errorf("argument %d: unknown %s %d", a.i-1, kind, ref.ID)
+	}
+	return h
+}
+
+func (a *args) buffer() *gg.Buffer {
+	h, _ := a.handle("Buffer").(*gg.Buffer)
+	return h
+}
+
+func (a *args) shader() *gg.Shader {
+	h, _ := a.handle("Shader").(*gg.Shader)
+	return h
+}
+
+func (a *args) program() *gg.Program {
+	h, _ := a.handle("Program").(*gg.Program)
+	return h
+}
+
+func (a *args) uniform() *gg.Uniform {
+	h, _ := a.handle("Uniform").(*gg.Uniform)
+	return h
+}
+
+func (a *args) attribute() *gg.Attribute {
+	h, _ := a.handle("Attribute").(*gg.Attribute)
+	return h
+}
+
+func (a *args) texture() *gg.Texture {
+	h, _ := a.handle("Texture").(*gg.Texture)
+	return h
+}
+
+func (a *args) framebuffer() *gg.Framebuffer {
+	h, _ := a.handle("Framebuffer").(*gg.Framebuffer)
+	return h
+}
+
+func (a *args) renderbuffer() *gg.Renderbuffer {
+	h, _ := a.handle("Renderbuffer").(*gg.Renderbuffer)
+	return h
+}
+
+// bind records the object created by a call under the ID in its result.
+func (p *replayer) bind(c *rawCall, h interface{}) error {
+	var ref handleRef
+	if err := json.Unmarshal(c.Ret, &ref); err != nil {
+		return fmt.Errorf("result: %v", err)
+	}
+	p.handles[ref.ID] = h
+	return nil
+}
+
+// check compares the error returned by a replayed call with the recorded
+// one. Calls that failed when recorded may fail again.
+func check(c *rawCall, err error) error {
+	if err != nil && c.Err == "" {
+		return err
+	}
+	return nil
+}
+
+func (p *replayer) replay(c *rawCall) error {
+	b := p.b
+	a := &args{p: p, raw: c.Args}
+	// Every argument is decoded before the backend is called, so that a
+	// malformed call is never partially applied.
+	var call func() error
+	switch c.Call {
+	case "Enable":
+		v := a.enum()
+		call = func() error { b.Enable(v); return nil }
+	case "Disable":
+		v := a.enum()
+		call = func() error { b.Disable(v); return nil }
+	case "DepthFunc":
+		f := a.enum()
+		call = func() error { b.DepthFunc(f); return nil }
+	case "BlendFunc":
+		s, d := a.enum(), a.enum()
+		call = func() error { b.BlendFunc(s, d); return nil }
+	case "Clear":
+		mask := a.enum()
+		call = func() error { b.Clear(mask); return nil }
+	case "ClearColor":
+		r, g, bl, al := a.float(), a.float(), a.float(), a.float()
+		call = func() error { b.ClearColor(r, g, bl, al); return nil }
+	case "Viewport":
+		x, y, w, h := a.int(), a.int(), a.int(), a.int()
+		call = func() error { b.Viewport(x, y, w, h); return nil }
+	case "Scissor":
+		x, y, w, h := a.int(), a.int(), a.int(), a.int()
+		call = func() error { b.Scissor(x, y, w, h); return nil }
+	case "CreateBuffer":
+		call = func() error { return p.bind(c, b.CreateBuffer()) }
+	case "BindBuffer":
+		typ, buf := a.enum(), a.buffer()
+		call = func() error { b.BindBuffer(typ, buf); return nil }
+	case "BufferData":
+		typ, src, usage := a.enum(), a.data(), a.enum()
+		call = func() error { b.BufferData(typ, src, usage); return nil }
+	case "BufferSubData":
+		typ, off, src := a.enum(), a.int(), a.data()
+		call = func() error { b.BufferSubData(typ, off, src); return nil }
+	case "DeleteBuffer":
+		buf := a.buffer()
+		call = func() error { b.DeleteBuffer(buf); return nil }
+	case "CreateShader":
+		src, typ := a.string(), a.enum()
+		call = func() error {
+			s, err := b.CreateShader([]byte(src), typ)
+			if err != nil {
+				return check(c, err)
+			}
+			return p.bind(c, s)
+		}
+	case "DeleteShader":
+		s := a.shader()
+		call = func() error { b.DeleteShader(s); return nil }
+	case "AttachShader":
+		prog, s := a.program(), a.shader()
+		call = func() error { b.AttachShader(prog, s); return nil }
+	case "DetachShader":
+		prog, s := a.program(), a.shader()
+		call = func() error { b.DetachShader(prog, s); return nil }
+	case "CreateProgram":
+		call = func() error { return p.bind(c, b.CreateProgram()) }
+	case "DeleteProgram":
+		prog := a.program()
+		call = func() error { b.DeleteProgram(prog); return nil }
+	case "LinkProgram":
+		prog := a.program()
+		call = func() error { return check(c, b.LinkProgram(prog)) }
+	case "GetProgramParameter":
+		prog, pname := a.program(), a.enum()
+		call = func() error { b.GetProgramParameter(prog, pname); return nil }
+	case "GetActiveUniform":
+		prog, index := a.program(), a.int()
+		call = func() error { b.GetActiveUniform(prog, index); return nil }
+	case "GetActiveAttrib":
+		prog, index := a.program(), a.int()
+		call = func() error { b.GetActiveAttrib(prog, index); return nil }
+	case "UseProgram":
+		prog := a.program()
+		call = func() error { b.UseProgram(prog); return nil }
+	case "GetUniformLocation":
+		prog, name := a.program(), a.string()
+		call = func() error {
+			u, err := b.GetUniformLocation(prog, name)
+			if err != nil {
+				return check(c, err)
+			}
+			return p.bind(c, u)
+		}
+	case "Uniform1f":
+		u, v0 := a.uniform(), a.float()
+		call = func() error { b.Uniform1f(u, v0); return nil }
+	case "Uniform2f":
+		u, v0, v1 := a.uniform(), a.float(), a.float()
+		call = func() error { b.Uniform2f(u, v0, v1); return nil }
+	case "Uniform3f":
+		u, v0, v1, v2 := a.uniform(), a.float(), a.float(), a.float()
+		call = func() error { b.Uniform3f(u, v0, v1, v2); return nil }
+	case "Uniform4f":
+		u, v0, v1, v2, v3 := a.uniform(), a.float(), a.float(), a.float(), a.float()
+		call = func() error { b.Uniform4f(u, v0, v1, v2, v3); return nil }
+	case "Uniform1i":
+		u, v0 := a.uniform(), a.int()
+		call = func() error { b.Uniform1i(u, v0); return nil }
+	case "Uniform2i":
+		u, v0, v1 := a.uniform(), a.int(), a.int()
+		call = func() error { b.Uniform2i(u, v0, v1); return nil }
+	case "Uniform3i":
+		u, v0, v1, v2 := a.uniform(), a.int(), a.int(), a.int()
+		call = func() error { b.Uniform3i(u, v0, v1, v2); return nil }
+	case "Uniform4i":
+		u, v0, v1, v2, v3 := a.uniform(), a.int(), a.int(), a.int(), a.int()
+		call = func() error { b.Uniform4i(u, v0, v1, v2, v3); return nil }
+	case "Uniform1fv", "Uniform2fv", "Uniform3fv", "Uniform4fv",
+		"UniformMatrix2fv", "UniformMatrix3fv", "UniformMatrix4fv":
+		u, v := a.uniform(), a.floats()
+		set := map[string]func(*gg.Uniform, []float32){
+			"Uniform1fv":       b.Uniform1fv,
+			"Uniform2fv":       b.Uniform2fv,
+			"Uniform3fv":       b.Uniform3fv,
+			"Uniform4fv":       b.Uniform4fv,
+			"UniformMatrix2fv": b.UniformMatrix2fv,
+			"UniformMatrix3fv": b.UniformMatrix3fv,
+			"UniformMatrix4fv": b.UniformMatrix4fv,
+		}[c.Call]
+		call = func() error { set(u, v); return nil }
+	case "Uniform1iv", "Uniform2iv", "Uniform3iv", "Uniform4iv":
+		u, v := a.uniform(), a.ints()
+		set := map[string]func(*gg.Uniform, []int32){
+			"Uniform1iv": b.Uniform1iv,
+			"Uniform2iv": b.Uniform2iv,
+			"Uniform3iv": b.Uniform3iv,
+			"Uniform4iv": b.Uniform4iv,
+		}[c.Call]
+		call = func() error { set(u, v); return nil }
+	case "GetAttribLocation":
+		prog, name := a.program(), a.string()
+		call = func() error {
+			attr, err := b.GetAttribLocation(prog, name)
+			if err != nil {
+				return check(c, err)
+			}
+			return p.bind(c, attr)
+		}
+	case "EnableVertexAttribArray":
+		attr := a.attribute()
+		call = func() error { b.EnableVertexAttribArray(attr); return nil }
+	case "VertexAttribPointer":
+		attr, size, typ, norm, stride, off := a.attribute(), a.int(), a.enum(), a.bool(), a.int(), a.int()
+		call = func() error { b.VertexAttribPointer(attr, size, typ, norm, stride, off); return nil }
+	case "CreateTexture":
+		call = func() error { return p.bind(c, b.CreateTexture()) }
+	case "ActiveTexture":
+		tex := a.enum()
+		call = func() error { b.ActiveTexture(tex); return nil }
+	case "BindTexture":
+		target, t := a.enum(), a.texture()
+		call = func() error { b.BindTexture(target, t); return nil }
+	case "DeleteTexture":
+		t := a.texture()
+		call = func() error { b.DeleteTexture(t); return nil }
+	case "CreateFramebuffer":
+		call = func() error { return p.bind(c, b.CreateFramebuffer()) }
+	case "BindFramebuffer":
+		target, fb := a.enum(), a.framebuffer()
+		call = func() error { b.BindFramebuffer(target, fb); return nil }
+	case "DeleteFramebuffer":
+		fb := a.framebuffer()
+		call = func() error { b.DeleteFramebuffer(fb); return nil }
+	case "FramebufferTexture2D":
+		target, attachment, textarget, t, level := a.enum(), a.enum(), a.enum(), a.texture(), a.int()
+		call = func() error { b.FramebufferTexture2D(target, attachment, textarget, t, level); return nil }
+	case "FramebufferRenderbuffer":
+		target, attachment, rbtarget, rb := a.enum(), a.enum(), a.enum(), a.renderbuffer()
+		call = func() error { b.FramebufferRenderbuffer(target, attachment, rbtarget, rb); return nil }
+	case "CheckFramebufferStatus":
+		target := a.enum()
+		call = func() error { b.CheckFramebufferStatus(target); return nil }
+	case "CreateRenderbuffer":
+		call = func() error { return p.bind(c, b.CreateRenderbuffer()) }
+	case "BindRenderbuffer":
+		target, rb := a.enum(), a.renderbuffer()
+		call = func() error { b.BindRenderbuffer(target, rb); return nil }
+	case "DeleteRenderbuffer":
+		rb := a.renderbuffer()
+		call = func() error { b.DeleteRenderbuffer(rb); return nil }
+	case "RenderbufferStorage":
+		target, format, w, h := a.enum(), a.enum(), a.int(), a.int()
+		call = func() error { b.RenderbufferStorage(target, format, w, h); return nil }
+	case "TexImage2D":
+		target, level, internalFormat := a.enum(), a.int(), a.enum()
+		w, h, border := a.int(), a.int(), a.int()
+		format, typ := a.enum(), a.enum()
+		data := a.data()
+		call = func() error {
+			b.TexImage2D(target, level, internalFormat, w, h, border, format, typ, data)
+			return nil
+		}
+	case "TexParameteri":
+		target, pname, param := a.enum(), a.enum(), a.enum()
+		call = func() error { b.TexParameteri(target, pname, param); return nil }
+	case "DrawArrays":
+		mode, first, count := a.enum(), a.int(), a.int()
+		call = func() error { b.DrawArrays(mode, first, count); return nil }
+	case "DrawElements":
+		mode, count, typ, off := a.enum(), a.int(), a.enum(), a.int()
+		call = func() error { return check(c, b.DrawElements(mode, count, typ, off)) }
+	case "ReadPixels":
+		x, y, w, h, format, typ := a.int(), a.int(), a.int(), a.int(), a.enum(), a.enum()
+		var ret dataRef
+		if err := json.Unmarshal(c.Ret, &ret); err != nil {
+			return fmt.Errorf("result: %v", err)
+		}
+		call = func() error {
+			b.ReadPixels(x, y, w, h, format, typ, make([]byte, ret.Len))
+			return nil
+		}
+	default:
+		return fmt.Errorf("unknown call")
+	}
+	if a.err != nil {
+		return a.err
+	}
+	return call()
+}
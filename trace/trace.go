@@ -0,0 +1,640 @@
+// Package gg_trace records the calls made to a gg.Backend as a command trace
+// that can later be replayed into any other backend.
+//
+// A Recorder wraps a backend, forwarding every call to it while writing the
+// call to an io.Writer. Replay reads a trace and issues the same calls to
+// another backend, for example to reproduce a user's rendering problem
+// against the software backend.
+//
+// # Trace format
+//
+// A trace is a sequence of JSON objects, one per line, each describing a
+// single call:
+//
+//	{"seq":12,"call":"BufferData","args":[34962,{"len":48,"sha256":"…","data":"…"},35044]}
+//
+// The fields are:
+//
+//	seq   the 0-based position of the call in the trace.
+//	call  the name of the gg.Backend method.
+//	args  the arguments, in the order of the method's parameters.
+//	ret   the result, for methods that return a value.
+//	err   the error message, for methods that returned a non-nil error.
+//
+// Arguments and results are encoded as follows:
+//
+//	gg.Enum, int        a JSON number.
+//	float32             a JSON number.
+//	bool                a JSON boolean.
+//	[]float32, []int32  a JSON array of numbers.
+//	handles             {"handle":"Buffer","id":3}, or null for a nil handle.
+//	                    IDs are assigned in order of creation, starting at 1,
+//	                    and are unique across all handle kinds. Looking up
+//	                    the same uniform or attribute location again
+//	                    refers to the same ID.
+//	[]byte              {"len":n,"sha256":"<hex>","data":"<base64>"}. The
+//	                    data field is omitted when the Recorder only hashes
+//	                    uploads.
+//	shader source       a JSON string.
+//	gg.ActiveInfo       {"name":"color","size":1,"type":35666}.
+package gg_trace
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/dmac/gg"
+)
+
+// call is one line of a trace.
+type call struct {
+	Seq  int           `json:"seq"`
+	Call string        `json:"call"`
+	Args []interface{} `json:"args,omitempty"`
+	Ret  interface{}   `json:"ret,omitempty"`
+	Err  string        `json:"err,omitempty"`
+}
+
+type handleRef struct {
+	Kind string `json:"handle"`
+	ID   int    `json:"id"`
+}
+
+type dataRef struct {
+	Len    int    `json:"len"`
+	SHA256 string `json:"sha256"`
+	Data   []byte `json:"data,omitempty"`
+}
+
+// Recorder is a gg.Backend that forwards calls to another backend while
+// recording them to a trace.
+type Recorder struct {
+	// HashOnly causes uploaded data to be recorded by length and hash only,
+	// which keeps traces small but means they cannot be replayed.
+	HashOnly bool
+
+	b   gg.Backend
+	enc *json.Encoder
+	err error
+	seq int
+
+	ids    map[handleKey]int
+	nextID int
+
+	// Deleted objects are forgotten, so ids does not grow over a long
+	// session. A deleted shader is forgotten only once it is detached from
+	// every program, since it can still be passed to DetachShader until then.
+	uniforms map[gg.ID][]gg.ID        // program to its uniforms in ids
+	attached map[gg.ID]map[gg.ID]bool // program to its attached shaders
+	shaders  map[gg.ID]*shaderState
+}
+
+// handleKey identifies a handle by its kind and its ID in the wrapped
+// backend. Attributes are identified by location.
+type handleKey struct {
+	kind string
+	id   gg.ID
+}
+
+type shaderState struct {
+	programs int // the number of programs the shader is attached to
+	deleted  bool
+}
+
+var _ gg.Backend = (*Recorder)(nil)
+
+// NewRecorder returns a Recorder that forwards calls to b and writes the
+// trace to w.
+func NewRecorder(b gg.Backend, w io.Writer) *Recorder {
+	return &Recorder{
+		b:        b,
+		enc:      json.NewEncoder(w),
+		ids:      make(map[handleKey]int),
+		uniforms: make(map[gg.ID][]gg.ID),
+		attached: make(map[gg.ID]map[gg.ID]bool),
+		shaders:  make(map[gg.ID]*shaderState),
+	}
+}
+
+// Err returns the first error encountered while writing the trace.
+func (r *Recorder) Err() error {
+	return r.err
+}
+
+func (r *Recorder) write(c *call) {
+	c.Seq = r.seq
+	r.seq++
+	if r.err != nil {
+		return
+	}
+	if err := r.enc.Encode(c); err != nil {
+		r.err = fmt.Errorf("gg: write trace: %v", err)
+	}
+}
+
+func (r *Recorder) rec(name string, args ...interface{}) {
+	r.write(&call{Call: name, Args: args})
+}
+
+func (r *Recorder) recRet(name string, ret interface{}, err error, args ...interface{}) {
+	c := &call{Call: name, Args: args, Ret: ret}
+	if err != nil {
+		c.Err = err.Error()
+	}
+	r.write(c)
+}
+
+// ref returns the encoding of handle h of the given kind, assigning it an ID
+// if it has not been seen before.
+func (r *Recorder) ref(kind string, h interface{}, isNil bool) interface{} {
+	if isNil {
+		return nil
+	}
+	k := handleKey{kind, handleID(h)}
+	id, ok := r.ids[k]
+	if !ok {
+		r.nextID++
+		id = r.nextID
+		r.ids[k] = id
+	}
+	return handleRef{Kind: kind, ID: id}
+}
+
+func handleID(h interface{}) gg.ID {
+	switch h := h.(type) {
+	case *gg.Buffer:
+		return h.ID
+	case *gg.Shader:
+		return h.ID
+	case *gg.Program:
+		return h.ID
+	case *gg.Uniform:
+		return h.ID
+	case *gg.Attribute:
+		return gg.ID(h.Location)
+	case *gg.Texture:
+		return h.ID
+	case *gg.Framebuffer:
+		return h.ID
+	case *gg.Renderbuffer:
+		return h.ID
+	}
+	panic(fmt.Sprintf("gg: trace: unexpected handle %T", h))
+}
+
+// forget removes the ID of a deleted object.
+func (r *Recorder) forget(kind string, id gg.ID) {
+	delete(r.ids, handleKey{kind, id})
+}
+
+// detach records that shader s is no longer attached to program p, and
+// forgets s if it was deleted and is now detached from every program.
+func (r *Recorder) detach(p, s gg.ID) {
+	if !r.attached[p][s] {
+		return
+	}
+	delete(r.attached[p], s)
+	st := r.shaders[s]
+	st.programs--
+	if st.programs == 0 {
+		delete(r.shaders, s)
+		if st.deleted {
+			r.forget("Shader", s)
+		}
+	}
+}
+
+func (r *Recorder) data(b []byte) interface{} {
+	sum := sha256.Sum256(b)
+	d := dataRef{Len: len(b), SHA256: hex.EncodeToString(sum[:])}
+	if !r.HashOnly {
+		d.Data = b
+	}
+	return d
+}
+
+func (r *Recorder) Enable(c gg.Enum) {
+	r.rec("Enable", c)
+	r.b.Enable(c)
+}
+
+func (r *Recorder) Disable(c gg.Enum) {
+	r.rec("Disable", c)
+	r.b.Disable(c)
+}
+
+func (r *Recorder) DepthFunc(f gg.Enum) {
+	r.rec("DepthFunc", f)
+	r.b.DepthFunc(f)
+}
+
+func (r *Recorder) BlendFunc(sfactor, dfactor gg.Enum) {
+	r.rec("BlendFunc", sfactor, dfactor)
+	r.b.BlendFunc(sfactor, dfactor)
+}
+
+func (r *Recorder) Clear(mask gg.Enum) {
+	r.rec("Clear", mask)
+	r.b.Clear(mask)
+}
+
+func (r *Recorder) ClearColor(red, green, blue, alpha float32) {
+	r.rec("ClearColor", red, green, blue, alpha)
+	r.b.ClearColor(red, green, blue, alpha)
+}
+
+func (r *Recorder) Viewport(x, y, width, height int) {
+	r.rec("Viewport", x, y, width, height)
+	r.b.Viewport(x, y, width, height)
+}
+
+func (r *Recorder) Scissor(x, y, width, height int) {
+	r.rec("Scissor", x, y, width, height)
+	r.b.Scissor(x, y, width, height)
+}
+
+func (r *Recorder) CreateBuffer() *gg.Buffer {
+	b := r.b.CreateBuffer()
+	r.recRet("CreateBuffer", r.ref("Buffer", b, false), nil)
+	return b
+}
+
+func (r *Recorder) BindBuffer(typ gg.Enum, b *gg.Buffer) {
+	r.rec("BindBuffer", typ, r.ref("Buffer", b, b == nil))
+	r.b.BindBuffer(typ, b)
+}
+
+func (r *Recorder) BufferData(typ gg.Enum, src []byte, usage gg.Enum) {
+	r.rec("BufferData", typ, r.data(src), usage)
+	r.b.BufferData(typ, src, usage)
+}
+
+func (r *Recorder) BufferSubData(typ gg.Enum, offset int, src []byte) {
+	r.rec("BufferSubData", typ, offset, r.data(src))
+	r.b.BufferSubData(typ, offset, src)
+}
+
+func (r *Recorder) DeleteBuffer(b *gg.Buffer) {
+	r.rec("DeleteBuffer", r.ref("Buffer", b, false))
+	r.b.DeleteBuffer(b)
+	r.forget("Buffer", b.ID)
+}
+
+// ShaderDialect reports the dialect of the wrapped backend, so that portable
+// shaders are translated for it.
+func (r *Recorder) ShaderDialect() gg.Dialect {
+	if d, ok := r.b.(gg.ShaderDialectBackend); ok {
+		return d.ShaderDialect()
+	}
+	return 0
+}
+
+func (r *Recorder) CreateShader(src []byte, typ gg.Enum) (*gg.Shader, error) {
+	s, err := r.b.CreateShader(src, typ)
+	var ret interface{}
+	if err == nil {
+		ret = r.ref("Shader", s, false)
+	}
+	r.recRet("CreateShader", ret, err, string(src), typ)
+	return s, err
+}
+
+func (r *Recorder) DeleteShader(s *gg.Shader) {
+	r.rec("DeleteShader", r.ref("Shader", s, false))
+	r.b.DeleteShader(s)
+	if st := r.shaders[s.ID]; st != nil {
+		st.deleted = true
+	} else {
+		r.forget("Shader", s.ID)
+	}
+}
+
+func (r *Recorder) AttachShader(p *gg.Program, s *gg.Shader) {
+	r.rec("AttachShader", r.ref("Program", p, false), r.ref("Shader", s, false))
+	r.b.AttachShader(p, s)
+	if r.attached[p.ID] == nil {
+		r.attached[p.ID] = make(map[gg.ID]bool)
+	}
+	if !r.attached[p.ID][s.ID] {
+		r.attached[p.ID][s.ID] = true
+		if r.shaders[s.ID] == nil {
+			r.shaders[s.ID] = &shaderState{}
+		}
+		r.shaders[s.ID].programs++
+	}
+}
+
+func (r *Recorder) DetachShader(p *gg.Program, s *gg.Shader) {
+	r.rec("DetachShader", r.ref("Program", p, false), r.ref("Shader", s, false))
+	r.b.DetachShader(p, s)
+	r.detach(p.ID, s.ID)
+}
+
+func (r *Recorder) CreateProgram() *gg.Program {
+	p := r.b.CreateProgram()
+	r.recRet("CreateProgram", r.ref("Program", p, false), nil)
+	return p
+}
+
+func (r *Recorder) DeleteProgram(p *gg.Program) {
+	r.rec("DeleteProgram", r.ref("Program", p, false))
+	r.b.DeleteProgram(p)
+	for s := range r.attached[p.ID] {
+		r.detach(p.ID, s)
+	}
+	delete(r.attached, p.ID)
+	for _, u := range r.uniforms[p.ID] {
+		r.forget("Uniform", u)
+	}
+	delete(r.uniforms, p.ID)
+	r.forget("Program", p.ID)
+}
+
+func (r *Recorder) LinkProgram(p *gg.Program) error {
+	err := r.b.LinkProgram(p)
+	r.recRet("LinkProgram", nil, err, r.ref("Program", p, false))
+	return err
+}
+
+func (r *Recorder) GetProgramParameter(p *gg.Program, pname gg.Enum) int {
+	v := r.b.GetProgramParameter(p, pname)
+	r.recRet("GetProgramParameter", v, nil, r.ref("Program", p, false), pname)
+	return v
+}
+
+func (r *Recorder) GetActiveUniform(p *gg.Program, index int) gg.ActiveInfo {
+	info := r.b.GetActiveUniform(p, index)
+	r.recRet("GetActiveUniform", activeInfo(info), nil, r.ref("Program", p, false), index)
+	return info
+}
+
+func (r *Recorder) GetActiveAttrib(p *gg.Program, index int) gg.ActiveInfo {
+	info := r.b.GetActiveAttrib(p, index)
+	r.recRet("GetActiveAttrib", activeInfo(info), nil, r.ref("Program", p, false), index)
+	return info
+}
+
+func activeInfo(info gg.ActiveInfo) interface{} {
+	return map[string]interface{}{"name": info.Name, "size": info.Size, "type": info.Type}
+}
+
+func (r *Recorder) UseProgram(p *gg.Program) {
+	r.rec("UseProgram", r.ref("Program", p, p == nil))
+	r.b.UseProgram(p)
+}
+
+func (r *Recorder) GetUniformLocation(p *gg.Program, name string) (*gg.Uniform, error) {
+	u, err := r.b.GetUniformLocation(p, name)
+	var ret interface{}
+	if err == nil {
+		if _, ok := r.ids[handleKey{"Uniform", u.ID}]; !ok {
+			r.uniforms[p.ID] = append(r.uniforms[p.ID], u.ID)
+		}
+		ret = r.ref("Uniform", u, false)
+	}
+	r.recRet("GetUniformLocation", ret, err, r.ref("Program", p, false), name)
+	return u, err
+}
+
+func (r *Recorder) uniform(name string, u *gg.Uniform, args ...interface{}) {
+	r.rec(name, append([]interface{}{r.ref("Uniform", u, false)}, args...)...)
+}
+
+func (r *Recorder) Uniform1f(u *gg.Uniform, v0 float32) {
+	r.uniform("Uniform1f", u, v0)
+	r.b.Uniform1f(u, v0)
+}
+
+func (r *Recorder) Uniform2f(u *gg.Uniform, v0, v1 float32) {
+	r.uniform("Uniform2f", u, v0, v1)
+	r.b.Uniform2f(u, v0, v1)
+}
+
+func (r *Recorder) Uniform3f(u *gg.Uniform, v0, v1, v2 float32) {
+	r.uniform("Uniform3f", u, v0, v1, v2)
+	r.b.Uniform3f(u, v0, v1, v2)
+}
+
+func (r *Recorder) Uniform4f(u *gg.Uniform, v0, v1, v2, v3 float32) {
+	r.uniform("Uniform4f", u, v0, v1, v2, v3)
+	r.b.Uniform4f(u, v0, v1, v2, v3)
+}
+
+func (r *Recorder) Uniform1i(u *gg.Uniform, v0 int) {
+	r.uniform("Uniform1i", u, v0)
+	r.b.Uniform1i(u, v0)
+}
+
+func (r *Recorder) Uniform2i(u *gg.Uniform, v0, v1 int) {
+	r.uniform("Uniform2i", u, v0, v1)
+	r.b.Uniform2i(u, v0, v1)
+}
+
+func (r *Recorder) Uniform3i(u *gg.Uniform, v0, v1, v2 int) {
+	r.uniform("Uniform3i", u, v0, v1, v2)
+	r.b.Uniform3i(u, v0, v1, v2)
+}
+
+func (r *Recorder) Uniform4i(u *gg.Uniform, v0, v1, v2, v3 int) {
+	r.uniform("Uniform4i", u, v0, v1, v2, v3)
+	r.b.Uniform4i(u, v0, v1, v2, v3)
+}
+
+func (r *Recorder) Uniform1fv(u *gg.Uniform, values []float32) {
+	r.uniform("Uniform1fv", u, values)
+	r.b.Uniform1fv(u, values)
+}
+
+func (r *Recorder) Uniform2fv(u *gg.Uniform, values []float32) {
+	r.uniform("Uniform2fv", u, values)
+	r.b.Uniform2fv(u, values)
+}
+
+func (r *Recorder) Uniform3fv(u *gg.Uniform, values []float32) {
+	r.uniform("Uniform3fv", u, values)
+	r.b.Uniform3fv(u, values)
+}
+
+func (r *Recorder) Uniform4fv(u *gg.Uniform, values []float32) {
+	r.uniform("Uniform4fv", u, values)
+	r.b.Uniform4fv(u, values)
+}
+
+func (r *Recorder) Uniform1iv(u *gg.Uniform, values []int32) {
+	r.uniform("Uniform1iv", u, values)
+	r.b.Uniform1iv(u, values)
+}
+
+func (r *Recorder) Uniform2iv(u *gg.Uniform, values []int32) {
+	r.uniform("Uniform2iv", u, values)
+	r.b.Uniform2iv(u, values)
+}
+
+func (r *Recorder) Uniform3iv(u *gg.Uniform, values []int32) {
+	r.uniform("Uniform3iv", u, values)
+	r.b.Uniform3iv(u, values)
+}
+
+func (r *Recorder) Uniform4iv(u *gg.Uniform, values []int32) {
+	r.uniform("Uniform4iv", u, values)
+	r.b.Uniform4iv(u, values)
+}
+
+func (r *Recorder) UniformMatrix2fv(u *gg.Uniform, values []float32) {
+	r.uniform("UniformMatrix2fv", u, values)
+	r.b.UniformMatrix2fv(u, values)
+}
+
+func (r *Recorder) UniformMatrix3fv(u *gg.Uniform, values []float32) {
+	r.uniform("UniformMatrix3fv", u, values)
+	r.b.UniformMatrix3fv(u, values)
+}
+
+func (r *Recorder) UniformMatrix4fv(u *gg.Uniform, values []float32) {
+	r.uniform("UniformMatrix4fv", u, values)
+	r.b.UniformMatrix4fv(u, values)
+}
+
+func (r *Recorder) GetAttribLocation(p *gg.Program, name string) (*gg.Attribute, error) {
+	a, err := r.b.GetAttribLocation(p, name)
+	var ret interface{}
+	if err == nil {
+		ret = r.ref("Attribute", a, false)
+	}
+	r.recRet("GetAttribLocation", ret, err, r.ref("Program", p, false), name)
+	return a, err
+}
+
+func (r *Recorder) EnableVertexAttribArray(a *gg.Attribute) {
+	r.rec("EnableVertexAttribArray", r.ref("Attribute", a, false))
+	r.b.EnableVertexAttribArray(a)
+}
+
+func (r *Recorder) VertexAttribPointer(a *gg.Attribute, size int, typ gg.Enum, normalized bool, stride, offset int) {
+	r.rec("VertexAttribPointer", r.ref("Attribute", a, false), size, typ, normalized, stride, offset)
+	r.b.VertexAttribPointer(a, size, typ, normalized, stride, offset)
+}
+
+func (r *Recorder) CreateTexture() *gg.Texture {
+	t := r.b.CreateTexture()
+	r.recRet("CreateTexture", r.ref("Texture", t, false), nil)
+	return t
+}
+
+func (r *Recorder) ActiveTexture(tex gg.Enum) {
+	r.rec("ActiveTexture", tex)
+	r.b.ActiveTexture(tex)
+}
+
+func (r *Recorder) BindTexture(target gg.Enum, t *gg.Texture) {
+	r.rec("BindTexture", target, r.ref("Texture", t, t == nil))
+	r.b.BindTexture(target, t)
+}
+
+func (r *Recorder) DeleteTexture(t *gg.Texture) {
+	r.rec("DeleteTexture", r.ref("Texture", t, false))
+	r.b.DeleteTexture(t)
+	r.forget("Texture", t.ID)
+}
+
+func (r *Recorder) CreateFramebuffer() *gg.Framebuffer {
+	fb := r.b.CreateFramebuffer()
+	r.recRet("CreateFramebuffer", r.ref("Framebuffer", fb, false), nil)
+	return fb
+}
+
+func (r *Recorder) BindFramebuffer(target gg.Enum, fb *gg.Framebuffer) {
+	r.rec("BindFramebuffer", target, r.ref("Framebuffer", fb, fb == nil))
+	r.b.BindFramebuffer(target, fb)
+}
+
+func (r *Recorder) DeleteFramebuffer(fb *gg.Framebuffer) {
+	r.rec("DeleteFramebuffer", r.ref("Framebuffer", fb, false))
+	r.b.DeleteFramebuffer(fb)
+	r.forget("Framebuffer", fb.ID)
+}
+
+func (r *Recorder) FramebufferTexture2D(
+	target, attachment, textarget gg.Enum,
+	t *gg.Texture, level int,
+) {
+	r.rec("FramebufferTexture2D", target, attachment, textarget, r.ref("Texture", t, t == nil), level)
+	r.b.FramebufferTexture2D(target, attachment, textarget, t, level)
+}
+
+func (r *Recorder) FramebufferRenderbuffer(
+	target, attachment, renderbuffertarget gg.Enum,
+	rb *gg.Renderbuffer,
+) {
+	r.rec("FramebufferRenderbuffer", target, attachment, renderbuffertarget, r.ref("Renderbuffer", rb, rb == nil))
+	r.b.FramebufferRenderbuffer(target, attachment, renderbuffertarget, rb)
+}
+
+func (r *Recorder) CheckFramebufferStatus(target gg.Enum) gg.Enum {
+	status := r.b.CheckFramebufferStatus(target)
+	r.recRet("CheckFramebufferStatus", status, nil, target)
+	return status
+}
+
+func (r *Recorder) CreateRenderbuffer() *gg.Renderbuffer {
+	rb := r.b.CreateRenderbuffer()
+	r.recRet("CreateRenderbuffer", r.ref("Renderbuffer", rb, false), nil)
+	return rb
+}
+
+func (r *Recorder) BindRenderbuffer(target gg.Enum, rb *gg.Renderbuffer) {
+	r.rec("BindRenderbuffer", target, r.ref("Renderbuffer", rb, rb == nil))
+	r.b.BindRenderbuffer(target, rb)
+}
+
+func (r *Recorder) DeleteRenderbuffer(rb *gg.Renderbuffer) {
+	r.rec("DeleteRenderbuffer", r.ref("Renderbuffer", rb, false))
+	r.b.DeleteRenderbuffer(rb)
+	r.forget("Renderbuffer", rb.ID)
+}
+
+func (r *Recorder) RenderbufferStorage(target, internalFormat gg.Enum, width, height int) {
+	r.rec("RenderbufferStorage", target, internalFormat, width, height)
+	r.b.RenderbufferStorage(target, internalFormat, width, height)
+}
+
+func (r *Recorder) TexImage2D(
+	target gg.Enum, level int, internalFormat gg.Enum,
+	width, height, border int,
+	format, typ gg.Enum,
+	data []byte,
+) {
+	var d interface{}
+	if data != nil {
+		d = r.data(data)
+	}
+	r.rec("TexImage2D", target, level, internalFormat, width, height, border, format, typ, d)
+	r.b.TexImage2D(target, level, internalFormat, width, height, border, format, typ, data)
+}
+
+func (r *Recorder) TexParameteri(target gg.Enum, pname gg.Enum, param gg.Enum) {
+	r.rec("TexParameteri", target, pname, param)
+	r.b.TexParameteri(target, pname, param)
+}
+
+func (r *Recorder) DrawArrays(mode gg.Enum, first, count int) {
+	r.rec("DrawArrays", mode, first, count)
+	r.b.DrawArrays(mode, first, count)
+}
+
+func (r *Recorder) DrawElements(mode gg.Enum, count int, typ gg.Enum, offset int) error {
+	err := r.b.DrawElements(mode, count, typ, offset)
+	r.recRet("DrawElements", nil, err, mode, count, typ, offset)
+	return err
+}
+
+func (r *Recorder) ReadPixels(x, y, width, height int, format, typ gg.Enum, dst []byte) {
+	r.b.ReadPixels(x, y, width, height, format, typ, dst)
+	// The pixels read are recorded as the result, so a replay can be
+	// compared against the original.
+	d := r.data(dst)
+	r.recRet("ReadPixels", d, nil, x, y, width, height, format, typ)
+}
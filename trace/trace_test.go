@@ -0,0 +1,270 @@
+package gg_trace_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/dmac/gg"
+	"github.com/dmac/gg/soft"
+	"github.com/dmac/gg/trace"
+)
+
+const (
+	vertexSrc = `
+attribute vec2 position;
+varying vec2 uv;
+void main() {
+	uv = (position + 1.0) / 2.0;
+	gl_Position = vec4(position, 0.0, 1.0);
+}
+`
+	colorSrc = `
+uniform vec4 color;
+void main() { gl_FragColor = color; }
+`
+	textureSrc = `
+uniform sampler2D tex;
+varying vec2 uv;
+void main() { gl_FragColor = texture2D(tex, uv); }
+`
+)
+
+func init() {
+	gg_soft.RegisterVertexShader(vertexSrc, func(v *gg_soft.Vertex) {
+		p := v.Attrib("position")
+		v.Position = [4]float32{p[0], p[1], 0, 1}
+		v.SetVarying("uv", (p[0]+1)/2, (p[1]+1)/2)
+	})
+	gg_soft.RegisterFragmentShader(colorSrc, func(f *gg_soft.Fragment) {
+		f.Color = f.Uniforms.Vec4("color")
+	})
+	gg_soft.RegisterFragmentShader(textureSrc, func(f *gg_soft.Fragment) {
+		uv := f.Varying("uv")
+		f.Color = f.Uniforms.Texture2D("tex", uv[0], uv[1])
+	})
+}
+
+func program(t *testing.T, b gg.Backend, fragmentSrc string) *gg.Program {
+	t.Helper()
+	vs, err := b.CreateShader([]byte(vertexSrc), gg.VERTEX_SHADER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs, err := b.CreateShader([]byte(fragmentSrc), gg.FRAGMENT_SHADER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := b.CreateProgram()
+	b.AttachShader(p, vs)
+	b.AttachShader(p, fs)
+	if err := b.LinkProgram(p); err != nil {
+		t.Fatal(err)
+	}
+	b.DetachShader(p, vs)
+	b.DetachShader(p, fs)
+	b.DeleteShader(vs)
+	b.DeleteShader(fs)
+	b.UseProgram(p)
+	return p
+}
+
+// triangle draws a triangle covering the lower left half of the viewport.
+func triangle(t *testing.T, b gg.Backend, p *gg.Program) {
+	t.Helper()
+	buf := b.CreateBuffer()
+	b.BindBuffer(gg.ARRAY_BUFFER, buf)
+	b.BufferData(gg.ARRAY_BUFFER, floatBytes(-1, -1, 1, -1, -1, 1), gg.STATIC_DRAW)
+	a, err := b.GetAttribLocation(p, "position")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.EnableVertexAttribArray(a)
+	b.VertexAttribPointer(a, 2, gg.FLOAT, false, 0, 0)
+	b.DrawArrays(gg.TRIANGLES, 0, 3)
+	b.DeleteBuffer(buf)
+}
+
+func floatBytes(values ...float32) []byte {
+	data := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(v))
+	}
+	return data
+}
+
+var sessions = []struct {
+	name string
+	run  func(t *testing.T, b gg.Backend)
+}{
+	{
+		name: "clear",
+		run: func(t *testing.T, b gg.Backend) {
+			b.ClearColor(0, 0, 1, 1)
+			b.Clear(gg.COLOR_BUFFER_BIT)
+		},
+	},
+	{
+		name: "uniform color",
+		run: func(t *testing.T, b gg.Backend) {
+			p := program(t, b, colorSrc)
+			u, err := b.GetUniformLocation(p, "color")
+			if err != nil {
+				t.Fatal(err)
+			}
+			b.Uniform4f(u, 1, 0, 0, 1)
+			triangle(t, b, p)
+		},
+	},
+	{
+		name: "program deleted and recreated",
+		run: func(t *testing.T, b gg.Backend) {
+			for i, c := range []float32{0.5, 1} {
+				p := program(t, b, colorSrc)
+				u, err := b.GetUniformLocation(p, "color")
+				if err != nil {
+					t.Fatal(err)
+				}
+				b.Uniform4f(u, c, float32(i), 0, 1)
+				triangle(t, b, p)
+				b.DeleteProgram(p)
+			}
+		},
+	},
+	{
+		name: "texture",
+		run: func(t *testing.T, b gg.Backend) {
+			p := program(t, b, textureSrc)
+			tex := b.CreateTexture()
+			b.ActiveTexture(gg.TEXTURE0)
+			b.BindTexture(gg.TEXTURE_2D, tex)
+			b.TexParameteri(gg.TEXTURE_2D, gg.TEXTURE_MIN_FILTER, gg.NEAREST)
+			b.TexParameteri(gg.TEXTURE_2D, gg.TEXTURE_MAG_FILTER, gg.NEAREST)
+			b.TexImage2D(gg.TEXTURE_2D, 0, gg.RGBA, 2, 2, 0, gg.RGBA, gg.UNSIGNED_BYTE, []byte{
+				255, 0, 0, 255, 0, 255, 0, 255,
+				0, 0, 255, 255, 255, 255, 255, 255,
+			})
+			u, err := b.GetUniformLocation(p, "tex")
+			if err != nil {
+				t.Fatal(err)
+			}
+			b.Uniform1i(u, 0)
+			triangle(t, b, p)
+			b.DeleteTexture(tex)
+		},
+	},
+}
+
+func TestRecordReplay(t *testing.T) {
+	for _, s := range sessions {
+		t.Run(s.name, func(t *testing.T) {
+			want := gg_soft.New(8, 8)
+			var trace bytes.Buffer
+			r := gg_trace.NewRecorder(want, &trace)
+			s.run(t, r)
+			if err := r.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if err := want.Err(); err != nil {
+				t.Fatal(err)
+			}
+
+			got := gg_soft.New(8, 8)
+			if err := gg_trace.Replay(&trace, got); err != nil {
+				t.Fatal(err)
+			}
+			if err := got.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Image().Pix, want.Image().Pix) {
+				t.Errorf("replayed image differs from the recorded one")
+			}
+			if bytes.Equal(want.Image().Pix, gg_soft.New(8, 8).Image().Pix) {
+				t.Errorf("session drew nothing")
+			}
+		})
+	}
+}
+
+func TestReplayHashOnly(t *testing.T) {
+	var trace bytes.Buffer
+	r := gg_trace.NewRecorder(gg_soft.New(8, 8), &trace)
+	r.HashOnly = true
+	sessions[1].run(t, r)
+	err := gg_trace.Replay(&trace, gg_soft.New(8, 8))
+	if err == nil || !strings.Contains(err.Error(), "hash only") {
+		t.Errorf("Replay error = %v, want one mentioning hash only", err)
+	}
+}
+
+func TestReplayErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		trace string
+		want  string
+	}{
+		{"malformed", `{"seq":0,"call":`, "gg: replay:"},
+		{"unknown handle", `{"seq":0,"call":"BindBuffer","args":[34962,{"handle":"Buffer","id":7}]}`, "unknown Buffer 7"},
+		{"wrong handle kind", `{"seq":0,"call":"CreateTexture","ret":{"handle":"Texture","id":1}}` + "\n" +
+			`{"seq":1,"call":"BindBuffer","args":[34962,{"handle":"Texture","id":1}]}`, "got Texture handle, want Buffer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := gg_trace.Replay(strings.NewReader(tt.trace), gg_soft.New(1, 1))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Replay error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// calls decodes a trace into its calls.
+func calls(t *testing.T, trace []byte) []map[string]json.RawMessage {
+	t.Helper()
+	var cs []map[string]json.RawMessage
+	sc := bufio.NewScanner(bytes.NewReader(trace))
+	for sc.Scan() {
+		var c map[string]json.RawMessage
+		if err := json.Unmarshal(sc.Bytes(), &c); err != nil {
+			t.Fatal(err)
+		}
+		cs = append(cs, c)
+	}
+	return cs
+}
+
+func TestUniformLocationIDs(t *testing.T) {
+	var trace bytes.Buffer
+	b := gg_trace.NewRecorder(gg_soft.New(1, 1), &trace)
+	p := program(t, b, colorSrc)
+	for i := 0; i < 2; i++ {
+		if _, err := b.GetUniformLocation(p, "color"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b.DeleteProgram(p)
+	p = program(t, b, colorSrc)
+	if _, err := b.GetUniformLocation(p, "color"); err != nil {
+		t.Fatal(err)
+	}
+
+	var rets []string
+	for _, c := range calls(t, trace.Bytes()) {
+		if string(c["call"]) == `"GetUniformLocation"` {
+			rets = append(rets, string(c["ret"]))
+		}
+	}
+	if len(rets) != 3 {
+		t.Fatalf("recorded %d GetUniformLocation calls, want 3", len(rets))
+	}
+	if rets[0] != rets[1] {
+		t.Errorf("repeated lookup returned %s, want %s", rets[1], rets[0])
+	}
+	if rets[2] == rets[0] {
+		t.Errorf("lookup in a new program reused %s", rets[0])
+	}
+}
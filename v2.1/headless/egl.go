@@ -0,0 +1,118 @@
+//go:build linux && !osmesa
+// +build linux,!osmesa
+
+package headless
+
+/*
+#cgo LDFLAGS: -lEGL
+#include <stdlib.h>
+#include <EGL/egl.h>
+#include <EGL/eglext.h>
+
+#ifndef EGL_PLATFORM_SURFACELESS_MESA
+#define EGL_PLATFORM_SURFACELESS_MESA 0x31DD
+#endif
+
+static EGLDisplay ggDisplay(void) {
+	PFNEGLGETPLATFORMDISPLAYEXTPROC getPlatformDisplay =
+		(PFNEGLGETPLATFORMDISPLAYEXTPROC)eglGetProcAddress("eglGetPlatformDisplayEXT");
+	if (getPlatformDisplay != NULL) {
+		EGLDisplay d = getPlatformDisplay(EGL_PLATFORM_SURFACELESS_MESA, EGL_DEFAULT_DISPLAY, NULL);
+		if (d != EGL_NO_DISPLAY) {
+			return d;
+		}
+	}
+	return eglGetDisplay(EGL_DEFAULT_DISPLAY);
+}
+
+static EGLBoolean ggConfig(EGLDisplay d, EGLConfig *config) {
+	static const EGLint attribs[] = {
+		EGL_SURFACE_TYPE, EGL_PBUFFER_BIT,
+		EGL_RENDERABLE_TYPE, EGL_OPENGL_BIT,
+		EGL_RED_SIZE, 8,
+		EGL_GREEN_SIZE, 8,
+		EGL_BLUE_SIZE, 8,
+		EGL_ALPHA_SIZE, 8,
+		EGL_DEPTH_SIZE, 24,
+		EGL_NONE,
+	};
+	EGLint n;
+	return eglChooseConfig(d, attribs, config, 1, &n) && n > 0;
+}
+
+static EGLSurface ggSurface(EGLDisplay d, EGLConfig config, EGLint width, EGLint height) {
+	const EGLint attribs[] = {
+		EGL_WIDTH, width,
+		EGL_HEIGHT, height,
+		EGL_NONE,
+	};
+	return eglCreatePbufferSurface(d, config, attribs);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+type egl struct {
+	display C.EGLDisplay
+	surface C.EGLSurface
+	context C.EGLContext
+}
+
+func eglError(call string) error {
+	return fmt.Errorf("gg: headless: %s failed with EGL error 0x%x", call, int(C.eglGetError()))
+}
+
+func newPlatform(width, height int) (platform, error) {
+	e := &egl{display: C.ggDisplay()}
+	if e.display == C.EGLDisplay(C.EGL_NO_DISPLAY) {
+		return nil, eglError("eglGetDisplay")
+	}
+	if C.eglInitialize(e.display, nil, nil) == C.EGL_FALSE {
+		return nil, eglError("eglInitialize")
+	}
+	if C.eglBindAPI(C.EGL_OPENGL_API) == C.EGL_FALSE {
+		e.destroy()
+		return nil, eglError("eglBindAPI")
+	}
+	var config C.EGLConfig
+	if C.ggConfig(e.display, &config) == C.EGL_FALSE {
+		e.destroy()
+		return nil, eglError("eglChooseConfig")
+	}
+	e.surface = C.ggSurface(e.display, config, C.EGLint(width), C.EGLint(height))
+	if e.surface == C.EGLSurface(C.EGL_NO_SURFACE) {
+		e.destroy()
+		return nil, eglError("eglCreatePbufferSurface")
+	}
+	e.context = C.eglCreateContext(e.display, config, C.EGLContext(C.EGL_NO_CONTEXT), nil)
+	if e.context == C.EGLContext(C.EGL_NO_CONTEXT) {
+		e.destroy()
+		return nil, eglError("eglCreateContext")
+	}
+	if C.eglMakeCurrent(e.display, e.surface, e.surface, e.context) == C.EGL_FALSE {
+		e.destroy()
+		return nil, eglError("eglMakeCurrent")
+	}
+	return e, nil
+}
+
+func (e *egl) procAddress(name string) unsafe.Pointer {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	return unsafe.Pointer(C.eglGetProcAddress(cname))
+}
+
+func (e *egl) destroy() {
+	C.eglMakeCurrent(e.display, C.EGLSurface(C.EGL_NO_SURFACE), C.EGLSurface(C.EGL_NO_SURFACE), C.EGLContext(C.EGL_NO_CONTEXT))
+	if e.context != C.EGLContext(C.EGL_NO_CONTEXT) {
+		C.eglDestroyContext(e.display, e.context)
+	}
+	if e.surface != C.EGLSurface(C.EGL_NO_SURFACE) {
+		C.eglDestroySurface(e.display, e.surface)
+	}
+	C.eglTerminate(e.display)
+}
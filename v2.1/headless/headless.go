@@ -0,0 +1,82 @@
+//go:build linux || osmesa
+// +build linux osmesa
+
+// Package headless creates OpenGL contexts that render without a window or
+// display server, for producing images on servers and in batch jobs.
+//
+// By default the context is created through EGL, using Mesa's surfaceless
+// platform when it is available so that no X11 or Wayland connection is
+// needed. Building with the osmesa tag uses OSMesa instead, which renders in
+// software (llvmpipe) and only needs libOSMesa.
+//
+// Rendering goes through the OpenGL 2.1 backend. A context is current on the
+// thread that created it, so callers should lock the goroutine to its thread
+// with runtime.LockOSThread before calling New and make every gg call from
+// that goroutine.
+package headless
+
+import (
+	"fmt"
+	"image"
+	"unsafe"
+
+	"github.com/dmac/gg"
+	"github.com/dmac/gg/helpers"
+	gg21 "github.com/dmac/gg/v2.1"
+	"github.com/go-gl/gl/v2.1/gl"
+)
+
+// platform is an offscreen GL context made current on the calling thread.
+type platform interface {
+	procAddress(name string) unsafe.Pointer
+	destroy()
+}
+
+// Context is a gg.Context rendering into an offscreen framebuffer of a fixed
+// size.
+type Context struct {
+	*gg.Context
+
+	width, height int
+	p             platform
+}
+
+// New creates an offscreen context with a width by height RGBA framebuffer
+// with a depth buffer, makes it current on the calling thread, and returns a
+// Context for it. The viewport is set to cover the framebuffer.
+func New(width, height int) (*Context, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("gg: headless: invalid size %dx%d", width, height)
+	}
+	p, err := newPlatform(width, height)
+	if err != nil {
+		return nil, err
+	}
+	if err := gl.InitWithProcAddrFunc(p.procAddress); err != nil {
+		p.destroy()
+		return nil, fmt.Errorf("gg: headless: %v", err)
+	}
+	c := &Context{
+		Context: gg21.NewContext(),
+		width:   width,
+		height:  height,
+		p:       p,
+	}
+	c.Viewport(0, 0, width, height)
+	return c, nil
+}
+
+// Size returns the size of the framebuffer in pixels.
+func (c *Context) Size() (width, height int) {
+	return c.width, c.height
+}
+
+// Frame reads the whole of the current framebuffer with ReadPixels.
+func (c *Context) Frame() (*image.RGBA, error) {
+	return helpers.ReadImage(c.Context, 0, 0, c.width, c.height)
+}
+
+// Destroy releases the GL context. c must not be used afterwards.
+func (c *Context) Destroy() {
+	c.p.destroy()
+}
@@ -0,0 +1,53 @@
+//go:build osmesa
+// +build osmesa
+
+package headless
+
+/*
+#cgo LDFLAGS: -lOSMesa
+#include <stdlib.h>
+#include <GL/osmesa.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+type osmesa struct {
+	context C.OSMesaContext
+	buf     unsafe.Pointer
+}
+
+func newPlatform(width, height int) (platform, error) {
+	o := &osmesa{context: C.OSMesaCreateContextExt(C.OSMESA_RGBA, 24, 8, 0, nil)}
+	if o.context == nil {
+		return nil, fmt.Errorf("gg: headless: OSMesaCreateContextExt failed")
+	}
+	o.buf = C.malloc(C.size_t(width * height * 4))
+	if o.buf == nil {
+		o.destroy()
+		return nil, fmt.Errorf("gg: headless: cannot allocate %dx%d framebuffer", width, height)
+	}
+	if C.OSMesaMakeCurrent(o.context, o.buf, C.GL_UNSIGNED_BYTE, C.GLsizei(width), C.GLsizei(height)) == 0 {
+		o.destroy()
+		return nil, fmt.Errorf("gg: headless: OSMesaMakeCurrent failed")
+	}
+	return o, nil
+}
+
+func (o *osmesa) procAddress(name string) unsafe.Pointer {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	return unsafe.Pointer(C.OSMesaGetProcAddress(cname))
+}
+
+func (o *osmesa) destroy() {
+	if o.context != nil {
+		C.OSMesaDestroyContext(o.context)
+	}
+	if o.buf != nil {
+		C.free(o.buf)
+	}
+}
@@ -0,0 +1,539 @@
+//go:build js && wasm
+// +build js,wasm
+
+// Package gg_wasm implements gg.Backend on a WebGL context using syscall/js,
+// for programs built with GOOS=js GOARCH=wasm.
+//
+// Go memory cannot be handed to WebGL directly, so slices are copied into a
+// reusable JavaScript ArrayBuffer and passed as typed array views of it.
+// TexImage2DFromSource accepts a js.Value holding an HTMLImageElement,
+// HTMLCanvasElement, ImageBitmap or other image source.
+package gg_wasm
+
+import (
+	"errors"
+	"fmt"
+	"syscall/js"
+	"unsafe"
+
+	"github.com/dmac/gg"
+)
+
+var (
+	uint8Array   = js.Global().Get("Uint8Array")
+	uint16Array  = js.Global().Get("Uint16Array")
+	int32Array   = js.Global().Get("Int32Array")
+	float32Array = js.Global().Get("Float32Array")
+)
+
+type backend struct {
+	gl   js.Value
+	objs gg.Table
+
+	// uintIndices reports whether OES_element_index_uint is available,
+	// allowing DrawElements with UNSIGNED_INT indices.
+	uintIndices bool
+
+	// scratch is the ArrayBuffer data is copied through, grown as needed.
+	scratch js.Value
+	size    int
+}
+
+var (
+	_ gg.Backend              = (*backend)(nil)
+	_ gg.FeatureBackend       = (*backend)(nil)
+	_ gg.ImageSourceBackend   = (*backend)(nil)
+	_ gg.ShaderDialectBackend = (*backend)(nil)
+)
+
+// NewContext returns a gg.Context that issues calls to gl, a
+// WebGLRenderingContext.
+func NewContext(gl js.Value) *gg.Context {
+	return gg.NewContext(&backend{
+		gl:          gl,
+		uintIndices: !gl.Call("getExtension", "OES_element_index_uint").IsNull(),
+	})
+}
+
+// NewCanvasContext creates a WebGL context for canvas with the given context
+// attributes, which may be nil, and returns a gg.Context for it.
+func NewCanvasContext(canvas js.Value, attrs map[string]interface{}) (*gg.Context, error) {
+	if js.Global().Get("WebGLRenderingContext").IsUndefined() {
+		return nil, errors.New("gg: the browser does not support WebGL")
+	}
+	gl := canvas.Call("getContext", "webgl", attrs)
+	if gl.IsNull() {
+		gl = canvas.Call("getContext", "experimental-webgl", attrs)
+	}
+	if gl.IsNull() {
+		return nil, errors.New("gg: creating a WebGL context failed")
+	}
+	return NewContext(gl), nil
+}
+
+// reserve returns a Uint8Array view of the first n bytes of the scratch
+// buffer.
+func (b *backend) reserve(n int) js.Value {
+	if n > b.size {
+		size := 2 * b.size
+		if size < n {
+			size = n
+		}
+		b.scratch = uint8Array.New(size)
+		b.size = size
+	}
+	return b.scratch.Call("subarray", 0, n)
+}
+
+// bytes copies p into the scratch buffer and returns a Uint8Array view of
+// the copy.
+func (b *backend) bytes(p []byte) js.Value {
+	view := b.reserve(len(p))
+	js.CopyBytesToJS(view, p)
+	return view
+}
+
+// view copies p into the scratch buffer and returns a view of the copy
+// through the typed array constructor ctor, whose elements are size bytes.
+func (b *backend) view(ctor js.Value, p []byte, size int) js.Value {
+	u8 := b.bytes(p)
+	return ctor.New(u8.Get("buffer"), 0, len(p)/size)
+}
+
+func (b *backend) float32s(v []float32) js.Value {
+	if len(v) == 0 {
+		return float32Array.New(0)
+	}
+	return b.view(float32Array, unsafe.Slice((*byte)(unsafe.Pointer(&v[0])), 4*len(v)), 4)
+}
+
+func (b *backend) int32s(v []int32) js.Value {
+	if len(v) == 0 {
+		return int32Array.New(0)
+	}
+	return b.view(int32Array, unsafe.Slice((*byte)(unsafe.Pointer(&v[0])), 4*len(v)), 4)
+}
+
+// pixels returns p as the typed array WebGL requires for pixel data of
+// type typ.
+func (b *backend) pixels(typ gg.Enum, p []byte) js.Value {
+	switch typ {
+	case gg.UNSIGNED_SHORT_5_6_5, gg.UNSIGNED_SHORT_4_4_4_4, gg.UNSIGNED_SHORT_5_5_5_1:
+		return b.view(uint16Array, p, 2)
+	case gg.FLOAT:
+		return b.view(float32Array, p, 4)
+	}
+	return b.bytes(p)
+}
+
+// object returns the JavaScript object with the given ID, or null if there
+// is none.
+func (b *backend) object(id gg.ID) js.Value {
+	if v, ok := b.objs.Get(id).(js.Value); ok {
+		return v
+	}
+	return js.Null()
+}
+
+func (b *backend) Enable(c gg.Enum) {
+	b.gl.Call("enable", int(c))
+}
+
+func (b *backend) Disable(c gg.Enum) {
+	b.gl.Call("disable", int(c))
+}
+
+func (b *backend) DepthFunc(f gg.Enum) {
+	b.gl.Call("depthFunc", int(f))
+}
+
+func (b *backend) BlendFunc(sfactor, dfactor gg.Enum) {
+	b.gl.Call("blendFunc", int(sfactor), int(dfactor))
+}
+
+func (b *backend) Clear(mask gg.Enum) {
+	b.gl.Call("clear", int(mask))
+}
+
+func (be *backend) ClearColor(r, g, b, a float32) {
+	be.gl.Call("clearColor", r, g, b, a)
+}
+
+func (b *backend) Viewport(x, y, width, height int) {
+	b.gl.Call("viewport", x, y, width, height)
+}
+
+func (b *backend) Scissor(x, y, width, height int) {
+	b.gl.Call("scissor", x, y, width, height)
+}
+
+func (b *backend) CreateBuffer() *gg.Buffer {
+	return &gg.Buffer{ID: b.objs.Add(b.gl.Call("createBuffer"))}
+}
+
+func (b *backend) BindBuffer(typ gg.Enum, buf *gg.Buffer) {
+	var id gg.ID
+	if buf != nil {
+		id = buf.ID
+	}
+	b.gl.Call("bindBuffer", int(typ), b.object(id))
+}
+
+func (b *backend) BufferData(typ gg.Enum, src []byte, usage gg.Enum) {
+	if len(src) == 0 {
+		b.gl.Call("bufferData", int(typ), 0, int(usage))
+		return
+	}
+	b.gl.Call("bufferData", int(typ), b.bytes(src), int(usage))
+}
+
+func (b *backend) BufferSubData(typ gg.Enum, offset int, src []byte) {
+	if len(src) == 0 {
+		return
+	}
+	b.gl.Call("bufferSubData", int(typ), offset, b.bytes(src))
+}
+
+func (b *backend) DeleteBuffer(buf *gg.Buffer) {
+	b.gl.Call("deleteBuffer", b.object(buf.ID))
+	b.objs.Delete(buf.ID)
+}
+
+func (*backend) ShaderDialect() gg.Dialect {
+	return gg.GLSLES100
+}
+
+func (b *backend) CreateShader(src []byte, typ gg.Enum) (*gg.Shader, error) {
+	shader := b.gl.Call("createShader", int(typ))
+	b.gl.Call("shaderSource", shader, string(src))
+	b.gl.Call("compileShader", shader)
+	if !b.gl.Call("getShaderParameter", shader, int(gg.COMPILE_STATUS)).Bool() {
+		log := b.gl.Call("getShaderInfoLog", shader).String()
+		b.gl.Call("deleteShader", shader)
+		return nil, gg.NewShaderError(typ, src, log)
+	}
+	return &gg.Shader{ID: b.objs.Add(shader)}, nil
+}
+
+func (b *backend) DeleteShader(s *gg.Shader) {
+	b.gl.Call("deleteShader", b.object(s.ID))
+	b.objs.Delete(s.ID)
+}
+
+func (b *backend) CreateProgram() *gg.Program {
+	return &gg.Program{ID: b.objs.Add(b.gl.Call("createProgram"))}
+}
+
+func (b *backend) DeleteProgram(p *gg.Program) {
+	b.gl.Call("deleteProgram", b.object(p.ID))
+	b.objs.Delete(p.ID)
+}
+
+func (b *backend) AttachShader(p *gg.Program, s *gg.Shader) {
+	b.gl.Call("attachShader", b.object(p.ID), b.object(s.ID))
+	b.objs.Attach(p.ID, s.ID)
+}
+
+func (b *backend) DetachShader(p *gg.Program, s *gg.Shader) {
+	b.gl.Call("detachShader", b.object(p.ID), b.object(s.ID))
+	b.objs.Detach(p.ID, s.ID)
+}
+
+func (b *backend) LinkProgram(p *gg.Program) error {
+	pv := b.object(p.ID)
+	b.gl.Call("linkProgram", pv)
+	if !b.gl.Call("getProgramParameter", pv, int(gg.LINK_STATUS)).Bool() {
+		return gg.NewLinkError(b.gl.Call("getProgramInfoLog", pv).String())
+	}
+	return nil
+}
+
+func (b *backend) GetProgramParameter(p *gg.Program, pname gg.Enum) int {
+	v := b.gl.Call("getProgramParameter", b.object(p.ID), int(pname))
+	switch v.Type() {
+	case js.TypeBoolean:
+		if v.Bool() {
+			return 1
+		}
+		return 0
+	case js.TypeNumber:
+		return v.Int()
+	}
+	return 0
+}
+
+func (b *backend) GetActiveUniform(p *gg.Program, index int) gg.ActiveInfo {
+	return activeInfo(b.gl.Call("getActiveUniform", b.object(p.ID), index))
+}
+
+func (b *backend) GetActiveAttrib(p *gg.Program, index int) gg.ActiveInfo {
+	return activeInfo(b.gl.Call("getActiveAttrib", b.object(p.ID), index))
+}
+
+// activeInfo converts a WebGLActiveInfo.
+func activeInfo(info js.Value) gg.ActiveInfo {
+	if info.IsNull() || info.IsUndefined() {
+		return gg.ActiveInfo{}
+	}
+	return gg.ActiveInfo{
+		Name: info.Get("name").String(),
+		Size: info.Get("size").Int(),
+		Type: gg.Enum(info.Get("type").Int()),
+	}
+}
+
+func (b *backend) UseProgram(p *gg.Program) {
+	var id gg.ID
+	if p != nil {
+		id = p.ID
+	}
+	b.gl.Call("useProgram", b.object(id))
+}
+
+func (b *backend) GetUniformLocation(p *gg.Program, name string) (*gg.Uniform, error) {
+	u := b.gl.Call("getUniformLocation", b.object(p.ID), name)
+	if u.IsNull() {
+		return nil, fmt.Errorf("gg: no uniform named %s", name)
+	}
+	return &gg.Uniform{ID: b.objs.Named(p.ID, name, u)}, nil
+}
+
+func (b *backend) Uniform1f(u *gg.Uniform, v0 float32) {
+	b.gl.Call("uniform1f", b.object(u.ID), v0)
+}
+
+func (b *backend) Uniform2f(u *gg.Uniform, v0, v1 float32) {
+	b.gl.Call("uniform2f", b.object(u.ID), v0, v1)
+}
+
+func (b *backend) Uniform3f(u *gg.Uniform, v0, v1, v2 float32) {
+	b.gl.Call("uniform3f", b.object(u.ID), v0, v1, v2)
+}
+
+func (b *backend) Uniform4f(u *gg.Uniform, v0, v1, v2, v3 float32) {
+	b.gl.Call("uniform4f", b.object(u.ID), v0, v1, v2, v3)
+}
+
+func (b *backend) Uniform1i(u *gg.Uniform, v0 int) {
+	b.gl.Call("uniform1i", b.object(u.ID), v0)
+}
+
+func (b *backend) Uniform2i(u *gg.Uniform, v0, v1 int) {
+	b.gl.Call("uniform2i", b.object(u.ID), v0, v1)
+}
+
+func (b *backend) Uniform3i(u *gg.Uniform, v0, v1, v2 int) {
+	b.gl.Call("uniform3i", b.object(u.ID), v0, v1, v2)
+}
+
+func (b *backend) Uniform4i(u *gg.Uniform, v0, v1, v2, v3 int) {
+	b.gl.Call("uniform4i", b.object(u.ID), v0, v1, v2, v3)
+}
+
+func (b *backend) Uniform1fv(u *gg.Uniform, values []float32) {
+	b.gl.Call("uniform1fv", b.object(u.ID), b.float32s(values))
+}
+
+func (b *backend) Uniform2fv(u *gg.Uniform, values []float32) {
+	b.gl.Call("uniform2fv", b.object(u.ID), b.float32s(values))
+}
+
+func (b *backend) Uniform3fv(u *gg.Uniform, values []float32) {
+	b.gl.Call("uniform3fv", b.object(u.ID), b.float32s(values))
+}
+
+func (b *backend) Uniform4fv(u *gg.Uniform, values []float32) {
+	b.gl.Call("uniform4fv", b.object(u.ID), b.float32s(values))
+}
+
+func (b *backend) Uniform1iv(u *gg.Uniform, values []int32) {
+	b.gl.Call("uniform1iv", b.object(u.ID), b.int32s(values))
+}
+
+func (b *backend) Uniform2iv(u *gg.Uniform, values []int32) {
+	b.gl.Call("uniform2iv", b.object(u.ID), b.int32s(values))
+}
+
+func (b *backend) Uniform3iv(u *gg.Uniform, values []int32) {
+	b.gl.Call("uniform3iv", b.object(u.ID), b.int32s(values))
+}
+
+func (b *backend) Uniform4iv(u *gg.Uniform, values []int32) {
+	b.gl.Call("uniform4iv", b.object(u.ID), b.int32s(values))
+}
+
+func (b *backend) UniformMatrix2fv(u *gg.Uniform, values []float32) {
+	b.gl.Call("uniformMatrix2fv", b.object(u.ID), false, b.float32s(values))
+}
+
+func (b *backend) UniformMatrix3fv(u *gg.Uniform, values []float32) {
+	b.gl.Call("uniformMatrix3fv", b.object(u.ID), false, b.float32s(values))
+}
+
+func (b *backend) UniformMatrix4fv(u *gg.Uniform, values []float32) {
+	b.gl.Call("uniformMatrix4fv", b.object(u.ID), false, b.float32s(values))
+}
+
+func (b *backend) GetAttribLocation(p *gg.Program, name string) (*gg.Attribute, error) {
+	a := b.gl.Call("getAttribLocation", b.object(p.ID), name).Int()
+	if a < 0 {
+		return nil, fmt.Errorf("gg: no attribute named %s", name)
+	}
+	return &gg.Attribute{Location: a}, nil
+}
+
+func (b *backend) EnableVertexAttribArray(a *gg.Attribute) {
+	b.gl.Call("enableVertexAttribArray", a.Location)
+}
+
+func (b *backend) VertexAttribPointer(a *gg.Attribute, size int, typ gg.Enum, normalized bool, stride, offset int) {
+	b.gl.Call("vertexAttribPointer", a.Location, size, int(typ), normalized, stride, offset)
+}
+
+func (b *backend) CreateTexture() *gg.Texture {
+	return &gg.Texture{ID: b.objs.Add(b.gl.Call("createTexture"))}
+}
+
+func (b *backend) ActiveTexture(tex gg.Enum) {
+	b.gl.Call("activeTexture", int(tex))
+}
+
+func (b *backend) BindTexture(target gg.Enum, texture *gg.Texture) {
+	var id gg.ID
+	if texture != nil {
+		id = texture.ID
+	}
+	b.gl.Call("bindTexture", int(target), b.object(id))
+}
+
+func (b *backend) DeleteTexture(t *gg.Texture) {
+	b.gl.Call("deleteTexture", b.object(t.ID))
+	b.objs.Delete(t.ID)
+}
+
+func (b *backend) TexImage2D(
+	target gg.Enum, level int, internalFormat gg.Enum,
+	width, height, border int,
+	format, typ gg.Enum,
+	data []byte,
+) {
+	pixels := js.Null()
+	if data != nil {
+		pixels = b.pixels(typ, data)
+	}
+	b.gl.Call(
+		"texImage2D",
+		int(target), level, int(internalFormat),
+		width, height, border,
+		int(format), int(typ),
+		pixels,
+	)
+}
+
+// TexImage2DFromSource uploads source, which must be a js.Value holding an
+// image, canvas, video or ImageBitmap.
+func (b *backend) TexImage2DFromSource(target gg.Enum, level int, internalFormat, format, typ gg.Enum, source interface{}) error {
+	src, ok := source.(js.Value)
+	if !ok {
+		return fmt.Errorf("gg: TexImage2DFromSource: source is a %T, not a js.Value", source)
+	}
+	b.gl.Call("texImage2D", int(target), level, int(internalFormat), int(format), int(typ), src)
+	return nil
+}
+
+func (b *backend) Supports(f gg.Feature) bool {
+	return f == gg.FeatureImageSources
+}
+
+func (b *backend) TexParameteri(target gg.Enum, pname gg.Enum, param gg.Enum) {
+	b.gl.Call("texParameteri", int(target), int(pname), int(param))
+}
+
+func (b *backend) DrawArrays(mode gg.Enum, first, count int) {
+	b.gl.Call("drawArrays", int(mode), first, count)
+}
+
+func (b *backend) DrawElements(mode gg.Enum, count int, typ gg.Enum, offset int) error {
+	switch typ {
+	case gg.UNSIGNED_BYTE, gg.UNSIGNED_SHORT:
+	case gg.UNSIGNED_INT:
+		if !b.uintIndices {
+			return fmt.Errorf("gg: UNSIGNED_INT indices require OES_element_index_uint")
+		}
+	default:
+		return fmt.Errorf("gg: invalid index type 0x%x", int(typ))
+	}
+	b.gl.Call("drawElements", int(mode), count, int(typ), offset)
+	return nil
+}
+
+func (b *backend) CreateFramebuffer() *gg.Framebuffer {
+	return &gg.Framebuffer{ID: b.objs.Add(b.gl.Call("createFramebuffer"))}
+}
+
+func (b *backend) BindFramebuffer(target gg.Enum, fb *gg.Framebuffer) {
+	var id gg.ID
+	if fb != nil {
+		id = fb.ID
+	}
+	b.gl.Call("bindFramebuffer", int(target), b.object(id))
+}
+
+func (b *backend) DeleteFramebuffer(fb *gg.Framebuffer) {
+	b.gl.Call("deleteFramebuffer", b.object(fb.ID))
+	b.objs.Delete(fb.ID)
+}
+
+func (b *backend) FramebufferTexture2D(
+	target, attachment, textarget gg.Enum,
+	texture *gg.Texture, level int,
+) {
+	var id gg.ID
+	if texture != nil {
+		id = texture.ID
+	}
+	b.gl.Call("framebufferTexture2D", int(target), int(attachment), int(textarget), b.object(id), level)
+}
+
+func (b *backend) FramebufferRenderbuffer(
+	target, attachment, renderbuffertarget gg.Enum,
+	rb *gg.Renderbuffer,
+) {
+	var id gg.ID
+	if rb != nil {
+		id = rb.ID
+	}
+	b.gl.Call("framebufferRenderbuffer", int(target), int(attachment), int(renderbuffertarget), b.object(id))
+}
+
+func (b *backend) CheckFramebufferStatus(target gg.Enum) gg.Enum {
+	return gg.Enum(b.gl.Call("checkFramebufferStatus", int(target)).Int())
+}
+
+func (b *backend) CreateRenderbuffer() *gg.Renderbuffer {
+	return &gg.Renderbuffer{ID: b.objs.Add(b.gl.Call("createRenderbuffer"))}
+}
+
+func (b *backend) BindRenderbuffer(target gg.Enum, rb *gg.Renderbuffer) {
+	var id gg.ID
+	if rb != nil {
+		id = rb.ID
+	}
+	b.gl.Call("bindRenderbuffer", int(target), b.object(id))
+}
+
+func (b *backend) DeleteRenderbuffer(rb *gg.Renderbuffer) {
+	b.gl.Call("deleteRenderbuffer", b.object(rb.ID))
+	b.objs.Delete(rb.ID)
+}
+
+func (b *backend) RenderbufferStorage(target, internalFormat gg.Enum, width, height int) {
+	b.gl.Call("renderbufferStorage", int(target), int(internalFormat), width, height)
+}
+
+func (b *backend) ReadPixels(x, y, width, height int, format, typ gg.Enum, dst []byte) {
+	// Read into the scratch buffer, then copy back into Go memory.
+	view := b.reserve(len(dst))
+	b.gl.Call("readPixels", x, y, width, height, int(format), int(typ), view)
+	js.CopyBytesToGo(dst, view)
+}
@@ -8,150 +8,458 @@ import (
 	"github.com/gopherjs/webgl"
 )
 
-type backend struct {
-	gl *webgl.Context
+// Backend implements gg.Backend on a WebGL context. The WebGL 2 backend
+// embeds it and adds the calls WebGL 2 introduces.
+type Backend struct {
+	gl   *webgl.Context
+	objs gg.Table
+
+	// uintIndices reports whether OES_element_index_uint is available,
+	// allowing DrawElements with UNSIGNED_INT indices.
+	uintIndices bool
 }
 
-var _ gg.Backend = (*backend)(nil)
+var (
+	_ gg.Backend              = (*Backend)(nil)
+	_ gg.FeatureBackend       = (*Backend)(nil)
+	_ gg.ImageSourceBackend   = (*Backend)(nil)
+	_ gg.ShaderDialectBackend = (*Backend)(nil)
+)
+
+// New returns a Backend that issues calls to gl.
+func New(gl *webgl.Context) *Backend {
+	return &Backend{
+		gl:          gl,
+		uintIndices: gl.GetExtension("OES_element_index_uint") != nil,
+	}
+}
 
+// NewContext returns a gg.Context that issues calls to gl.
+func NewContext(gl *webgl.Context) *gg.Context {
+	return gg.NewContext(New(gl))
+}
+
+// Init sets a context for gl as the default gg context.
+//
+// Deprecated: Use NewContext.
 func Init(gl *webgl.Context) {
-	gg.Register(&backend{gl: gl})
+	gg.SetDefault(NewContext(gl))
+}
+
+// Object returns the WebGL object with the given ID, or nil if there is
+// none.
+func (b *Backend) Object(id gg.ID) *js.Object {
+	v, _ := b.objs.Get(id).(*js.Object)
+	return v
 }
 
-func (b *backend) Enable(c gg.Enum) {
+// Objects returns the table mapping IDs to WebGL objects. Backends that
+// embed b add the objects they create to it.
+func (b *Backend) Objects() *gg.Table {
+	return &b.objs
+}
+
+func (b *Backend) Enable(c gg.Enum) {
 	b.gl.Enable(int(c))
 }
 
-func (b *backend) DepthFunc(f gg.Enum) {
+func (b *Backend) Disable(c gg.Enum) {
+	b.gl.Disable(int(c))
+}
+
+func (b *Backend) DepthFunc(f gg.Enum) {
 	b.gl.DepthFunc(int(f))
 }
 
-func (b *backend) BlendFunc(sfactor, dfactor gg.Enum) {
+func (b *Backend) BlendFunc(sfactor, dfactor gg.Enum) {
 	b.gl.BlendFunc(int(sfactor), int(dfactor))
 }
 
-func (b *backend) Clear(mask gg.Enum) {
+func (b *Backend) Clear(mask gg.Enum) {
 	b.gl.Clear(int(mask))
 }
 
-func (be *backend) ClearColor(r, g, b, a float32) {
+func (be *Backend) ClearColor(r, g, b, a float32) {
 	be.gl.ClearColor(r, g, b, a)
 }
 
-func (b *backend) CreateBuffer() *gg.Buffer {
-	return &gg.Buffer{Value: b.gl.CreateBuffer()}
+func (b *Backend) Viewport(x, y, width, height int) {
+	b.gl.Viewport(x, y, width, height)
 }
 
-func (b *backend) BindBuffer(typ gg.Enum, buf *gg.Buffer) {
-	b.gl.BindBuffer(int(typ), buf.Value.(*js.Object))
+func (b *Backend) Scissor(x, y, width, height int) {
+	b.gl.Scissor(x, y, width, height)
 }
 
-func (b *backend) BufferData(typ gg.Enum, src []byte, usage gg.Enum) {
+func (b *Backend) CreateBuffer() *gg.Buffer {
+	return &gg.Buffer{ID: b.objs.Add(b.gl.CreateBuffer())}
+}
+
+func (b *Backend) BindBuffer(typ gg.Enum, buf *gg.Buffer) {
+	var v *js.Object
+	if buf != nil {
+		v = b.Object(buf.ID)
+	}
+	b.gl.BindBuffer(int(typ), v)
+}
+
+func (b *Backend) BufferData(typ gg.Enum, src []byte, usage gg.Enum) {
 	b.gl.BufferData(int(typ), src, int(usage))
 }
 
-func (b *backend) CreateShader(src []byte, typ gg.Enum) (*gg.Shader, error) {
+func (b *Backend) BufferSubData(typ gg.Enum, offset int, src []byte) {
+	b.gl.BufferSubData(int(typ), offset, src)
+}
+
+func (b *Backend) DeleteBuffer(buf *gg.Buffer) {
+	b.gl.DeleteBuffer(b.Object(buf.ID))
+	b.objs.Delete(buf.ID)
+}
+
+func (*Backend) ShaderDialect() gg.Dialect {
+	return gg.GLSLES100
+}
+
+func (b *Backend) CreateShader(src []byte, typ gg.Enum) (*gg.Shader, error) {
 	shader := b.gl.CreateShader(int(typ))
 	b.gl.ShaderSource(shader, string(src))
 	b.gl.CompileShader(shader)
-	log := b.gl.GetShaderInfoLog(shader)
-	if log != "" {
-		return nil, fmt.Errorf("gg: compile shader: %s%s", src, log)
+	if !b.gl.GetShaderParameterb(shader, int(gg.COMPILE_STATUS)) {
+		log := b.gl.GetShaderInfoLog(shader)
+		b.gl.DeleteShader(shader)
+		return nil, gg.NewShaderError(typ, src, log)
 	}
-	return &gg.Shader{Value: shader}, nil
+	return &gg.Shader{ID: b.objs.Add(shader)}, nil
+}
+
+func (b *Backend) DeleteShader(s *gg.Shader) {
+	b.gl.DeleteShader(b.Object(s.ID))
+	b.objs.Delete(s.ID)
+}
+
+func (b *Backend) CreateProgram() *gg.Program {
+	return &gg.Program{ID: b.objs.Add(b.gl.CreateProgram())}
+}
+
+func (b *Backend) DeleteProgram(p *gg.Program) {
+	b.gl.DeleteProgram(b.Object(p.ID))
+	b.objs.Delete(p.ID)
 }
 
-func (b *backend) CreateProgram() *gg.Program {
-	return &gg.Program{Value: b.gl.CreateProgram()}
+func (b *Backend) AttachShader(p *gg.Program, s *gg.Shader) {
+	b.gl.AttachShader(b.Object(p.ID), b.Object(s.ID))
+	b.objs.Attach(p.ID, s.ID)
 }
 
-func (b *backend) AttachShader(p *gg.Program, s *gg.Shader) {
-	b.gl.AttachShader(p.Value.(*js.Object), s.Value.(*js.Object))
+func (b *Backend) DetachShader(p *gg.Program, s *gg.Shader) {
+	b.gl.DetachShader(b.Object(p.ID), b.Object(s.ID))
+	b.objs.Detach(p.ID, s.ID)
 }
 
-func (b *backend) LinkProgram(p *gg.Program) error {
-	b.gl.LinkProgram(p.Value.(*js.Object))
-	log := b.gl.GetProgramInfoLog(p.Value.(*js.Object))
-	if log != "" {
-		return fmt.Errorf("gg: link program: %s", log)
+func (b *Backend) LinkProgram(p *gg.Program) error {
+	pv := b.Object(p.ID)
+	b.gl.LinkProgram(pv)
+	if !b.gl.GetProgramParameterb(pv, int(gg.LINK_STATUS)) {
+		return gg.NewLinkError(b.gl.GetProgramInfoLog(pv))
 	}
 	return nil
 }
 
-func (b *backend) UseProgram(p *gg.Program) {
-	b.gl.UseProgram(p.Value.(*js.Object))
+func (b *Backend) GetProgramParameter(p *gg.Program, pname gg.Enum) int {
+	// Number converts boolean parameters such as LINK_STATUS to 0 or 1.
+	v := b.gl.Call("getProgramParameter", b.Object(p.ID), int(pname))
+	return js.Global.Call("Number", v).Int()
 }
 
-func (b *backend) GetUniformLocation(p *gg.Program, name string) (*gg.Uniform, error) {
-	u := b.gl.GetUniformLocation(p.Value.(*js.Object), name)
-	if u.Int() < 0 {
-		return nil, fmt.Errorf("gg: no uniform named " + name)
+func (b *Backend) GetActiveUniform(p *gg.Program, index int) gg.ActiveInfo {
+	return activeInfo(b.gl.Call("getActiveUniform", b.Object(p.ID), index))
+}
+
+func (b *Backend) GetActiveAttrib(p *gg.Program, index int) gg.ActiveInfo {
+	return activeInfo(b.gl.Call("getActiveAttrib", b.Object(p.ID), index))
+}
+
+// activeInfo converts a WebGLActiveInfo.
+func activeInfo(info *js.Object) gg.ActiveInfo {
+	if info == nil {
+		return gg.ActiveInfo{}
+	}
+	return gg.ActiveInfo{
+		Name: info.Get("name").String(),
+		Size: info.Get("size").Int(),
+		Type: gg.Enum(info.Get("type").Int()),
+	}
+}
+
+func (b *Backend) UseProgram(p *gg.Program) {
+	var v *js.Object
+	if p != nil {
+		v = b.Object(p.ID)
 	}
-	return &gg.Uniform{Value: u}, nil
+	b.gl.UseProgram(v)
+}
+
+func (b *Backend) GetUniformLocation(p *gg.Program, name string) (*gg.Uniform, error) {
+	u := b.gl.GetUniformLocation(b.Object(p.ID), name)
+	if u == nil {
+		return nil, fmt.Errorf("gg: no uniform named %s", name)
+	}
+	return &gg.Uniform{ID: b.objs.Named(p.ID, name, u)}, nil
+}
+
+func (b *Backend) Uniform1f(u *gg.Uniform, v0 float32) {
+	b.gl.Uniform1f(b.Object(u.ID), v0)
+}
+
+func (b *Backend) Uniform2f(u *gg.Uniform, v0, v1 float32) {
+	b.gl.Uniform2f(b.Object(u.ID), v0, v1)
+}
+
+func (b *Backend) Uniform3f(u *gg.Uniform, v0, v1, v2 float32) {
+	b.gl.Uniform3f(b.Object(u.ID), v0, v1, v2)
+}
+
+func (b *Backend) Uniform4f(u *gg.Uniform, v0, v1, v2, v3 float32) {
+	b.gl.Uniform4f(b.Object(u.ID), v0, v1, v2, v3)
+}
+
+func (b *Backend) Uniform1i(u *gg.Uniform, v0 int) {
+	b.gl.Uniform1i(b.Object(u.ID), v0)
+}
+
+func (b *Backend) Uniform2i(u *gg.Uniform, v0, v1 int) {
+	b.gl.Uniform2i(b.Object(u.ID), v0, v1)
+}
+
+func (b *Backend) Uniform3i(u *gg.Uniform, v0, v1, v2 int) {
+	b.gl.Uniform3i(b.Object(u.ID), v0, v1, v2)
+}
+
+func (b *Backend) Uniform4i(u *gg.Uniform, v0, v1, v2, v3 int) {
+	b.gl.Uniform4i(b.Object(u.ID), v0, v1, v2, v3)
+}
+
+func (b *Backend) Uniform1fv(u *gg.Uniform, values []float32) {
+	b.gl.Call("uniform1fv", b.Object(u.ID), values)
 }
 
-func (b *backend) Uniform1f(u *gg.Uniform, v0 float32) {
-	b.gl.Uniform1f(u.Value.(*js.Object), v0)
+func (b *Backend) Uniform2fv(u *gg.Uniform, values []float32) {
+	b.gl.Call("uniform2fv", b.Object(u.ID), values)
 }
 
-func (b *backend) Uniform1i(u *gg.Uniform, v0 int) {
-	b.gl.Uniform1i(u.Value.(*js.Object), v0)
+func (b *Backend) Uniform3fv(u *gg.Uniform, values []float32) {
+	b.gl.Call("uniform3fv", b.Object(u.ID), values)
 }
 
-func (b *backend) Uniform4f(u *gg.Uniform, v0, v1, v2, v3 float32) {
-	b.gl.Uniform4f(u.Value.(*js.Object), v0, v1, v2, v3)
+func (b *Backend) Uniform4fv(u *gg.Uniform, values []float32) {
+	b.gl.Call("uniform4fv", b.Object(u.ID), values)
 }
 
-func (b *backend) UniformMatrix4fv(u *gg.Uniform, values []float32) {
-	b.gl.UniformMatrix4fv(u.Value.(*js.Object), false, values)
+func (b *Backend) Uniform1iv(u *gg.Uniform, values []int32) {
+	b.gl.Call("uniform1iv", b.Object(u.ID), values)
 }
 
-func (b *backend) GetAttribLocation(p *gg.Program, name string) (*gg.Attribute, error) {
-	a := b.gl.GetAttribLocation(p.Value.(*js.Object), name)
+func (b *Backend) Uniform2iv(u *gg.Uniform, values []int32) {
+	b.gl.Call("uniform2iv", b.Object(u.ID), values)
+}
+
+func (b *Backend) Uniform3iv(u *gg.Uniform, values []int32) {
+	b.gl.Call("uniform3iv", b.Object(u.ID), values)
+}
+
+func (b *Backend) Uniform4iv(u *gg.Uniform, values []int32) {
+	b.gl.Call("uniform4iv", b.Object(u.ID), values)
+}
+
+func (b *Backend) UniformMatrix2fv(u *gg.Uniform, values []float32) {
+	b.gl.UniformMatrix2fv(b.Object(u.ID), false, values)
+}
+
+func (b *Backend) UniformMatrix3fv(u *gg.Uniform, values []float32) {
+	b.gl.UniformMatrix3fv(b.Object(u.ID), false, values)
+}
+
+func (b *Backend) UniformMatrix4fv(u *gg.Uniform, values []float32) {
+	b.gl.UniformMatrix4fv(b.Object(u.ID), false, values)
+}
+
+func (b *Backend) GetAttribLocation(p *gg.Program, name string) (*gg.Attribute, error) {
+	a := b.gl.GetAttribLocation(b.Object(p.ID), name)
 	if a < 0 {
-		return nil, fmt.Errorf("gg: no attribute named " + name)
+		return nil, fmt.Errorf("gg: no attribute named %s", name)
 	}
-	return &gg.Attribute{Value: a}, nil
+	return &gg.Attribute{Location: a}, nil
 }
 
-func (b *backend) EnableVertexAttribArray(a *gg.Attribute) {
-	b.gl.EnableVertexAttribArray(a.Value.(int))
+func (b *Backend) EnableVertexAttribArray(a *gg.Attribute) {
+	b.gl.EnableVertexAttribArray(a.Location)
 }
 
-func (b *backend) VertexAttribPointer(a *gg.Attribute, size int, typ gg.Enum, normalized bool, stride, offset int) {
-	b.gl.VertexAttribPointer(a.Value.(int), size, int(typ), normalized, stride, offset)
+func (b *Backend) VertexAttribPointer(a *gg.Attribute, size int, typ gg.Enum, normalized bool, stride, offset int) {
+	b.gl.VertexAttribPointer(a.Location, size, int(typ), normalized, stride, offset)
 }
 
-func (b *backend) CreateTexture() *gg.Texture {
+func (b *Backend) CreateTexture() *gg.Texture {
 	t := b.gl.CreateTexture()
-	return &gg.Texture{Value: t}
+	return &gg.Texture{ID: b.objs.Add(t)}
 }
 
-func (b *backend) ActiveTexture(tex gg.Enum) {
+func (b *Backend) ActiveTexture(tex gg.Enum) {
 	b.gl.ActiveTexture(int(tex))
 }
 
-func (b *backend) BindTexture(target gg.Enum, texture *gg.Texture) {
-	b.gl.BindTexture(int(target), texture.Value.(*js.Object))
+func (b *Backend) BindTexture(target gg.Enum, texture *gg.Texture) {
+	var v *js.Object
+	if texture != nil {
+		v = b.Object(texture.ID)
+	}
+	b.gl.BindTexture(int(target), v)
+}
+
+func (b *Backend) DeleteTexture(t *gg.Texture) {
+	b.gl.DeleteTexture(b.Object(t.ID))
+	b.objs.Delete(t.ID)
 }
 
-func (b *backend) TexImage2D(
+func (b *Backend) TexImage2D(
 	target gg.Enum, level int, internalFormat gg.Enum,
 	width, height, border int,
 	format, typ gg.Enum,
-	data interface{},
+	data []byte,
 ) {
-	b.gl.TexImage2D(
-		gg.TEXTURE_2D, int(level), gg.RGBA,
-		gg.RGBA, gg.UNSIGNED_BYTE,
-		data.(*js.Object),
+	var pixels interface{}
+	if data != nil {
+		pixels = pixelView(typ, data)
+	}
+	b.gl.Call(
+		"texImage2D",
+		int(target), level, int(internalFormat),
+		width, height, border,
+		int(format), int(typ),
+		pixels,
 	)
 }
 
-func (b *backend) TexParameteri(target gg.Enum, pname gg.Enum, param gg.Enum) {
+// TexImage2DFromSource uploads source, which must be a *js.Object holding
+// an image, canvas, video or ImageBitmap.
+func (b *Backend) TexImage2DFromSource(target gg.Enum, level int, internalFormat, format, typ gg.Enum, source interface{}) error {
+	src, ok := source.(*js.Object)
+	if !ok {
+		return fmt.Errorf("gg: TexImage2DFromSource: source is a %T, not a *js.Object", source)
+	}
+	b.gl.TexImage2D(int(target), level, int(internalFormat), int(format), int(typ), src)
+	return nil
+}
+
+// pixelView returns data as the ArrayBufferView type WebGL requires for
+// pixel data of type typ: a Uint16Array for the packed 16-bit types and a
+// Float32Array for FLOAT, over a copy of data.
+func pixelView(typ gg.Enum, data []byte) interface{} {
+	var ctor string
+	switch typ {
+	case gg.UNSIGNED_SHORT_5_6_5, gg.UNSIGNED_SHORT_4_4_4_4, gg.UNSIGNED_SHORT_5_5_5_1:
+		ctor = "Uint16Array"
+	case gg.FLOAT:
+		ctor = "Float32Array"
+	default:
+		return data
+	}
+	buf := js.Global.Get("Uint8Array").New(data).Get("buffer")
+	return js.Global.Get(ctor).New(buf)
+}
+
+func (b *Backend) Supports(f gg.Feature) bool {
+	return f == gg.FeatureImageSources
+}
+
+func (b *Backend) TexParameteri(target gg.Enum, pname gg.Enum, param gg.Enum) {
 	b.gl.TexParameteri(int(target), int(pname), int(param))
 }
 
-func (b *backend) DrawArrays(mode gg.Enum, first, count int) {
+func (b *Backend) DrawArrays(mode gg.Enum, first, count int) {
 	b.gl.DrawArrays(int(mode), first, count)
 }
+
+func (b *Backend) DrawElements(mode gg.Enum, count int, typ gg.Enum, offset int) error {
+	switch typ {
+	case gg.UNSIGNED_BYTE, gg.UNSIGNED_SHORT:
+	case gg.UNSIGNED_INT:
+		if !b.uintIndices {
+			return fmt.Errorf("gg: UNSIGNED_INT indices require OES_element_index_uint")
+		}
+	default:
+		return fmt.Errorf("gg: invalid index type 0x%x", int(typ))
+	}
+	b.gl.DrawElements(int(mode), count, int(typ), offset)
+	return nil
+}
+
+func (b *Backend) CreateFramebuffer() *gg.Framebuffer {
+	return &gg.Framebuffer{ID: b.objs.Add(b.gl.CreateFramebuffer())}
+}
+
+func (b *Backend) BindFramebuffer(target gg.Enum, fb *gg.Framebuffer) {
+	var v *js.Object
+	if fb != nil {
+		v = b.Object(fb.ID)
+	}
+	b.gl.BindFramebuffer(int(target), v)
+}
+
+func (b *Backend) DeleteFramebuffer(fb *gg.Framebuffer) {
+	b.gl.DeleteFramebuffer(b.Object(fb.ID))
+	b.objs.Delete(fb.ID)
+}
+
+func (b *Backend) FramebufferTexture2D(
+	target, attachment, textarget gg.Enum,
+	texture *gg.Texture, level int,
+) {
+	var v *js.Object
+	if texture != nil {
+		v = b.Object(texture.ID)
+	}
+	b.gl.FramebufferTexture2D(int(target), int(attachment), int(textarget), v, level)
+}
+
+func (b *Backend) FramebufferRenderbuffer(
+	target, attachment, renderbuffertarget gg.Enum,
+	rb *gg.Renderbuffer,
+) {
+	var v *js.Object
+	if rb != nil {
+		v = b.Object(rb.ID)
+	}
+	b.gl.FramebufferRenderbuffer(int(target), int(attachment), int(renderbuffertarget), v)
+}
+
+func (b *Backend) CheckFramebufferStatus(target gg.Enum) gg.Enum {
+	return gg.Enum(b.gl.CheckFramebufferStatus(int(target)))
+}
+
+func (b *Backend) CreateRenderbuffer() *gg.Renderbuffer {
+	return &gg.Renderbuffer{ID: b.objs.Add(b.gl.CreateRenderbuffer())}
+}
+
+func (b *Backend) BindRenderbuffer(target gg.Enum, rb *gg.Renderbuffer) {
+	var v *js.Object
+	if rb != nil {
+		v = b.Object(rb.ID)
+	}
+	b.gl.BindRenderbuffer(int(target), v)
+}
+
+func (b *Backend) DeleteRenderbuffer(rb *gg.Renderbuffer) {
+	b.gl.DeleteRenderbuffer(b.Object(rb.ID))
+	b.objs.Delete(rb.ID)
+}
+
+func (b *Backend) RenderbufferStorage(target, internalFormat gg.Enum, width, height int) {
+	b.gl.RenderbufferStorage(int(target), int(internalFormat), width, height)
+}
+
+func (b *Backend) ReadPixels(x, y, width, height int, format, typ gg.Enum, dst []byte) {
+	// dst is passed as a Uint8Array view of the same memory, so the
+	// browser writes directly into it.
+	b.gl.Call("readPixels", x, y, width, height, int(format), int(typ), dst)
+}
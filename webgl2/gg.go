@@ -0,0 +1,225 @@
+// Package gg_webgl2 implements gg.Backend on a WebGL 2 context.
+//
+// In addition to the base API, the backend supports every gg.Feature:
+// vertex array objects, instancing, 3D textures, multiple render targets,
+// uniform buffers and integer textures. The calls WebGL 2 shares with
+// WebGL 1 go through the gg_webgl backend.
+package gg_webgl2
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dmac/gg"
+	ggwebgl "github.com/dmac/gg/webgl"
+	"github.com/gopherjs/gopherjs/js"
+	"github.com/gopherjs/webgl"
+)
+
+// backend adds the WebGL 2 calls to the WebGL 1 backend, which drives
+// the shared part of the API.
+type backend struct {
+	*ggwebgl.Backend
+	gl *webgl.Context
+}
+
+var (
+	_ gg.Backend              = (*backend)(nil)
+	_ gg.FeatureBackend       = (*backend)(nil)
+	_ gg.VertexArrayBackend   = (*backend)(nil)
+	_ gg.InstancingBackend    = (*backend)(nil)
+	_ gg.Texture3DBackend     = (*backend)(nil)
+	_ gg.DrawBuffersBackend   = (*backend)(nil)
+	_ gg.UniformBufferBackend = (*backend)(nil)
+	_ gg.IntegerBackend       = (*backend)(nil)
+	_ gg.ImageSourceBackend   = (*backend)(nil)
+	_ gg.ShaderDialectBackend = (*backend)(nil)
+)
+
+// NewContext creates a WebGL 2 context for canvas and returns a gg.Context
+// that issues calls to it. If attrs is nil, webgl.DefaultAttributes is used.
+func NewContext(canvas *js.Object, attrs *webgl.ContextAttributes) (*gg.Context, error) {
+	if js.Global.Get("WebGL2RenderingContext") == js.Undefined {
+		return nil, errors.New("gg: the browser does not support WebGL 2")
+	}
+	if attrs == nil {
+		attrs = webgl.DefaultAttributes()
+	}
+	gl := canvas.Call("getContext", "webgl2", map[string]bool{
+		"alpha":                 attrs.Alpha,
+		"depth":                 attrs.Depth,
+		"stencil":               attrs.Stencil,
+		"antialias":             attrs.Antialias,
+		"premultipliedAlpha":    attrs.PremultipliedAlpha,
+		"preserveDrawingBuffer": attrs.PreserveDrawingBuffer,
+	})
+	if gl == nil {
+		return nil, errors.New("gg: creating a WebGL 2 context failed")
+	}
+	// WebGL 2 is a superset of WebGL 1, so the WebGL 1 backend can drive it.
+	ctx := &webgl.Context{Object: gl}
+	return gg.NewContext(&backend{Backend: ggwebgl.New(ctx), gl: ctx}), nil
+}
+
+func (*backend) ShaderDialect() gg.Dialect {
+	return gg.GLSLES300
+}
+
+// TexImage2D accepts the wider range of pixel types WebGL 2 allows.
+func (b *backend) TexImage2D(
+	target gg.Enum, level int, internalFormat gg.Enum,
+	width, height, border int,
+	format, typ gg.Enum,
+	data []byte,
+) {
+	var pixels interface{}
+	if data != nil {
+		pixels = pixelView(typ, data)
+	}
+	b.gl.Call(
+		"texImage2D",
+		int(target), level, int(internalFormat),
+		width, height, border,
+		int(format), int(typ),
+		pixels,
+	)
+}
+
+// DrawElements accepts UNSIGNED_INT indices, which WebGL 2 supports
+// without an extension.
+func (b *backend) DrawElements(mode gg.Enum, count int, typ gg.Enum, offset int) error {
+	if err := checkIndexType(typ); err != nil {
+		return err
+	}
+	b.gl.DrawElements(int(mode), count, int(typ), offset)
+	return nil
+}
+
+func checkIndexType(typ gg.Enum) error {
+	switch typ {
+	case gg.UNSIGNED_BYTE, gg.UNSIGNED_SHORT, gg.UNSIGNED_INT:
+		return nil
+	}
+	return fmt.Errorf("gg: invalid index type 0x%x", int(typ))
+}
+
+// pixelView returns data as the ArrayBufferView type WebGL 2 requires for
+// pixel data of type typ. Byte data is passed as is; other types get a
+// typed array over a copy of data.
+func pixelView(typ gg.Enum, data []byte) interface{} {
+	var ctor string
+	switch typ {
+	case gg.BYTE:
+		ctor = "Int8Array"
+	case gg.SHORT:
+		ctor = "Int16Array"
+	case gg.UNSIGNED_SHORT, gg.HALF_FLOAT,
+		gg.UNSIGNED_SHORT_5_6_5, gg.UNSIGNED_SHORT_4_4_4_4, gg.UNSIGNED_SHORT_5_5_5_1:
+		ctor = "Uint16Array"
+	case gg.INT:
+		ctor = "Int32Array"
+	case gg.UNSIGNED_INT, gg.UNSIGNED_INT_24_8,
+		gg.UNSIGNED_INT_2_10_10_10_REV, gg.UNSIGNED_INT_10F_11F_11F_REV, gg.UNSIGNED_INT_5_9_9_9_REV:
+		ctor = "Uint32Array"
+	case gg.FLOAT:
+		ctor = "Float32Array"
+	default:
+		return data
+	}
+	buf := js.Global.Get("Uint8Array").New(data).Get("buffer")
+	return js.Global.Get(ctor).New(buf)
+}
+
+func (b *backend) Supports(f gg.Feature) bool {
+	switch f {
+	case gg.FeatureVertexArrays, gg.FeatureInstancing, gg.FeatureTexture3D,
+		gg.FeatureDrawBuffers, gg.FeatureUniformBuffers, gg.FeatureIntegerTextures,
+		gg.FeatureImageSources:
+		return true
+	}
+	return false
+}
+
+func (b *backend) CreateVertexArray() *gg.VertexArray {
+	return &gg.VertexArray{ID: b.Objects().Add(b.gl.Call("createVertexArray"))}
+}
+
+func (b *backend) BindVertexArray(va *gg.VertexArray) {
+	var v *js.Object
+	if va != nil {
+		v = b.Object(va.ID)
+	}
+	b.gl.Call("bindVertexArray", v)
+}
+
+func (b *backend) DeleteVertexArray(va *gg.VertexArray) {
+	b.gl.Call("deleteVertexArray", b.Object(va.ID))
+	b.Objects().Delete(va.ID)
+}
+
+func (b *backend) VertexAttribDivisor(a *gg.Attribute, divisor int) {
+	b.gl.Call("vertexAttribDivisor", a.Location, divisor)
+}
+
+func (b *backend) DrawArraysInstanced(mode gg.Enum, first, count, instances int) {
+	b.gl.Call("drawArraysInstanced", int(mode), first, count, instances)
+}
+
+func (b *backend) DrawElementsInstanced(mode gg.Enum, count int, typ gg.Enum, offset, instances int) error {
+	if err := checkIndexType(typ); err != nil {
+		return err
+	}
+	b.gl.Call("drawElementsInstanced", int(mode), count, int(typ), offset, instances)
+	return nil
+}
+
+func (b *backend) TexImage3D(
+	target gg.Enum, level int, internalFormat gg.Enum,
+	width, height, depth, border int,
+	format, typ gg.Enum,
+	data []byte,
+) {
+	var v interface{}
+	if data != nil {
+		v = pixelView(typ, data)
+	}
+	b.gl.Call(
+		"texImage3D",
+		int(target), level, int(internalFormat),
+		width, height, depth, border,
+		int(format), int(typ),
+		v,
+	)
+}
+
+func (b *backend) DrawBuffers(bufs []gg.Enum) {
+	v := make([]int, len(bufs))
+	for i, buf := range bufs {
+		v[i] = int(buf)
+	}
+	b.gl.Call("drawBuffers", v)
+}
+
+func (b *backend) GetUniformBlockIndex(p *gg.Program, name string) (int, error) {
+	i := b.gl.Call("getUniformBlockIndex", b.Object(p.ID), name).Int64()
+	if i == 0xFFFFFFFF { // INVALID_INDEX
+		return 0, fmt.Errorf("gg: no uniform block named %s", name)
+	}
+	return int(i), nil
+}
+
+func (b *backend) UniformBlockBinding(p *gg.Program, index, binding int) {
+	b.gl.Call("uniformBlockBinding", b.Object(p.ID), index, binding)
+}
+
+func (b *backend) BindBufferBase(target gg.Enum, index int, buf *gg.Buffer) {
+	var v *js.Object
+	if buf != nil {
+		v = b.Object(buf.ID)
+	}
+	b.gl.Call("bindBufferBase", int(target), index, v)
+}
+
+func (b *backend) VertexAttribIPointer(a *gg.Attribute, size int, typ gg.Enum, stride, offset int) {
+	b.gl.Call("vertexAttribIPointer", a.Location, size, int(typ), stride, offset)
+}